@@ -2,25 +2,34 @@ package main
 
 import (
 	"api-core/internal/config"
+	"api-core/internal/health"
+	"api-core/pkg/metrics"
 	"context"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"time"
+	"syscall"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/do"
 	"github.com/urfave/cli/v2"
 )
 
+// startAPIServer serves the API until SIGINT or SIGTERM. It then drains, with
+// /readyz failing, and waits for the in-flight requests. The container, with
+// the pool and the Redis client, is shut down once the command returns.
 func startAPIServer(c *cli.Context) error {
-	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	container, err := containerFrom(c)
+	if err != nil {
+		return err
+	}
 
-	container, ok := c.App.Metadata[config.FlagContainer].(*do.Injector)
-	if !ok {
-		return errors.New("invalid service container")
+	cfg, err := do.Invoke[*config.Config](container)
+	if err != nil {
+		return err
 	}
 
 	router, err := do.Invoke[http.Handler](container)
@@ -28,24 +37,85 @@ func startAPIServer(c *cli.Context) error {
 		return err
 	}
 
-	srv := &http.Server{
-		Addr:    c.String(config.FlagAddress),
-		Handler: router,
+	drain, err := do.Invoke[*health.Drain](container)
+	if err != nil {
+		return err
 	}
 
-	quit := make(chan os.Signal, 1)
-
+	// a listener failing stops the command, without draining
+	failed := make(chan error, 2)
+	srv := newServer(c.String(config.FlagAddress), router, cfg.Server)
 	go func() {
-		log.Printf("ListenAndServe: %s\n", c.String(config.FlagAddress))
+		slog.Info("api: listening", "addr", srv.Addr)
 		err := srv.ListenAndServe()
-		if err != nil && err != http.ErrServerClosed {
-			log.Printf("ListenAndServe failed: %s\n", err)
-			quit <- os.Kill
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("api: listen: %w", err)
 		}
 	}()
 
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	metricsSrv, err := startMetricsServer(container, cfg, failed)
+	if err != nil {
+		return err
+	}
 
-	return srv.Shutdown(ctxShutdown)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	var listenErr error
+	select {
+	case sig := <-quit:
+		slog.Info("api: shutting down", "signal", sig.String())
+		// a second signal ends the drain early
+		drain.Run(cfg.Server.DrainPeriod, quit)
+	case listenErr = <-failed:
+	}
+
+	// created once the requests are drained, so the whole timeout is left to
+	// the in-flight ones
+	ctxShutdown, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	err = errors.Join(listenErr, srv.Shutdown(ctxShutdown))
+	if metricsSrv != nil {
+		err = errors.Join(err, metricsSrv.Shutdown(ctxShutdown))
+	}
+	slog.Info("api: stopped")
+	return err
+}
+
+func newServer(addr string, handler http.Handler, cfg config.ServerConfig) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
+
+// startMetricsServer serves /metrics on the separate metrics address, nil when
+// metrics are disabled.
+func startMetricsServer(container *do.Injector, cfg *config.Config, failed chan<- error) (*http.Server, error) {
+	if !cfg.Metrics.Enabled {
+		return nil, nil
+	}
+
+	registry, err := do.Invoke[*prometheus.Registry](container)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler(registry))
+	srv := newServer(cfg.Metrics.Address, mux, cfg.Server)
+
+	go func() {
+		slog.Info("metrics: listening", "addr", cfg.Metrics.Address)
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("metrics: listen: %w", err)
+		}
+	}()
+	return srv, nil
 }
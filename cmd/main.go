@@ -4,11 +4,15 @@ import (
 	"api-core/internal/config"
 	"api-core/internal/container"
 	"api-core/internal/migrate"
-	"log"
+	auditservice "api-core/internal/service/audit"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/samber/do"
 	"github.com/urfave/cli/v2"
 )
 
@@ -24,6 +28,12 @@ var (
 		Usage:    "Configuration Address",
 		Required: false,
 	}
+	configOpenAPIOutputFlag = cli.StringFlag{
+		Name:    config.FlagOutput,
+		Aliases: []string{"o"},
+		Value:   "openapi.json",
+		Usage:   "File to write the document to, - for stdout",
+	}
 	configMigrateActionFlag = cli.StringFlag{
 		Name:     config.FlagMigrateAction,
 		Value:    config.FlagUpAction,
@@ -35,37 +45,34 @@ var (
 func init() {
 	err := godotenv.Load(strings.Split(envPath, ",")...)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		fatal("error loading .env file", err)
 	}
 }
 
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal(err)
-	}
-	ctn, err := container.NewContainer(cfg)
-	if err != nil {
-		log.Fatal(err)
+		fatal("load configuration", err)
 	}
 
 	app := cli.NewApp()
 	app.Name = appName
 	flags := []cli.Flag{}
-	app.Metadata = map[string]any{
-		config.FlagContainer: ctn,
-	}
+	app.Metadata = map[string]any{}
+	app.After = shutdownContainer
 	app.Commands = []*cli.Command{
 		{
 			Name:    "api",
 			Aliases: []string{},
 			Usage:   "Run the api",
+			Before:  withContainer(cfg),
 			Action:  startAPIServer,
 			Flags:   append(flags, &configAddressFlag),
 		},
 		{
-			Name:  "migrate",
-			Usage: "Run database migrations (goose)",
+			Name:   "migrate",
+			Usage:  "Run database migrations (goose)",
+			Before: withContainer(cfg),
 			Flags: []cli.Flag{
 				&configMigrateActionFlag,
 			},
@@ -77,11 +84,78 @@ func main() {
 				return migrate.Run(c.Context, cfg.Database, migrate.Command(action))
 			},
 		},
+		{
+			Name:   "audit-purge",
+			Usage:  "Delete audit events older than AUDIT_RETENTION_DAYS",
+			Before: withContainer(cfg),
+			Action: func(c *cli.Context) error {
+				ctn, err := containerFrom(c)
+				if err != nil {
+					return err
+				}
+				service, err := do.Invoke[*auditservice.Service](ctn)
+				if err != nil {
+					return err
+				}
+				deleted, err := service.Purge(c.Context)
+				if err != nil {
+					return err
+				}
+				slog.InfoContext(c.Context, "audit-purge: deleted events", "count", deleted)
+				return nil
+			},
+		},
+		{
+			Name:  "openapi",
+			Usage: "Write the OpenAPI document, without connecting to the database or redis",
+			Flags: []cli.Flag{
+				&configOpenAPIOutputFlag,
+			},
+			Before: withContainer(offlineConfig(cfg), container.Offline()),
+			Action: writeOpenAPI,
+		},
 	}
 
 	err = app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
-		os.Exit(1)
+		fatal(appName+" failed", err)
 	}
 }
+
+// withContainer creates the service container before the command runs, so
+// each command only connects to what it needs.
+func withContainer(cfg *config.Config, opts ...container.Option) cli.BeforeFunc {
+	return func(c *cli.Context) error {
+		ctn, err := container.NewContainer(cfg, opts...)
+		if err != nil {
+			return fmt.Errorf("create container: %w", err)
+		}
+		c.App.Metadata[config.FlagContainer] = ctn
+		return nil
+	}
+}
+
+// shutdownContainer shuts down the services of the command container in
+// reverse dependency order: the services first, then the pgx pool and the
+// Redis client, and last the tracer provider flushing the pending spans. A
+// failure is logged and the following services are still shut down.
+func shutdownContainer(c *cli.Context) error {
+	ctn, ok := c.App.Metadata[config.FlagContainer].(*do.Injector)
+	if !ok {
+		return nil
+	}
+	return container.Shutdown(ctn)
+}
+
+func containerFrom(c *cli.Context) (*do.Injector, error) {
+	ctn, ok := c.App.Metadata[config.FlagContainer].(*do.Injector)
+	if !ok {
+		return nil, errors.New("invalid service container")
+	}
+	return ctn, nil
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
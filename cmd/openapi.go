@@ -0,0 +1,61 @@
+package main
+
+import (
+	"api-core/internal/config"
+	"api-core/pkg/openapi"
+	"api-core/pkg/tracing"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/samber/do"
+	"github.com/urfave/cli/v2"
+)
+
+// writeOpenAPI builds the router so every route is registered, then writes the
+// document, e.g. for CI to diff against the committed one.
+func writeOpenAPI(c *cli.Context) error {
+	container, err := containerFrom(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := do.Invoke[http.Handler](container); err != nil {
+		return err
+	}
+	registry, err := do.Invoke[*openapi.Registry](container)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(registry.Document(), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	output := c.String(config.FlagOutput)
+	if output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return err
+	}
+	slog.InfoContext(c.Context, "openapi: document written", "path", output)
+	return nil
+}
+
+// offlineConfig fills the credentials the wiring validates but the document
+// does not depend on, so the command runs in CI without secrets.
+func offlineConfig(cfg *config.Config) *config.Config {
+	offline := *cfg
+	if offline.Google.ClientID == "" || offline.Google.ClientSecret == "" || offline.Google.RedirectURL == "" {
+		offline.Google.ClientID = "openapi"
+		offline.Google.ClientSecret = "openapi"
+		offline.Google.RedirectURL = "http://localhost/openapi"
+	}
+	offline.Tracing.Exporter = tracing.ExporterNone
+	return &offline
+}
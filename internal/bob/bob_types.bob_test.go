@@ -8,11 +8,20 @@ import "github.com/stephenafamo/bob"
 // Set the testDB to enable tests that use the database
 var testDB bob.Transactor[bob.Tx]
 
+// Make sure the type AuditEvent runs hooks after queries
+var _ bob.HookableType = &AuditEvent{}
+
 // Make sure the type HealthCheck runs hooks after queries
 var _ bob.HookableType = &HealthCheck{}
 
+// Make sure the type OauthToken runs hooks after queries
+var _ bob.HookableType = &OauthToken{}
+
 // Make sure the type SchemaMigration runs hooks after queries
 var _ bob.HookableType = &SchemaMigration{}
 
+// Make sure the type SignupWaitlist runs hooks after queries
+var _ bob.HookableType = &SignupWaitlist{}
+
 // Make sure the type User runs hooks after queries
 var _ bob.HookableType = &User{}
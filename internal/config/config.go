@@ -2,7 +2,10 @@ package config
 
 import (
 	"api-core/internal/db"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -16,21 +19,71 @@ const (
 	FlagUpAction      = "up"
 	FlagDownAction    = "down"
 	FlagContainer     = "container"
+	FlagOutput        = "output"
 )
 
-const defaultJWTSecret = "dev-secret-change-me"
+const (
+	defaultJWTSecret          = "dev-secret-change-me"
+	defaultProblemTypeBaseURI = "urn:api-core:problem:"
+	// defaultContentSecurityPolicy fits a JSON API, nothing may be loaded or
+	// framed.
+	defaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+)
+
+// Deployment profiles selected through APP_ENV.
+const (
+	EnvDev        = "dev"
+	EnvTest       = "test"
+	EnvProduction = "production"
+)
 
 type Config struct {
+	Env      string
 	Database db.DatabaseConfig `mapstructure:"Database"`
 	Redis    db.RedisConfig    `mapstructure:"Redis"`
 	Auth     AuthConfig
 	Google   GoogleConfig
+	Device   DeviceConfig
+
+	Introspection IntrospectionConfig
+	DevIdP        DevIdPConfig
+	Signup        SignupConfig
+	Audit         AuditConfig
+	Server        ServerConfig
+	HTTP          HTTPConfig
+	CORS          CORSConfig
+	Security      SecurityHeadersConfig
+	Log           LogConfig
+	RateLimit     RateLimitConfig
+	Idempotency   IdempotencyConfig
+	Health        HealthConfig
+	Metrics       MetricsConfig
+	Tracing       TracingConfig
+	ResponseCache ResponseCacheConfig
+}
+
+// IsDev reports whether the app runs in a local development or test profile.
+func (c *Config) IsDev() bool {
+	return c.Env == EnvDev || c.Env == EnvTest
 }
 
 type AuthConfig struct {
 	JWTSecret     string
 	JWTIssuer     string
 	JWTExpiration time.Duration
+	// AdminEmails are granted every action by the authorization guard.
+	AdminEmails []string
+	// TokenEncryptionKey is the base64 encoded AES-256 key used to encrypt
+	// stored provider tokens, token storage is disabled when empty.
+	TokenEncryptionKey string
+	// Argon2id password hashing cost, hashes with other parameters are
+	// upgraded on the next successful login.
+	PasswordMemoryKiB   int
+	PasswordIterations  int
+	PasswordParallelism int
+	// FreshAuthMaxAge is how recent the sign-in must be on sensitive routes
+	// such as device approvals and waitlist administration.
+	FreshAuthMaxAge time.Duration
 }
 
 type GoogleConfig struct {
@@ -40,6 +93,195 @@ type GoogleConfig struct {
 	Scopes       []string
 }
 
+// DeviceConfig configures the OAuth 2.0 device authorization grant (RFC 8628).
+type DeviceConfig struct {
+	// VerificationURI is the page where users enter their user code.
+	VerificationURI string
+	CodeTTL         time.Duration
+	PollInterval    time.Duration
+	// ClientIDs lists the public clients allowed to start a device flow.
+	ClientIDs []string
+	// ClientScopes lists the scopes each client may request, a client
+	// without an entry requests none.
+	ClientScopes map[string][]string
+}
+
+// IntrospectionConfig configures token introspection (RFC 7662) and userinfo.
+type IntrospectionConfig struct {
+	// Clients maps machine client ids to their secrets.
+	Clients       map[string]string
+	CacheTTL      time.Duration
+	UserInfoCache time.Duration
+}
+
+// DevIdPConfig configures the built-in fake identity provider that replaces
+// Google in the dev and test profiles.
+type DevIdPConfig struct {
+	Enabled bool
+	// BaseURL is the public URL the dev IdP routes are served under.
+	BaseURL string
+	// Users are the test accounts offered on the dev IdP login page.
+	Users []string
+}
+
+// Sign-up modes selected through AUTH_SIGNUP_MODE.
+const (
+	SignupModeOpen       = "open"
+	SignupModeInviteOnly = "invite_only"
+)
+
+// SignupConfig configures the policies evaluated before a new user is created.
+type SignupConfig struct {
+	Mode string
+	// AllowedDomains restricts sign-up to these email domains, empty allows all.
+	AllowedDomains []string
+	// HostedDomain is the Google Workspace domain sent as the hd parameter and
+	// required in the hd claim.
+	HostedDomain string
+}
+
+// AuditConfig configures the security audit log.
+type AuditConfig struct {
+	// Retention is how long events are kept before the purge deletes them.
+	Retention time.Duration
+}
+
+// Error formats selected through HTTP_ERROR_FORMAT.
+const (
+	ErrorFormatEnvelope = "envelope"
+	ErrorFormatProblem  = "problem"
+)
+
+// ServerConfig configures the HTTP listeners and their shutdown.
+type ServerConfig struct {
+	// ReadTimeout bounds reading a request with its body, WriteTimeout
+	// writing the response. Keep WriteTimeout above the HTTP handler timeout.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// IdleTimeout closes keep-alive connections without requests.
+	IdleTimeout time.Duration
+	// DrainPeriod keeps serving after SIGTERM with /readyz failing, so the
+	// load balancer stops routing to the instance first. It defaults to 0 in
+	// the dev and test profiles.
+	DrainPeriod time.Duration
+	// ShutdownTimeout bounds waiting for the in-flight requests after the
+	// drain. DrainPeriod and ShutdownTimeout must fit in the termination grace
+	// period of the orchestrator.
+	ShutdownTimeout time.Duration
+}
+
+// HTTPConfig configures the HTTP layer.
+type HTTPConfig struct {
+	// ErrorFormat is envelope ({code, message}) or problem (RFC 7807).
+	ErrorFormat string
+	// ProblemTypeBaseURI prefixes the error code to build the problem type URI.
+	ProblemTypeBaseURI string
+	// DocsEnabled serves the OpenAPI document and the docs UI, by default in
+	// the dev and test profiles only.
+	DocsEnabled bool
+	// ValidateRequests checks requests against the OpenAPI document, and
+	// responses too in the dev and test profiles.
+	ValidateRequests bool
+	// BodyLimit is the largest request body in bytes, zero disables it.
+	BodyLimit int64
+	// Timeout bounds the /api/v1 handlers, RouteTimeouts overrides it per
+	// "METHOD /path" or "/path" route template. Zero disables it.
+	Timeout       time.Duration
+	RouteTimeouts map[string]time.Duration
+}
+
+// CORSConfig configures the cross-origin requests, see httpx.CORSConfig for
+// the origin patterns.
+type CORSConfig struct {
+	// AllowOrigins defaults to the localhost origins in the dev and test
+	// profiles, and to none (same origin only) otherwise.
+	AllowOrigins []string
+	// AllowHeaders and AllowMethods default to the ones the API uses.
+	AllowHeaders     []string
+	AllowMethods     []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// SecurityHeadersConfig configures the security headers of every response.
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge is only sent over HTTPS, directly or behind a proxy setting
+	// X-Forwarded-Proto. Zero omits the header, the default in the dev and
+	// test profiles.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// ContentSecurityPolicy applies to every response but the docs UI, which
+	// sets its own.
+	ContentSecurityPolicy string
+	CSPReportOnly         bool
+	FrameOptions          string
+	ReferrerPolicy        string
+}
+
+// LogConfig configures the application logger.
+type LogConfig struct {
+	// Format is json or text.
+	Format string
+	// Level is debug, info, warn or error.
+	Level string
+}
+
+// RateLimitConfig configures the request rate limits, a limit with zero
+// requests is disabled.
+type RateLimitConfig struct {
+	Enabled bool
+	// Algorithm is sliding_window or token_bucket.
+	Algorithm string
+	// API limits every /api/v1 request per client address.
+	APIRequests int
+	APIWindow   time.Duration
+	// Auth limits the /auth endpoints per client address and route.
+	AuthRequests int
+	AuthWindow   time.Duration
+	// User limits authenticated admin requests per user.
+	UserRequests int
+	UserWindow   time.Duration
+}
+
+// IdempotencyConfig configures the replay of requests sent with an
+// Idempotency-Key header.
+type IdempotencyConfig struct {
+	// TTL is how long a response is replayed for the same key.
+	TTL time.Duration
+}
+
+// HealthConfig configures the readiness checks.
+type HealthConfig struct {
+	// Timeout bounds each dependency check.
+	Timeout time.Duration
+}
+
+// MetricsConfig configures the Prometheus endpoint.
+type MetricsConfig struct {
+	Enabled bool
+	// Address serves /metrics on a separate listener, kept off the public
+	// port so the load balancer does not expose it.
+	Address string
+}
+
+// TracingConfig configures the OpenTelemetry traces, see the tracing package.
+type TracingConfig struct {
+	// Exporter is none, otlp or stdout. The OTLP endpoint and headers are read
+	// from the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter string
+	// File is where the stdout exporter writes, empty writes to stdout.
+	File string
+	// SampleRatio is the share of new traces recorded.
+	SampleRatio float64
+}
+
+// ResponseCacheConfig sets the TTLs of the cached GET routes, zero disables
+// the cache of a route.
+type ResponseCacheConfig struct {
+	Waitlist time.Duration
+}
+
 // Load loads the configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{}
@@ -51,14 +293,17 @@ func Load() (*Config, error) {
 	// Set default values
 	setDefaults()
 
+	cfg.Env = getEnvString("APP_ENV", EnvProduction)
+
 	// Load Database config
 	cfg.Database = db.DatabaseConfig{
-		Host:     getEnvString("DATABASE_HOST", "postgres"),
-		Port:     getEnvString("DATABASE_PORT", "5432"),
-		User:     getEnvString("DATABASE_USER", "postgres"),
-		Password: getEnvString("DATABASE_PASSWORD", ""),
-		Name:     getEnvString("DATABASE_NAME", "postgres"),
-		MaxConns: getEnvInt("DATABASE_MAX_CONNS", 0),
+		Host:        getEnvString("DATABASE_HOST", "postgres"),
+		Port:        getEnvString("DATABASE_PORT", "5432"),
+		User:        getEnvString("DATABASE_USER", "postgres"),
+		Password:    getEnvString("DATABASE_PASSWORD", ""),
+		Name:        getEnvString("DATABASE_NAME", "postgres"),
+		MaxConns:    getEnvInt("DATABASE_MAX_CONNS", 0),
+		SQLComments: getEnvBool("DATABASE_SQL_COMMENTS", true),
 	}
 
 	// Load Redis config
@@ -75,9 +320,17 @@ func Load() (*Config, error) {
 		JWTSecret:     getEnvString("AUTH_JWT_SECRET", defaultJWTSecret),
 		JWTIssuer:     getEnvString("AUTH_JWT_ISSUER", "api-core"),
 		JWTExpiration: time.Duration(jwtExpMinutes) * time.Minute,
+		AdminEmails:   getEnvStringSlice("AUTH_ADMIN_EMAILS", []string{}),
+
+		TokenEncryptionKey: getEnvString("AUTH_TOKEN_ENCRYPTION_KEY", ""),
+
+		PasswordMemoryKiB:   getEnvInt("AUTH_PASSWORD_MEMORY_KIB", 19456),
+		PasswordIterations:  getEnvInt("AUTH_PASSWORD_ITERATIONS", 2),
+		PasswordParallelism: getEnvInt("AUTH_PASSWORD_PARALLELISM", 1),
+		FreshAuthMaxAge:     time.Duration(getEnvInt("AUTH_FRESH_AUTH_MAX_AGE_SECONDS", 300)) * time.Second,
 	}
 	if cfg.Auth.JWTSecret == defaultJWTSecret {
-		log.Println("Warning: using default JWT secret, override AUTH_JWT_SECRET in production")
+		slog.Warn("using default JWT secret, override AUTH_JWT_SECRET in production")
 	}
 
 	// Google OAuth config
@@ -88,15 +341,204 @@ func Load() (*Config, error) {
 		Scopes:       getEnvStringSlice("GOOGLE_OAUTH_SCOPES", DefaultGoogleScopes()),
 	}
 
-	log.Println("Configuration loaded from environment variables")
-	log.Printf("Database: %s@%s:%s/%s", cfg.Database.User, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name)
-	log.Printf("Redis: %s:%s", cfg.Redis.Host, cfg.Redis.Port)
+	// Device authorization grant config
+	cfg.Device = DeviceConfig{
+		VerificationURI: getEnvString("AUTH_DEVICE_VERIFICATION_URI", ""),
+		CodeTTL:         time.Duration(getEnvInt("AUTH_DEVICE_CODE_TTL_MINUTES", 10)) * time.Minute,
+		PollInterval:    time.Duration(getEnvInt("AUTH_DEVICE_POLL_INTERVAL_SECONDS", 5)) * time.Second,
+		ClientIDs:       getEnvStringSlice("AUTH_DEVICE_CLIENT_IDS", []string{}),
+		ClientScopes:    map[string][]string{},
+	}
+	for clientID, scopes := range getEnvStringMap("AUTH_DEVICE_CLIENT_SCOPES") {
+		cfg.Device.ClientScopes[clientID] = strings.Fields(scopes)
+	}
+
+	// Introspection and userinfo config
+	cfg.Introspection = IntrospectionConfig{
+		Clients:       getEnvStringMap("AUTH_INTROSPECTION_CLIENTS"),
+		CacheTTL:      time.Duration(getEnvInt("AUTH_INTROSPECTION_CACHE_SECONDS", 30)) * time.Second,
+		UserInfoCache: time.Duration(getEnvInt("AUTH_USERINFO_CACHE_SECONDS", 60)) * time.Second,
+	}
+
+	// Dev identity provider config, never enabled outside the dev profile
+	cfg.DevIdP = DevIdPConfig{
+		Enabled: getEnvBool("AUTH_DEV_IDP_ENABLED", false),
+		BaseURL: strings.TrimSuffix(getEnvString("AUTH_DEV_IDP_BASE_URL", "http://localhost:3030/dev/idp"), "/"),
+		Users:   getEnvStringSlice("AUTH_DEV_IDP_USERS", []string{"dev@example.com"}),
+	}
+	if cfg.DevIdP.Enabled && !cfg.IsDev() {
+		slog.Warn("AUTH_DEV_IDP_ENABLED ignored", "env", cfg.Env)
+		cfg.DevIdP.Enabled = false
+	}
+
+	// Sign-up policy config
+	cfg.Signup = SignupConfig{
+		Mode:           getEnvString("AUTH_SIGNUP_MODE", SignupModeOpen),
+		AllowedDomains: getEnvStringSlice("AUTH_SIGNUP_ALLOWED_DOMAINS", []string{}),
+		HostedDomain:   getEnvString("AUTH_SIGNUP_HOSTED_DOMAIN", ""),
+	}
+
+	// Audit log config
+	cfg.Audit = AuditConfig{
+		Retention: time.Duration(getEnvInt("AUDIT_RETENTION_DAYS", 90)) * 24 * time.Hour,
+	}
+
+	// Server config
+	defaultDrainSeconds := 10
+	if cfg.IsDev() {
+		defaultDrainSeconds = 0
+	}
+	cfg.Server = ServerConfig{
+		ReadTimeout:     time.Duration(getEnvInt("SERVER_READ_TIMEOUT_SECONDS", 30)) * time.Second,
+		WriteTimeout:    time.Duration(getEnvInt("SERVER_WRITE_TIMEOUT_SECONDS", 60)) * time.Second,
+		IdleTimeout:     time.Duration(getEnvInt("SERVER_IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+		DrainPeriod:     time.Duration(getEnvInt("SERVER_DRAIN_SECONDS", defaultDrainSeconds)) * time.Second,
+		ShutdownTimeout: time.Duration(getEnvInt("SERVER_SHUTDOWN_TIMEOUT_SECONDS", 15)) * time.Second,
+	}
+
+	// HTTP config
+	cfg.HTTP = HTTPConfig{
+		ErrorFormat:        getEnvString("HTTP_ERROR_FORMAT", ErrorFormatEnvelope),
+		ProblemTypeBaseURI: getEnvString("HTTP_PROBLEM_TYPE_BASE_URI", defaultProblemTypeBaseURI),
+		DocsEnabled:        getEnvBool("HTTP_DOCS_ENABLED", cfg.IsDev()),
+		ValidateRequests:   getEnvBool("HTTP_VALIDATE_REQUESTS", false),
+		BodyLimit:          int64(getEnvInt("HTTP_BODY_LIMIT_KIB", 1024)) * 1024,
+		Timeout:            time.Duration(getEnvInt("HTTP_TIMEOUT_SECONDS", 30)) * time.Second,
+		RouteTimeouts:      getEnvDurationMap("HTTP_ROUTE_TIMEOUT_SECONDS", time.Second),
+	}
+
+	// CORS config
+	defaultOrigins := []string{}
+	if cfg.IsDev() {
+		defaultOrigins = []string{"http://localhost:*", "http://127.0.0.1:*"}
+	}
+	cfg.CORS = CORSConfig{
+		AllowOrigins:     getEnvStringSlice("CORS_ALLOW_ORIGINS", defaultOrigins),
+		AllowHeaders:     getEnvStringSlice("CORS_ALLOW_HEADERS", []string{}),
+		AllowMethods:     getEnvStringSlice("CORS_ALLOW_METHODS", []string{}),
+		AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", true),
+		MaxAge:           time.Duration(getEnvInt("CORS_MAX_AGE_SECONDS", 3600)) * time.Second,
+	}
+	if len(cfg.CORS.AllowOrigins) == 1 && cfg.CORS.AllowOrigins[0] == "*" && cfg.CORS.AllowCredentials {
+		slog.Warn("CORS_ALLOW_CREDENTIALS ignored with CORS_ALLOW_ORIGINS=*, list the origins to allow credentials")
+		cfg.CORS.AllowCredentials = false
+	}
+
+	// Security headers config
+	defaultHSTSMaxAge := 365 * 24 * 60 * 60
+	if cfg.IsDev() {
+		defaultHSTSMaxAge = 0
+	}
+	cfg.Security = SecurityHeadersConfig{
+		HSTSMaxAge:            time.Duration(getEnvInt("SECURITY_HSTS_MAX_AGE_SECONDS", defaultHSTSMaxAge)) * time.Second,
+		HSTSIncludeSubdomains: getEnvBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true),
+		HSTSPreload:           getEnvBool("SECURITY_HSTS_PRELOAD", false),
+		ContentSecurityPolicy: getEnvString("SECURITY_CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy),
+		CSPReportOnly:         getEnvBool("SECURITY_CSP_REPORT_ONLY", false),
+		FrameOptions:          getEnvString("SECURITY_FRAME_OPTIONS", "DENY"),
+		ReferrerPolicy:        getEnvString("SECURITY_REFERRER_POLICY", "no-referrer"),
+	}
+
+	// Logging config
+	cfg.Log = LogConfig{
+		Format: getEnvString("LOG_FORMAT", "json"),
+		Level:  getEnvString("LOG_LEVEL", "info"),
+	}
+
+	// Rate limit config
+	cfg.RateLimit = RateLimitConfig{
+		Enabled:      getEnvBool("RATE_LIMIT_ENABLED", true),
+		Algorithm:    getEnvString("RATE_LIMIT_ALGORITHM", "sliding_window"),
+		APIRequests:  getEnvInt("RATE_LIMIT_API_REQUESTS", 300),
+		APIWindow:    time.Duration(getEnvInt("RATE_LIMIT_API_WINDOW_SECONDS", 60)) * time.Second,
+		AuthRequests: getEnvInt("RATE_LIMIT_AUTH_REQUESTS", 20),
+		AuthWindow:   time.Duration(getEnvInt("RATE_LIMIT_AUTH_WINDOW_SECONDS", 60)) * time.Second,
+		UserRequests: getEnvInt("RATE_LIMIT_USER_REQUESTS", 120),
+		UserWindow:   time.Duration(getEnvInt("RATE_LIMIT_USER_WINDOW_SECONDS", 60)) * time.Second,
+	}
+
+	// Idempotency config
+	cfg.Idempotency = IdempotencyConfig{
+		TTL: time.Duration(getEnvInt("IDEMPOTENCY_TTL_HOURS", 24)) * time.Hour,
+	}
+
+	// Health config
+	cfg.Health = HealthConfig{
+		Timeout: time.Duration(getEnvInt("HEALTH_CHECK_TIMEOUT_MS", 2000)) * time.Millisecond,
+	}
+
+	// Metrics config
+	cfg.Metrics = MetricsConfig{
+		Enabled: getEnvBool("METRICS_ENABLED", true),
+		Address: getEnvString("METRICS_ADDRESS", ":9090"),
+	}
+
+	// Tracing config
+	cfg.Tracing = TracingConfig{
+		Exporter:    getEnvString("TRACING_EXPORTER", "none"),
+		File:        getEnvString("TRACING_FILE", ""),
+		SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+	}
+
+	// Response cache config
+	cfg.ResponseCache = ResponseCacheConfig{
+		Waitlist: time.Duration(getEnvInt("RESPONSE_CACHE_WAITLIST_TTL_SECONDS", 60)) * time.Second,
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("configuration loaded from environment variables",
+		"database", fmt.Sprintf("%s@%s:%s/%s", cfg.Database.User, cfg.Database.Host, cfg.Database.Port, cfg.Database.Name),
+		"redis", fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port),
+	)
 
 	return cfg, nil
 }
 
+// validate rejects the values the dependencies cannot represent, so a bad
+// setting fails the start instead of being truncated.
+func (c *Config) validate() error {
+	var errs []error
+	if c.Auth.PasswordMemoryKiB < 8*c.Auth.PasswordParallelism || int64(c.Auth.PasswordMemoryKiB) > math.MaxUint32 {
+		errs = append(errs, fmt.Errorf("AUTH_PASSWORD_MEMORY_KIB must be between 8 x AUTH_PASSWORD_PARALLELISM and %d", uint32(math.MaxUint32)))
+	}
+	if c.Auth.PasswordIterations < 1 || int64(c.Auth.PasswordIterations) > math.MaxUint32 {
+		errs = append(errs, fmt.Errorf("AUTH_PASSWORD_ITERATIONS must be between 1 and %d", uint32(math.MaxUint32)))
+	}
+	if c.Auth.PasswordParallelism < 1 || c.Auth.PasswordParallelism > math.MaxUint8 {
+		errs = append(errs, fmt.Errorf("AUTH_PASSWORD_PARALLELISM must be between 1 and %d", math.MaxUint8))
+	}
+	if c.Signup.Mode != SignupModeOpen && c.Signup.Mode != SignupModeInviteOnly {
+		errs = append(errs, fmt.Errorf("AUTH_SIGNUP_MODE must be %s or %s, got %q", SignupModeOpen, SignupModeInviteOnly, c.Signup.Mode))
+	}
+	limits := []struct {
+		name     string
+		requests int
+		window   time.Duration
+	}{
+		{"API", c.RateLimit.APIRequests, c.RateLimit.APIWindow},
+		{"AUTH", c.RateLimit.AuthRequests, c.RateLimit.AuthWindow},
+		{"USER", c.RateLimit.UserRequests, c.RateLimit.UserWindow},
+	}
+	for _, l := range limits {
+		if l.requests < 0 {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_%s_REQUESTS must not be negative", l.name))
+		}
+		// windows are whole seconds, so this also rules out windows under the
+		// millisecond the limiters count in
+		if l.requests > 0 && l.window < time.Second {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_%s_WINDOW_SECONDS must be at least 1", l.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // setDefaults sets default values for viper
 func setDefaults() {
+	viper.SetDefault("APP_ENV", EnvProduction)
+
 	// Database defaults
 	viper.SetDefault("DATABASE_HOST", "postgres")
 	viper.SetDefault("DATABASE_PORT", "5432")
@@ -104,6 +546,7 @@ func setDefaults() {
 	viper.SetDefault("DATABASE_PASSWORD", "")
 	viper.SetDefault("DATABASE_NAME", "postgres")
 	viper.SetDefault("DATABASE_MAX_CONNS", 0)
+	viper.SetDefault("DATABASE_SQL_COMMENTS", true)
 
 	// Redis defaults
 	viper.SetDefault("REDIS_HOST", "redis")
@@ -115,12 +558,104 @@ func setDefaults() {
 	viper.SetDefault("AUTH_JWT_ISSUER", "api-core")
 	viper.SetDefault("AUTH_JWT_SECRET", defaultJWTSecret)
 	viper.SetDefault("AUTH_JWT_EXP_MINUTES", 60)
+	viper.SetDefault("AUTH_ADMIN_EMAILS", "")
+	viper.SetDefault("AUTH_TOKEN_ENCRYPTION_KEY", "")
+	viper.SetDefault("AUTH_PASSWORD_MEMORY_KIB", 19456)
+	viper.SetDefault("AUTH_PASSWORD_ITERATIONS", 2)
+	viper.SetDefault("AUTH_PASSWORD_PARALLELISM", 1)
+	viper.SetDefault("AUTH_FRESH_AUTH_MAX_AGE_SECONDS", 300)
 
 	// Google OAuth defaults
 	viper.SetDefault("GOOGLE_OAUTH_CLIENT_ID", "")
 	viper.SetDefault("GOOGLE_OAUTH_CLIENT_SECRET", "")
 	viper.SetDefault("GOOGLE_OAUTH_REDIRECT_URL", "")
 	viper.SetDefault("GOOGLE_OAUTH_SCOPES", strings.Join(DefaultGoogleScopes(), ","))
+
+	// Device authorization grant defaults
+	viper.SetDefault("AUTH_DEVICE_VERIFICATION_URI", "")
+	viper.SetDefault("AUTH_DEVICE_CODE_TTL_MINUTES", 10)
+	viper.SetDefault("AUTH_DEVICE_POLL_INTERVAL_SECONDS", 5)
+	viper.SetDefault("AUTH_DEVICE_CLIENT_IDS", "")
+	viper.SetDefault("AUTH_DEVICE_CLIENT_SCOPES", "")
+
+	// Introspection defaults
+	viper.SetDefault("AUTH_INTROSPECTION_CLIENTS", "")
+	viper.SetDefault("AUTH_INTROSPECTION_CACHE_SECONDS", 30)
+	viper.SetDefault("AUTH_USERINFO_CACHE_SECONDS", 60)
+
+	// Dev identity provider defaults
+	viper.SetDefault("AUTH_DEV_IDP_ENABLED", false)
+	viper.SetDefault("AUTH_DEV_IDP_BASE_URL", "http://localhost:3030/dev/idp")
+	viper.SetDefault("AUTH_DEV_IDP_USERS", "dev@example.com")
+
+	// Sign-up policy defaults
+	viper.SetDefault("AUTH_SIGNUP_MODE", SignupModeOpen)
+	viper.SetDefault("AUTH_SIGNUP_ALLOWED_DOMAINS", "")
+	viper.SetDefault("AUTH_SIGNUP_HOSTED_DOMAIN", "")
+
+	// Audit log defaults
+	viper.SetDefault("AUDIT_RETENTION_DAYS", 90)
+
+	// Server defaults, SERVER_DRAIN_SECONDS depends on the profile
+	viper.SetDefault("SERVER_READ_TIMEOUT_SECONDS", 30)
+	viper.SetDefault("SERVER_WRITE_TIMEOUT_SECONDS", 60)
+	viper.SetDefault("SERVER_IDLE_TIMEOUT_SECONDS", 120)
+	viper.SetDefault("SERVER_SHUTDOWN_TIMEOUT_SECONDS", 15)
+
+	// HTTP defaults, HTTP_DOCS_ENABLED depends on the profile
+	viper.SetDefault("HTTP_ERROR_FORMAT", ErrorFormatEnvelope)
+	viper.SetDefault("HTTP_PROBLEM_TYPE_BASE_URI", defaultProblemTypeBaseURI)
+	viper.SetDefault("HTTP_VALIDATE_REQUESTS", false)
+	viper.SetDefault("HTTP_BODY_LIMIT_KIB", 1024)
+	viper.SetDefault("HTTP_TIMEOUT_SECONDS", 30)
+	viper.SetDefault("HTTP_ROUTE_TIMEOUT_SECONDS", "")
+
+	// CORS defaults, CORS_ALLOW_ORIGINS depends on the profile
+	viper.SetDefault("CORS_ALLOW_HEADERS", "")
+	viper.SetDefault("CORS_ALLOW_METHODS", "")
+	viper.SetDefault("CORS_ALLOW_CREDENTIALS", true)
+	viper.SetDefault("CORS_MAX_AGE_SECONDS", 3600)
+
+	// Security headers defaults, SECURITY_HSTS_MAX_AGE_SECONDS depends on the
+	// profile
+	viper.SetDefault("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true)
+	viper.SetDefault("SECURITY_HSTS_PRELOAD", false)
+	viper.SetDefault("SECURITY_CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy)
+	viper.SetDefault("SECURITY_CSP_REPORT_ONLY", false)
+	viper.SetDefault("SECURITY_FRAME_OPTIONS", "DENY")
+	viper.SetDefault("SECURITY_REFERRER_POLICY", "no-referrer")
+
+	// Logging defaults
+	viper.SetDefault("LOG_FORMAT", "json")
+	viper.SetDefault("LOG_LEVEL", "info")
+
+	// Rate limit defaults
+	viper.SetDefault("RATE_LIMIT_ENABLED", true)
+	viper.SetDefault("RATE_LIMIT_ALGORITHM", "sliding_window")
+	viper.SetDefault("RATE_LIMIT_API_REQUESTS", 300)
+	viper.SetDefault("RATE_LIMIT_API_WINDOW_SECONDS", 60)
+	viper.SetDefault("RATE_LIMIT_AUTH_REQUESTS", 20)
+	viper.SetDefault("RATE_LIMIT_AUTH_WINDOW_SECONDS", 60)
+	viper.SetDefault("RATE_LIMIT_USER_REQUESTS", 120)
+	viper.SetDefault("RATE_LIMIT_USER_WINDOW_SECONDS", 60)
+
+	// Idempotency defaults
+	viper.SetDefault("IDEMPOTENCY_TTL_HOURS", 24)
+
+	// Health defaults
+	viper.SetDefault("HEALTH_CHECK_TIMEOUT_MS", 2000)
+
+	// Metrics defaults
+	viper.SetDefault("METRICS_ENABLED", true)
+	viper.SetDefault("METRICS_ADDRESS", ":9090")
+
+	// Tracing defaults
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_FILE", "")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1)
+
+	// Response cache defaults
+	viper.SetDefault("RESPONSE_CACHE_WAITLIST_TTL_SECONDS", 60)
 }
 
 // getEnvString gets environment variable as string with fallback
@@ -140,12 +675,40 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	intValue, err := strconv.Atoi(value)
 	if err != nil {
-		log.Printf("Warning: invalid integer value for %s: %s, using default: %d", key, value, defaultValue)
+		slog.Warn("invalid integer value, using default", "key", key, "value", value, "default", defaultValue)
 		return defaultValue
 	}
 	return intValue
 }
 
+// getEnvFloat gets environment variable as float with fallback
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := viper.GetString(key)
+	if value == "" {
+		return defaultValue
+	}
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("invalid float value, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return floatValue
+}
+
+// getEnvBool gets environment variable as bool with fallback
+func getEnvBool(key string, defaultValue bool) bool {
+	value := viper.GetString(key)
+	if value == "" {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("invalid boolean value, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return boolValue
+}
+
 func getEnvStringSlice(key string, fallback []string) []string {
 	value := viper.GetString(key)
 	if value == "" {
@@ -164,6 +727,43 @@ func getEnvStringSlice(key string, fallback []string) []string {
 	return result
 }
 
+// getEnvStringMap parses a comma separated list of key:value pairs
+func getEnvStringMap(key string) map[string]string {
+	result := map[string]string{}
+	for _, pair := range getEnvStringSlice(key, nil) {
+		k, v, ok := strings.Cut(pair, ":")
+		if !ok || k == "" || v == "" {
+			slog.Warn("invalid key:value pair, skipping", "key", key)
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
+
+// getEnvDurationMap parses a comma separated list of key=value pairs, the
+// values are counted in unit. Keys may hold colons, such as route templates.
+func getEnvDurationMap(key string, unit time.Duration) map[string]time.Duration {
+	result := map[string]time.Duration{}
+	for _, pair := range getEnvStringSlice(key, nil) {
+		k, v, ok := cutLast(pair, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if !ok || strings.TrimSpace(k) == "" || err != nil {
+			slog.Warn("invalid key=value pair, skipping", "key", key, "pair", pair)
+			continue
+		}
+		result[strings.TrimSpace(k)] = time.Duration(n) * unit
+	}
+	return result
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
 func DefaultGoogleScopes() []string {
 	return []string{
 		"https://www.googleapis.com/auth/userinfo.email",
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestValidatePasswordParams(t *testing.T) {
+	valid := AuthConfig{PasswordMemoryKiB: 19456, PasswordIterations: 2, PasswordParallelism: 1}
+
+	tests := []struct {
+		name    string
+		modify  func(*AuthConfig)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(*AuthConfig) {}},
+		{name: "memory overflows uint32", modify: func(a *AuthConfig) { a.PasswordMemoryKiB = math.MaxUint32 + 1 }, wantErr: true},
+		{name: "memory below 8 per lane", modify: func(a *AuthConfig) { a.PasswordMemoryKiB = 8; a.PasswordParallelism = 2 }, wantErr: true},
+		{name: "no iterations", modify: func(a *AuthConfig) { a.PasswordIterations = 0 }, wantErr: true},
+		{name: "negative iterations", modify: func(a *AuthConfig) { a.PasswordIterations = -1 }, wantErr: true},
+		{name: "parallelism overflows uint8", modify: func(a *AuthConfig) { a.PasswordParallelism = 256 }, wantErr: true},
+		{name: "no parallelism", modify: func(a *AuthConfig) { a.PasswordParallelism = 0 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Auth: valid, Signup: SignupConfig{Mode: SignupModeOpen}}
+			tt.modify(&cfg.Auth)
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRateLimits(t *testing.T) {
+	valid := RateLimitConfig{
+		APIRequests: 300, APIWindow: time.Minute,
+		AuthRequests: 20, AuthWindow: time.Minute,
+		UserRequests: 120, UserWindow: time.Minute,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*RateLimitConfig)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(*RateLimitConfig) {}},
+		{name: "disabled limit", modify: func(r *RateLimitConfig) { r.AuthRequests = 0; r.AuthWindow = 0 }},
+		{name: "no window", modify: func(r *RateLimitConfig) { r.APIWindow = 0 }, wantErr: true},
+		{name: "negative window", modify: func(r *RateLimitConfig) { r.UserWindow = -time.Second }, wantErr: true},
+		{name: "sub-second window", modify: func(r *RateLimitConfig) { r.AuthWindow = 500 * time.Microsecond }, wantErr: true},
+		{name: "negative requests", modify: func(r *RateLimitConfig) { r.APIRequests = -1 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Auth:      AuthConfig{PasswordMemoryKiB: 19456, PasswordIterations: 2, PasswordParallelism: 1},
+				RateLimit: valid,
+				Signup:    SignupConfig{Mode: SignupModeOpen},
+			}
+			tt.modify(&cfg.RateLimit)
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSignupMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		wantErr bool
+	}{
+		{mode: SignupModeOpen},
+		{mode: SignupModeInviteOnly},
+		// a typo must not fall back to open sign-up
+		{mode: "invite-only", wantErr: true},
+		{mode: "Open", wantErr: true},
+		{mode: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := &Config{
+				Auth:   AuthConfig{PasswordMemoryKiB: 19456, PasswordIterations: 2, PasswordParallelism: 1},
+				Signup: SignupConfig{Mode: tt.mode},
+			}
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
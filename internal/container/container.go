@@ -3,34 +3,161 @@ package container
 import (
 	"api-core/internal/config"
 	"api-core/internal/datastore"
+	"api-core/internal/datastore/auditstore"
+	"api-core/internal/datastore/healthstore"
+	"api-core/internal/datastore/tokenstore"
 	"api-core/internal/datastore/userstore"
+	"api-core/internal/datastore/waitliststore"
 	"api-core/internal/db"
+	"api-core/internal/devidp"
 	"api-core/internal/handler"
+	audithandler "api-core/internal/handler/audit"
 	authhandler "api-core/internal/handler/auth"
+	healthhandler "api-core/internal/handler/health"
+	signuphandler "api-core/internal/handler/signup"
+	userhandler "api-core/internal/handler/user"
+	"api-core/internal/health"
+	auditservice "api-core/internal/service/audit"
 	authservice "api-core/internal/service/auth"
+	"api-core/internal/service/oauthtoken"
+	"api-core/internal/service/signup"
+	userservice "api-core/internal/service/user"
+	"api-core/pkg/audit"
 	appauth "api-core/pkg/auth"
+	"api-core/pkg/cryptox"
+	"api-core/pkg/errorx"
+	httpx "api-core/pkg/httpx_echo"
 	"api-core/pkg/jwtx"
+	"api-core/pkg/logx"
+	"api-core/pkg/metrics"
+	"api-core/pkg/openapi"
+	"api-core/pkg/tracing"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/redis/go-redis/v9"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/do"
 )
 
-func NewContainer(cfg *config.Config) (*do.Injector, error) {
+type options struct {
+	offline bool
+}
+
+type Option func(*options)
+
+// Offline creates the database and redis clients without connecting, for
+// commands that only need the wiring, such as writing the OpenAPI document.
+func Offline() Option {
+	return func(o *options) {
+		o.offline = true
+	}
+}
+
+func NewContainer(cfg *config.Config, opts ...Option) (*do.Injector, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	injector := do.New()
 
 	do.ProvideValue(injector, cfg)
 
-	do.Provide(injector, func(i *do.Injector) (*pgxpool.Pool, error) {
-		return db.NewSQLDB(cfg.Database)
+	logger, err := logx.New(logx.Config{Format: cfg.Log.Format, Level: cfg.Log.Level}, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	// packages without an injected logger, and the log package, use the default
+	slog.SetDefault(logger)
+	do.ProvideValue(injector, logger)
+
+	do.Provide(injector, func(i *do.Injector) (*tracing.Provider, error) {
+		provider, err := tracing.Setup(tracing.Config{
+			Exporter:       cfg.Tracing.Exporter,
+			File:           cfg.Tracing.File,
+			SampleRatio:    cfg.Tracing.SampleRatio,
+			ServiceName:    "api-core",
+			ServiceVersion: apiVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	})
+	// invoked before the router and the clients, their instrumentations read
+	// the global tracer provider when created, and shut down after them
+	if _, err := do.Invoke[*tracing.Provider](injector); err != nil {
+		return nil, err
+	}
+
+	do.Provide(injector, func(i *do.Injector) (*db.Postgres, error) {
+		newPool := db.NewSQLDB
+		if o.offline {
+			newPool = db.NewSQLPool
+		}
+		pool, err := newPool(cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		return &db.Postgres{Pool: pool}, nil
+	})
+	// the stores and transactions run through this pool
+	do.Provide(injector, func(i *do.Injector) (datastore.PGXPool, error) {
+		postgres, err := do.Invoke[*db.Postgres](i)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Database.SQLComments {
+			return datastore.WithSQLComments(postgres.Pool), nil
+		}
+		return postgres.Pool, nil
+	})
+	do.Provide(injector, func(i *do.Injector) (*db.Redis, error) {
+		newClient := db.NewRedis
+		if o.offline {
+			newClient = db.NewRedisClient
+		}
+		client, err := newClient(cfg.Redis)
+		if err != nil {
+			return nil, err
+		}
+		return &db.Redis{Client: client}, nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (db.SharedCache, error) {
+		redisClient := do.MustInvoke[*db.Redis](i).Client
+		return db.NewSharedCacheRedis(redisClient)
 	})
-	do.Provide(injector, func(i *do.Injector) (*redis.Client, error) {
-		return db.NewRedis(cfg.Redis)
+
+	do.Provide(injector, func(i *do.Injector) (*httpx.ResponseCache, error) {
+		return httpx.NewResponseCache(do.MustInvoke[db.SharedCache](i)), nil
+	})
+
+	do.Provide(injector, provideMetrics)
+
+	do.Provide(injector, func(i *do.Injector) (httpx.RateLimiter, error) {
+		algorithm := httpx.RateLimitAlgorithm(cfg.RateLimit.Algorithm)
+		limiter, err := httpx.NewRedisRateLimiter(do.MustInvoke[*db.Redis](i).Client, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		fallback, err := httpx.NewMemoryRateLimiter(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		return httpx.NewFallbackRateLimiter(limiter, fallback, 10*time.Second), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (httpx.IdempotencyStore, error) {
+		return httpx.NewRedisIdempotencyStore(do.MustInvoke[*db.Redis](i).Client), nil
 	})
 
 	do.Provide(injector, func(i *do.Injector) (datastore.TxRunner, error) {
-		pool, err := do.Invoke[*pgxpool.Pool](i)
+		pool, err := do.Invoke[datastore.PGXPool](i)
 		if err != nil {
 			return nil, err
 		}
@@ -38,15 +165,121 @@ func NewContainer(cfg *config.Config) (*do.Injector, error) {
 	})
 
 	do.Provide(injector, func(i *do.Injector) (userstore.Store, error) {
-		pool, err := do.Invoke[*pgxpool.Pool](i)
+		pool, err := do.Invoke[datastore.PGXPool](i)
 		if err != nil {
 			return nil, err
 		}
 		return userstore.New(pool), nil
 	})
 
+	do.Provide(injector, func(i *do.Injector) (waitliststore.Store, error) {
+		pool, err := do.Invoke[datastore.PGXPool](i)
+		if err != nil {
+			return nil, err
+		}
+		return waitliststore.New(pool), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (auditstore.Store, error) {
+		pool, err := do.Invoke[datastore.PGXPool](i)
+		if err != nil {
+			return nil, err
+		}
+		return auditstore.New(pool), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*auditservice.Service, error) {
+		store := do.MustInvoke[auditstore.Store](i)
+		cfg := do.MustInvoke[*config.Config](i)
+		logger := do.MustInvoke[*slog.Logger](i)
+		return auditservice.NewService(store, logger, cfg.Audit), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (audit.Auditor, error) {
+		service := do.MustInvoke[*auditservice.Service](i)
+		if !cfg.Metrics.Enabled {
+			return service, nil
+		}
+		return metrics.NewAuditor(service, do.MustInvoke[*prometheus.Registry](i)), nil
+	})
+
+	// failed authentications are recorded in the background, any client can
+	// trigger them
+	do.Provide(injector, func(i *do.Injector) (*audit.Async, error) {
+		return audit.NewAsync(do.MustInvoke[audit.Auditor](i), 1024), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (healthstore.Store, error) {
+		pool, err := do.Invoke[datastore.PGXPool](i)
+		if err != nil {
+			return nil, err
+		}
+		return healthstore.New(pool), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*healthhandler.Handler, error) {
+		return healthhandler.NewHandler(i), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*audithandler.Handler, error) {
+		service := do.MustInvoke[*auditservice.Service](i)
+		return audithandler.NewHandler(service), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*userservice.Service, error) {
+		store := do.MustInvoke[userstore.Store](i)
+		return userservice.NewService(store), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*userhandler.Handler, error) {
+		service := do.MustInvoke[*userservice.Service](i)
+		return userhandler.NewHandler(service), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (tokenstore.Store, error) {
+		pool, err := do.Invoke[datastore.PGXPool](i)
+		if err != nil {
+			return nil, err
+		}
+		return tokenstore.New(pool), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*signup.Engine, error) {
+		waitlist := do.MustInvoke[waitliststore.Store](i)
+		cfg := do.MustInvoke[*config.Config](i)
+		return signup.NewEngine(cfg.Signup, cfg.Auth.AdminEmails, waitlist, do.MustInvoke[*httpx.ResponseCache](i)), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*signup.Service, error) {
+		waitlist := do.MustInvoke[waitliststore.Store](i)
+		auditor := do.MustInvoke[audit.Auditor](i)
+		return signup.NewService(waitlist, auditor, do.MustInvoke[*httpx.ResponseCache](i)), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*signuphandler.Handler, error) {
+		service := do.MustInvoke[*signup.Service](i)
+		return signuphandler.NewHandler(service), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*devidp.Provider, error) {
+		cfg := do.MustInvoke[*config.Config](i)
+		return devidp.NewProvider(cfg.DevIdP.BaseURL, cfg.DevIdP.Users)
+	})
+
 	do.Provide(injector, func(i *do.Injector) (*appauth.GoogleOAuth, error) {
 		cfg := do.MustInvoke[*config.Config](i)
+		if cfg.DevIdP.Enabled {
+			provider := do.MustInvoke[*devidp.Provider](i)
+			return appauth.NewGoogleOAuth(appauth.GoogleOAuthConfig{
+				ClientID:     devidp.ClientID,
+				ClientSecret: devidp.ClientSecret,
+				RedirectURL:  cfg.Google.RedirectURL,
+				Scopes:       cfg.Google.Scopes,
+				Endpoint:     provider.Endpoint(),
+				UserInfoURL:  provider.UserInfoURL(),
+				Issuers:      []string{provider.Issuer()},
+			})
+		}
 		return appauth.NewGoogleOAuth(appauth.GoogleOAuthConfig{
 			ClientID:     cfg.Google.ClientID,
 			ClientSecret: cfg.Google.ClientSecret,
@@ -60,36 +293,265 @@ func NewContainer(cfg *config.Config) (*do.Injector, error) {
 		return jwtx.NewHMACIssuer(cfg.Auth.JWTSecret, cfg.Auth.JWTIssuer, cfg.Auth.JWTExpiration)
 	})
 
+	do.Provide(injector, func(i *do.Injector) (*oauthtoken.Service, error) {
+		store := do.MustInvoke[tokenstore.Store](i)
+		googleOAuth := do.MustInvoke[*appauth.GoogleOAuth](i)
+		cfg := do.MustInvoke[*config.Config](i)
+
+		var aead *cryptox.AEAD
+		if cfg.Auth.TokenEncryptionKey != "" {
+			var err error
+			if aead, err = cryptox.NewAEADFromBase64(cfg.Auth.TokenEncryptionKey); err != nil {
+				return nil, err
+			}
+		}
+		return oauthtoken.NewService(store, aead, googleOAuth), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (appauth.PasswordHasher, error) {
+		cfg := do.MustInvoke[*config.Config](i)
+		params := appauth.DefaultArgon2Params()
+		// the ranges are checked by config.Load
+		params.Memory = uint32(cfg.Auth.PasswordMemoryKiB)
+		params.Iterations = uint32(cfg.Auth.PasswordIterations)
+		params.Parallelism = uint8(cfg.Auth.PasswordParallelism)
+		hasher, err := appauth.NewArgon2idHasher(params)
+		if err != nil {
+			return nil, err
+		}
+		appauth.SetDefaultHasher(hasher)
+		return hasher, nil
+	})
+
 	do.Provide(injector, func(i *do.Injector) (*authservice.Service, error) {
 		repo := do.MustInvoke[userstore.Store](i)
 		googleOAuth := do.MustInvoke[*appauth.GoogleOAuth](i)
 		tokenIssuer := do.MustInvoke[*jwtx.HMACIssuer](i)
-		redisClient := do.MustInvoke[*redis.Client](i)
+		redisClient := do.MustInvoke[*db.Redis](i).Client
+		signupEngine := do.MustInvoke[*signup.Engine](i)
+		tokens := do.MustInvoke[*oauthtoken.Service](i)
+		auditor := do.MustInvoke[audit.Auditor](i)
+		logger := do.MustInvoke[*slog.Logger](i)
+		cfg := do.MustInvoke[*config.Config](i)
+		return authservice.NewService(repo, googleOAuth, tokenIssuer, redisClient, signupEngine, tokens, auditor, logger, cfg.Google), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*authservice.DeviceService, error) {
+		tokenIssuer := do.MustInvoke[*jwtx.HMACIssuer](i)
+		redisClient := do.MustInvoke[*db.Redis](i).Client
+		auditor := do.MustInvoke[audit.Auditor](i)
+		cfg := do.MustInvoke[*config.Config](i)
+		return authservice.NewDeviceService(tokenIssuer, redisClient, auditor, cfg.Device), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*appauth.Guard, error) {
+		tokenIssuer := do.MustInvoke[*jwtx.HMACIssuer](i)
 		cfg := do.MustInvoke[*config.Config](i)
-		return authservice.NewService(repo, googleOAuth, tokenIssuer, redisClient, cfg.Google), nil
+		warden, err := appauth.NewLadon(appauth.AdminPolicies(cfg.Auth.AdminEmails))
+		if err != nil {
+			return nil, err
+		}
+		auditor := do.MustInvoke[audit.Auditor](i)
+		return appauth.NewGuard(tokenIssuer, warden, auditor)
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*authservice.IntrospectionService, error) {
+		guard := do.MustInvoke[*appauth.Guard](i)
+		repo := do.MustInvoke[userstore.Store](i)
+		cache := do.MustInvoke[db.SharedCache](i)
+		redisClient := do.MustInvoke[*db.Redis](i).Client
+		auditor := do.MustInvoke[audit.Auditor](i)
+		cfg := do.MustInvoke[*config.Config](i)
+		return authservice.NewIntrospectionService(guard, repo, cache, redisClient, auditor, cfg.Introspection), nil
 	})
 
 	do.Provide(injector, func(i *do.Injector) (*authhandler.Handler, error) {
 		service := do.MustInvoke[*authservice.Service](i)
-		return authhandler.NewHandler(service), nil
+		device := do.MustInvoke[*authservice.DeviceService](i)
+		introspection := do.MustInvoke[*authservice.IntrospectionService](i)
+		return authhandler.NewHandler(service, device, introspection), nil
+	})
+
+	do.Provide(injector, func(i *do.Injector) (*openapi.Registry, error) {
+		cfg := do.MustInvoke[*config.Config](i)
+		var kinds []errorx.Kind
+		if cfg.RateLimit.Enabled {
+			kinds = append(kinds, errorx.RateLimiting)
+		}
+		if cfg.HTTP.Timeout > 0 || len(cfg.HTTP.RouteTimeouts) > 0 {
+			kinds = append(kinds, errorx.Timeout)
+		}
+		return openapi.NewRegistry(openapi.Config{
+			Info: openapi.Info{
+				Title:       "api-core",
+				Version:     apiVersion,
+				Description: "Authentication, waitlist and audit API.",
+			},
+			ProblemDetails: cfg.HTTP.ErrorFormat == config.ErrorFormatProblem,
+			Errors:         kinds,
+			Validation:     cfg.HTTP.ValidateRequests,
+		}), nil
 	})
 
 	do.Provide(injector, ProvideRouter)
 
-	if _, err := do.Invoke[*pgxpool.Pool](injector); err != nil {
+	if err := provideHealthChecks(injector, cfg.Health); err != nil {
 		return nil, err
 	}
 
-	if _, err := do.Invoke[*redis.Client](injector); err != nil {
+	// also sets the hasher of auth.Hash, so it must not wait for a consumer
+	if _, err := do.Invoke[appauth.PasswordHasher](injector); err != nil {
+		return nil, err
+	}
+
+	if o.offline {
+		return injector, nil
+	}
+
+	if _, err := do.Invoke[*db.Postgres](injector); err != nil {
+		return nil, err
+	}
+
+	if _, err := do.Invoke[*db.Redis](injector); err != nil {
 		return nil, err
 	}
 
 	return injector, nil
 }
 
+// provideMetrics returns the registry with the pool and cache collectors, the
+// HTTP and business metrics register themselves where they are recorded.
+func provideMetrics(i *do.Injector) (*prometheus.Registry, error) {
+	postgres, err := do.Invoke[*db.Postgres](i)
+	if err != nil {
+		return nil, err
+	}
+	redisClient, err := do.Invoke[*db.Redis](i)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := do.Invoke[db.SharedCache](i)
+	if err != nil {
+		return nil, err
+	}
+
+	reg := metrics.NewRegistry()
+	reg.MustRegister(
+		metrics.NewPGXPoolCollector(postgres.Pool),
+		metrics.NewRedisPoolCollector(redisClient.Client),
+	)
+	if cache, ok := cache.(*db.CacheRedis); ok {
+		reg.MustRegister(metrics.NewCacheCollector("redis", func() metrics.CacheStats {
+			hits, misses := cache.Stats()
+			return metrics.CacheStats{Hits: hits, Misses: misses}
+		}))
+	}
+	return reg, nil
+}
+
+// Shutdown shuts the services down in reverse invocation order, so each one
+// before its dependencies. do.Injector.Shutdown returns at the first failing
+// service and keeps it, it is called again for the following services. A
+// service reports a failed shutdown once, see db.Redis. The failures are
+// logged and returned joined, a second call does nothing.
+func Shutdown(i *do.Injector) error {
+	var errs []error
+	// bounds the retries should a service keep failing
+	for range len(i.ListProvidedServices()) + 1 {
+		err := i.Shutdown()
+		if err == nil {
+			break
+		}
+		slog.Error("container: shutdown", "error", err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// provideHealthChecks registers the /readyz checks, see the health package.
+func provideHealthChecks(i *do.Injector, cfg config.HealthConfig) error {
+	postgres, err := do.Invoke[*db.Postgres](i)
+	if err != nil {
+		return err
+	}
+	redisClient, err := do.Invoke[*db.Redis](i)
+	if err != nil {
+		return err
+	}
+	store, err := do.Invoke[healthstore.Store](i)
+	if err != nil {
+		return err
+	}
+
+	health.Provide(i, &health.Check{Name: "postgres", Timeout: cfg.Timeout, Probe: store.Check})
+	health.Provide(i, &health.Check{Name: "redis", Timeout: cfg.Timeout, Probe: health.Redis(redisClient.Client)})
+	health.Provide(i, &health.Check{Name: "migrations", Timeout: cfg.Timeout, Probe: health.Migrations(postgres.Pool)})
+
+	drain := &health.Drain{}
+	do.ProvideValue(i, drain)
+	health.Provide(i, &health.Check{Name: "shutdown", Timeout: cfg.Timeout, Probe: drain.Probe})
+	return nil
+}
+
+// apiVersion is the version of the /api/v1 contract published in the OpenAPI
+// document.
+const apiVersion = "1.0.0"
+
 func ProvideRouter(i *do.Injector) (http.Handler, error) {
+	cfg := do.MustInvoke[*config.Config](i)
+
+	renderer, err := httpx.NewErrorRenderer(cfg.HTTP.ErrorFormat, cfg.HTTP.ProblemTypeBaseURI)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits handler.RateLimits
+	if cfg.RateLimit.Enabled {
+		limits = handler.RateLimits{
+			API:  httpx.Rate{Requests: cfg.RateLimit.APIRequests, Window: cfg.RateLimit.APIWindow},
+			Auth: httpx.Rate{Requests: cfg.RateLimit.AuthRequests, Window: cfg.RateLimit.AuthWindow},
+			User: httpx.Rate{Requests: cfg.RateLimit.UserRequests, Window: cfg.RateLimit.UserWindow},
+		}
+	}
+
+	var registry *prometheus.Registry
+	if cfg.Metrics.Enabled {
+		registry = do.MustInvoke[*prometheus.Registry](i)
+	}
+
 	return handler.New(&handler.Config{
 		Container: i,
-		Origins:   []string{"*"},
+		CORS: httpx.CORSConfig{
+			AllowOrigins:     cfg.CORS.AllowOrigins,
+			AllowHeaders:     cfg.CORS.AllowHeaders,
+			AllowMethods:     cfg.CORS.AllowMethods,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		},
+		Security: middleware.SecureConfig{
+			ContentTypeNosniff:    "nosniff",
+			XFrameOptions:         cfg.Security.FrameOptions,
+			HSTSMaxAge:            int(cfg.Security.HSTSMaxAge.Seconds()),
+			HSTSExcludeSubdomains: !cfg.Security.HSTSIncludeSubdomains,
+			HSTSPreloadEnabled:    cfg.Security.HSTSPreload,
+			ContentSecurityPolicy: cfg.Security.ContentSecurityPolicy,
+			CSPReportOnly:         cfg.Security.CSPReportOnly,
+			ReferrerPolicy:        cfg.Security.ReferrerPolicy,
+		},
+		BodyLimit:       cfg.HTTP.BodyLimit,
+		Timeouts:        httpx.TimeoutConfig{Default: cfg.HTTP.Timeout, Routes: cfg.HTTP.RouteTimeouts},
+		FreshAuthMaxAge: cfg.Auth.FreshAuthMaxAge,
+		DevIdP:          cfg.DevIdP.Enabled,
+		ErrorRenderer:   renderer,
+		RateLimits:      limits,
+		IdempotencyTTL:  cfg.Idempotency.TTL,
+		CacheTTLs:       handler.CacheTTLs{Waitlist: cfg.ResponseCache.Waitlist},
+		Docs:            cfg.HTTP.DocsEnabled,
+		Validation: handler.Validation{
+			Requests:  cfg.HTTP.ValidateRequests,
+			Responses: cfg.HTTP.ValidateRequests && cfg.IsDev(),
+		},
+		Metrics: registry,
+		Tracing: cfg.Tracing.Exporter != tracing.ExporterNone,
 	})
 }
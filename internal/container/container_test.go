@@ -0,0 +1,62 @@
+package container
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/samber/do"
+)
+
+// service records its shutdown in closed, and reports err the first time like
+// db.Redis.
+type service struct {
+	name   string
+	err    error
+	closed *[]string
+	once   sync.Once
+}
+
+func (s *service) Shutdown() error {
+	var err error
+	s.once.Do(func() {
+		*s.closed = append(*s.closed, s.name)
+		err = s.err
+	})
+	return err
+}
+
+func TestShutdown(t *testing.T) {
+	i := do.New()
+	var closed []string
+	provide := func(name string, err error, deps ...string) {
+		do.ProvideNamed(i, name, func(i *do.Injector) (*service, error) {
+			for _, dep := range deps {
+				do.MustInvokeNamed[*service](i, dep)
+			}
+			return &service{name: name, err: err, closed: &closed}, nil
+		})
+	}
+	errRedis := errors.New("redis: client is closed")
+	errTracing := errors.New("exporter unreachable")
+	provide("tracing", errTracing)
+	provide("postgres", nil, "tracing")
+	provide("redis", errRedis, "tracing")
+	provide("audit", nil, "postgres", "redis")
+	do.MustInvokeNamed[*service](i, "audit")
+
+	err := Shutdown(i)
+	// the failure of redis does not keep postgres and tracing open
+	if want := []string{"audit", "redis", "postgres", "tracing"}; !slices.Equal(closed, want) {
+		t.Fatalf("closed = %v, want %v", closed, want)
+	}
+	if !errors.Is(err, errRedis) || !errors.Is(err, errTracing) {
+		t.Fatalf("err = %v, want both failures", err)
+	}
+
+	closed = nil
+	if err := Shutdown(i); err != nil || len(closed) != 0 {
+		t.Fatalf("second shutdown = %v after closing %v, want nothing to do", err, closed)
+	}
+}
@@ -0,0 +1,142 @@
+package auditstore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bobmodel "api-core/internal/bob"
+	"api-core/internal/datastore"
+
+	"github.com/aarondl/opt/omit"
+	"github.com/aarondl/opt/omitnull"
+	"github.com/stephenafamo/bob"
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/dialect"
+	"github.com/stephenafamo/bob/dialect/psql/dm"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+	"github.com/stephenafamo/bob/types"
+)
+
+type Store interface {
+	Insert(ctx context.Context, params InsertParams) error
+	List(ctx context.Context, params ListParams) ([]*Event, error)
+	// Purge deletes the events that occurred before the given time.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type store struct {
+	exec bob.Executor
+}
+
+func New(pool datastore.PGXPool) Store {
+	return NewWithExecutor(datastore.NewBobExecutor(pool))
+}
+
+func NewWithExecutor(exec bob.Executor) Store {
+	return &store{
+		exec: exec,
+	}
+}
+
+type Event struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	Outcome    string          `json:"outcome"`
+	Resource   string          `json:"resource,omitempty"`
+	IP         *string         `json:"ip,omitempty"`
+	UserAgent  *string         `json:"user_agent,omitempty"`
+	Metadata   json.RawMessage `json:"metadata"`
+}
+
+type InsertParams struct {
+	OccurredAt time.Time
+	Actor      string
+	Action     string
+	Outcome    string
+	Resource   string
+	IP         *string
+	UserAgent  *string
+	Metadata   json.RawMessage
+}
+
+type ListParams struct {
+	// Actor, Action, From and To filter events when set, To is exclusive.
+	Actor  string
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+func (s *store) Insert(ctx context.Context, params InsertParams) error {
+	setter := &bobmodel.AuditEventSetter{
+		OccurredAt: omit.From(params.OccurredAt),
+		Actor:      omit.From(params.Actor),
+		Action:     omit.From(params.Action),
+		Outcome:    omit.From(params.Outcome),
+		Resource:   omit.From(params.Resource),
+		IP:         omitnull.FromPtr(params.IP),
+		UserAgent:  omitnull.FromPtr(params.UserAgent),
+		Metadata:   omit.From(types.NewJSON(params.Metadata)),
+	}
+
+	_, err := bobmodel.AuditEvents.Insert(setter).Exec(ctx, s.exec)
+	return err
+}
+
+func (s *store) List(ctx context.Context, params ListParams) ([]*Event, error) {
+	columns := bobmodel.AuditEvents.Columns
+	mods := []bob.Mod[*dialect.SelectQuery]{
+		sm.OrderBy(columns.OccurredAt).Desc(),
+		sm.OrderBy(columns.ID).Desc(),
+		sm.Limit(params.Limit),
+		sm.Offset(params.Offset),
+	}
+	if params.Actor != "" {
+		mods = append(mods, sm.Where(columns.Actor.EQ(psql.Arg(params.Actor))))
+	}
+	if params.Action != "" {
+		mods = append(mods, sm.Where(columns.Action.EQ(psql.Arg(params.Action))))
+	}
+	if !params.From.IsZero() {
+		mods = append(mods, sm.Where(columns.OccurredAt.GTE(psql.Arg(params.From))))
+	}
+	if !params.To.IsZero() {
+		mods = append(mods, sm.Where(columns.OccurredAt.LT(psql.Arg(params.To))))
+	}
+
+	rows, err := bobmodel.AuditEvents.Query(mods...).All(ctx, s.exec)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*Event, len(rows))
+	for i, row := range rows {
+		events[i] = convertEvent(row)
+	}
+	return events, nil
+}
+
+func (s *store) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return bobmodel.AuditEvents.Delete(
+		dm.Where(bobmodel.AuditEvents.Columns.OccurredAt.LT(psql.Arg(before))),
+	).Exec(ctx, s.exec)
+}
+
+func convertEvent(model *bobmodel.AuditEvent) *Event {
+	return &Event{
+		ID:         model.ID,
+		OccurredAt: model.OccurredAt,
+		Actor:      model.Actor,
+		Action:     model.Action,
+		Outcome:    model.Outcome,
+		Resource:   model.Resource,
+		IP:         model.IP.Ptr(),
+		UserAgent:  model.UserAgent.Ptr(),
+		Metadata:   model.Metadata.Val,
+	}
+}
@@ -0,0 +1,119 @@
+package auditstore
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stephenafamo/scan"
+)
+
+// recordingExec records the statements and returns no rows.
+type recordingExec struct {
+	query string
+	args  []any
+}
+
+func (e *recordingExec) QueryContext(_ context.Context, query string, args ...any) (scan.Rows, error) {
+	e.query, e.args = query, args
+	return emptyRows{}, nil
+}
+
+func (e *recordingExec) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	e.query, e.args = query, args
+	return driver.RowsAffected(3), nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Scan(...any) error          { return nil }
+func (emptyRows) Columns() ([]string, error) { return nil, nil }
+func (emptyRows) Next() bool                 { return false }
+func (emptyRows) Close() error               { return nil }
+func (emptyRows) Err() error                 { return nil }
+
+// clause returns the line of the query starting with the keyword.
+func clause(query, keyword string) string {
+	for _, line := range strings.Split(query, "\n") {
+		if strings.HasPrefix(line, keyword+" ") {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestList(t *testing.T) {
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		params    ListParams
+		wantWhere string
+		wantArgs  []any
+		wantPage  string
+	}{
+		{
+			name:     "no filter",
+			params:   ListParams{Limit: 50},
+			wantPage: "LIMIT 50 OFFSET 0",
+		},
+		{
+			name:      "every filter",
+			params:    ListParams{Actor: "42", Action: "auth.login", From: from, To: to, Limit: 10, Offset: 20},
+			wantWhere: `WHERE ("audit_events"."actor" = $1) AND ("audit_events"."action" = $2) AND ("audit_events"."occurred_at" >= $3) AND ("audit_events"."occurred_at" < $4)`,
+			wantArgs:  []any{"42", "auth.login", from, to},
+			wantPage:  "LIMIT 10 OFFSET 20",
+		},
+		{
+			name:      "open range",
+			params:    ListParams{To: to, Limit: 10},
+			wantWhere: `WHERE ("audit_events"."occurred_at" < $1)`,
+			wantArgs:  []any{to},
+			wantPage:  "LIMIT 10 OFFSET 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := &recordingExec{}
+			events, err := NewWithExecutor(exec).List(context.Background(), tt.params)
+			if err != nil || len(events) != 0 {
+				t.Fatalf("list = %v, %v, want no events", events, err)
+			}
+
+			if where := clause(exec.query, "WHERE"); where != tt.wantWhere {
+				t.Fatalf("where = %s, want %s", where, tt.wantWhere)
+			}
+			// the newest first, the id breaks ties between events of the same instant
+			if order := clause(exec.query, "ORDER BY"); order != `ORDER BY "audit_events"."occurred_at" DESC, "audit_events"."id" DESC` {
+				t.Fatalf("order = %s", order)
+			}
+			if page := clause(exec.query, "LIMIT") + " " + clause(exec.query, "OFFSET"); page != tt.wantPage {
+				t.Fatalf("page = %s, want %s", page, tt.wantPage)
+			}
+			if len(exec.args) != len(tt.wantArgs) || len(exec.args) > 0 && !reflect.DeepEqual(exec.args, tt.wantArgs) {
+				t.Fatalf("args = %#v, want %#v", exec.args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	before := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	exec := &recordingExec{}
+
+	deleted, err := NewWithExecutor(exec).Purge(context.Background(), before)
+	if err != nil || deleted != 3 {
+		t.Fatalf("purge = %d, %v, want 3", deleted, err)
+	}
+	if where := clause(exec.query, "WHERE"); where != `WHERE ("audit_events"."occurred_at" < $1)` {
+		t.Fatalf("where = %s", where)
+	}
+	if !reflect.DeepEqual(exec.args, []any{before}) {
+		t.Fatalf("args = %v, want %v", exec.args, before)
+	}
+}
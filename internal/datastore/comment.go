@@ -0,0 +1,72 @@
+package datastore
+
+import (
+	"context"
+
+	"api-core/pkg/requestid"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// commentedPool appends the request id of the context to every statement as a
+// sqlcommenter comment, see requestid.SQLComment. An annotated statement is
+// unique to its request, it is described on each execution rather than
+// prepared and cached, which would evict the cached statements of every other
+// query.
+type commentedPool struct {
+	PGXPool
+}
+
+// WithSQLComments annotates the statements run through the pool and its
+// transactions with the request id.
+func WithSQLComments(pool PGXPool) PGXPool {
+	return commentedPool{pool}
+}
+
+func (p commentedPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := p.PGXPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return commentedTx{tx}, nil
+}
+
+func (p commentedPool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	sql, args = annotate(ctx, sql, args)
+	return p.PGXPool.Exec(ctx, sql, args...)
+}
+
+func (p commentedPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	sql, args = annotate(ctx, sql, args)
+	return p.PGXPool.Query(ctx, sql, args...)
+}
+
+type commentedTx struct {
+	pgx.Tx
+}
+
+func (t commentedTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	sql, args = annotate(ctx, sql, args)
+	return t.Tx.Exec(ctx, sql, args...)
+}
+
+func (t commentedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	sql, args = annotate(ctx, sql, args)
+	return t.Tx.Query(ctx, sql, args...)
+}
+
+func (t commentedTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	sql, args = annotate(ctx, sql, args)
+	return t.Tx.QueryRow(ctx, sql, args...)
+}
+
+// annotate returns the statement with the comment, run without the statement
+// cache. Statements outside a request are left as they are.
+func annotate(ctx context.Context, sql string, args []any) (string, []any) {
+	annotated := requestid.SQLComment(ctx, sql)
+	if annotated == sql {
+		return sql, args
+	}
+	return annotated, append([]any{pgx.QueryExecModeDescribeExec}, args...)
+}
@@ -0,0 +1,121 @@
+package datastore
+
+import (
+	"context"
+	"testing"
+
+	"api-core/pkg/requestid"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// recordingPool records the statements and arguments it is given.
+type recordingPool struct {
+	pgx.Tx
+	sql  []string
+	args [][]any
+}
+
+func (p *recordingPool) record(sql string, args []any) {
+	p.sql = append(p.sql, sql)
+	p.args = append(p.args, args)
+}
+
+func (p *recordingPool) Begin(context.Context) (pgx.Tx, error) {
+	return p, nil
+}
+
+func (p *recordingPool) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	p.record(sql, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func (p *recordingPool) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	p.record(sql, args)
+	return nil, nil
+}
+
+func (p *recordingPool) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	p.record(sql, args)
+	return nil
+}
+
+func TestWithSQLComments(t *testing.T) {
+	const comment = " /*request_id='req-1'*/"
+	inRequest := requestid.NewContext(context.Background(), requestid.IDs{RequestID: "req-1"})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		run      func(ctx context.Context, pool PGXPool) error
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name: "query",
+			ctx:  inRequest,
+			run: func(ctx context.Context, pool PGXPool) error {
+				_, err := pool.Query(ctx, "SELECT $1", 1)
+				return err
+			},
+			wantSQL:  "SELECT $1" + comment,
+			wantArgs: []any{pgx.QueryExecModeDescribeExec, 1},
+		},
+		{
+			name: "exec",
+			ctx:  inRequest,
+			run: func(ctx context.Context, pool PGXPool) error {
+				_, err := pool.Exec(ctx, "DELETE FROM t")
+				return err
+			},
+			wantSQL:  "DELETE FROM t" + comment,
+			wantArgs: []any{pgx.QueryExecModeDescribeExec},
+		},
+		{
+			name: "transaction",
+			ctx:  inRequest,
+			run: func(ctx context.Context, pool PGXPool) error {
+				tx, err := pool.Begin(ctx)
+				if err != nil {
+					return err
+				}
+				tx.QueryRow(ctx, "SELECT $1", 1)
+				return nil
+			},
+			wantSQL:  "SELECT $1" + comment,
+			wantArgs: []any{pgx.QueryExecModeDescribeExec, 1},
+		},
+		{
+			// keeps using the statement cache
+			name: "outside a request",
+			ctx:  context.Background(),
+			run: func(ctx context.Context, pool PGXPool) error {
+				_, err := pool.Query(ctx, "SELECT $1", 1)
+				return err
+			},
+			wantSQL:  "SELECT $1",
+			wantArgs: []any{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &recordingPool{}
+			if err := tt.run(tt.ctx, WithSQLComments(recorder)); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if len(recorder.sql) != 1 || recorder.sql[0] != tt.wantSQL {
+				t.Fatalf("sql = %q, want %q", recorder.sql, tt.wantSQL)
+			}
+			args := recorder.args[0]
+			if len(args) != len(tt.wantArgs) {
+				t.Fatalf("args = %v, want %v", args, tt.wantArgs)
+			}
+			for i := range args {
+				if args[i] != tt.wantArgs[i] {
+					t.Fatalf("args = %v, want %v", args, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
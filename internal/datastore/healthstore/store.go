@@ -0,0 +1,39 @@
+package healthstore
+
+import (
+	"context"
+
+	bobmodel "api-core/internal/bob"
+	"api-core/internal/datastore"
+
+	"github.com/stephenafamo/bob"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+)
+
+type Store interface {
+	// Check reads the latest health_check row, a round trip proving the
+	// database answers and the schema is migrated.
+	Check(ctx context.Context) error
+}
+
+type store struct {
+	exec bob.Executor
+}
+
+func New(pool datastore.PGXPool) Store {
+	return NewWithExecutor(datastore.NewBobExecutor(pool))
+}
+
+func NewWithExecutor(exec bob.Executor) Store {
+	return &store{
+		exec: exec,
+	}
+}
+
+func (s *store) Check(ctx context.Context) error {
+	_, err := bobmodel.HealthChecks.Query(
+		sm.OrderBy(bobmodel.HealthChecks.Columns.ID).Desc(),
+		sm.Limit(1),
+	).One(ctx, s.exec)
+	return err
+}
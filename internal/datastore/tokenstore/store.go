@@ -0,0 +1,119 @@
+package tokenstore
+
+import (
+	"context"
+	"time"
+
+	bobmodel "api-core/internal/bob"
+	"api-core/internal/datastore"
+
+	"github.com/aarondl/opt/omit"
+	"github.com/aarondl/opt/omitnull"
+	"github.com/stephenafamo/bob"
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/im"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+)
+
+// Store keeps provider tokens per identity, secrets are stored as ciphertext
+// and never decrypted here.
+type Store interface {
+	GetByUser(ctx context.Context, userID int64, provider string) (*Token, error)
+	Upsert(ctx context.Context, params UpsertParams) (*Token, error)
+}
+
+type store struct {
+	exec bob.Executor
+}
+
+func New(pool datastore.PGXPool) Store {
+	return NewWithExecutor(datastore.NewBobExecutor(pool))
+}
+
+func NewWithExecutor(exec bob.Executor) Store {
+	return &store{
+		exec: exec,
+	}
+}
+
+type Token struct {
+	ID           int64
+	UserID       int64
+	Provider     string
+	Subject      string
+	AccessToken  []byte
+	RefreshToken []byte
+	TokenType    string
+	Expiry       *time.Time
+	Scopes       string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type UpsertParams struct {
+	UserID       int64
+	Provider     string
+	Subject      string
+	AccessToken  []byte
+	RefreshToken []byte
+	TokenType    string
+	Expiry       *time.Time
+	Scopes       string
+}
+
+func (s *store) GetByUser(ctx context.Context, userID int64, provider string) (*Token, error) {
+	row, err := bobmodel.OauthTokens.Query(
+		sm.Where(bobmodel.OauthTokens.Columns.UserID.EQ(psql.Arg(userID))),
+		sm.Where(bobmodel.OauthTokens.Columns.Provider.EQ(psql.Arg(provider))),
+	).One(ctx, s.exec)
+	if err != nil {
+		return nil, err
+	}
+	return convertToken(row), nil
+}
+
+// Upsert replaces the token of the identity. A nil refresh token keeps the
+// stored one because providers only return it on the first consent.
+func (s *store) Upsert(ctx context.Context, params UpsertParams) (*Token, error) {
+	setter := &bobmodel.OauthTokenSetter{
+		UserID:      omit.From(params.UserID),
+		Provider:    omit.From(params.Provider),
+		Subject:     omit.From(params.Subject),
+		AccessToken: omit.From(params.AccessToken),
+		TokenType:   omit.From(params.TokenType),
+		Expiry:      omitnull.FromPtr(params.Expiry),
+		Scopes:      omit.From(params.Scopes),
+		UpdatedAt:   omit.From(time.Now().UTC()),
+	}
+
+	updated := []string{"user_id", "access_token", "token_type", "expiry", "scopes", "updated_at"}
+	if params.RefreshToken != nil {
+		setter.RefreshToken = omitnull.From(params.RefreshToken)
+		updated = append(updated, "refresh_token")
+	}
+
+	row, err := bobmodel.OauthTokens.Insert(
+		setter,
+		im.OnConflict("provider", "subject").DoUpdate(im.SetExcluded(updated...)),
+	).One(ctx, s.exec)
+	if err != nil {
+		return nil, err
+	}
+	return convertToken(row), nil
+}
+
+func convertToken(model *bobmodel.OauthToken) *Token {
+	return &Token{
+		ID:           model.ID,
+		UserID:       model.UserID,
+		Provider:     model.Provider,
+		Subject:      model.Subject,
+		AccessToken:  model.AccessToken,
+		RefreshToken: model.RefreshToken.GetOrZero(),
+		TokenType:    model.TokenType,
+		Expiry:       model.Expiry.Ptr(),
+		Scopes:       model.Scopes,
+		CreatedAt:    model.CreatedAt,
+		UpdatedAt:    model.UpdatedAt,
+	}
+}
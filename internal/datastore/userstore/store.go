@@ -7,20 +7,58 @@ import (
 
 	bobmodel "api-core/internal/bob"
 	"api-core/internal/datastore"
+	"api-core/pkg/pagination"
 
 	"github.com/aarondl/opt/omit"
 	"github.com/aarondl/opt/omitnull"
 	"github.com/stephenafamo/bob"
 	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/im"
 	"github.com/stephenafamo/bob/dialect/psql/sm"
 	"github.com/stephenafamo/bob/expr"
 )
 
 type Store interface {
+	GetByID(ctx context.Context, id int64) (*User, error)
 	GetByGoogleID(ctx context.Context, googleID string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
 	UpsertGoogleUser(ctx context.Context, params UpsertGoogleUserParams) (*User, error)
+	List(ctx context.Context, query *pagination.Query[*bobmodel.User]) ([]*User, pagination.Pagination, error)
 }
 
+// ListConfig whitelists the sort and filter fields of List, newest first by
+// default.
+var ListConfig = pagination.MustConfig(pagination.Config[*bobmodel.User]{
+	Fields: []pagination.Field[*bobmodel.User]{
+		{
+			Name: "id", Column: bobmodel.Users.Columns.ID, Type: pagination.Int,
+			Sortable: true, Filters: []pagination.Op{pagination.OpEQ, pagination.OpIn},
+			Value: func(u *bobmodel.User) any { return u.ID },
+		},
+		{
+			Name: "email", Column: bobmodel.Users.Columns.Email, Type: pagination.String,
+			Sortable: true, Filters: []pagination.Op{pagination.OpEQ, pagination.OpContains},
+			Value: func(u *bobmodel.User) any { return u.Email },
+		},
+		{
+			Name: "created_at", Column: bobmodel.Users.Columns.CreatedAt, Type: pagination.Time,
+			Sortable: true, Filters: []pagination.Op{pagination.OpGT, pagination.OpGTE, pagination.OpLT, pagination.OpLTE},
+			Value: func(u *bobmodel.User) any { return u.CreatedAt },
+		},
+		{
+			// nullable, so not sortable
+			Name: "last_login_at", Column: bobmodel.Users.Columns.LastLoginAt, Type: pagination.Time,
+			Filters: []pagination.Op{pagination.OpGT, pagination.OpGTE, pagination.OpLT, pagination.OpLTE},
+		},
+		{
+			Name: "verified_email", Column: bobmodel.Users.Columns.VerifiedEmail, Type: pagination.Bool,
+			Filters: []pagination.Op{pagination.OpEQ},
+		},
+	},
+	Key:  "id",
+	Sort: "-created_at",
+})
+
 type store struct {
 	exec bob.Executor
 }
@@ -58,6 +96,14 @@ type User struct {
 	LastLoginAt   *time.Time
 }
 
+func (s *store) GetByID(ctx context.Context, id int64) (*User, error) {
+	row, err := bobmodel.FindUser(ctx, s.exec, id)
+	if err != nil {
+		return nil, err
+	}
+	return convertUser(row), nil
+}
+
 func (s *store) GetByGoogleID(ctx context.Context, googleID string) (*User, error) {
 	row, err := bobmodel.Users.Query(
 		sm.Where(bobmodel.Users.Columns.GoogleID.EQ(psql.Arg(googleID))),
@@ -68,6 +114,16 @@ func (s *store) GetByGoogleID(ctx context.Context, googleID string) (*User, erro
 	return convertUser(row), nil
 }
 
+func (s *store) GetByEmail(ctx context.Context, email string) (*User, error) {
+	row, err := bobmodel.Users.Query(
+		sm.Where(bobmodel.Users.Columns.Email.EQ(psql.Arg(email))),
+	).One(ctx, s.exec)
+	if err != nil {
+		return nil, err
+	}
+	return convertUser(row), nil
+}
+
 func (s *store) UpsertGoogleUser(ctx context.Context, params UpsertGoogleUserParams) (*User, error) {
 	setter := &bobmodel.UserSetter{
 		GoogleID:      omit.From(params.GoogleID),
@@ -83,7 +139,14 @@ func (s *store) UpsertGoogleUser(ctx context.Context, params UpsertGoogleUserPar
 		return nil, fmt.Errorf("userstore: empty column set for google user %s", params.GoogleID)
 	}
 
-	row, err := bobmodel.Users.Insert(setter).One(ctx, s.exec)
+	// returning users only refresh their profile and last login
+	row, err := bobmodel.Users.Insert(
+		setter,
+		im.OnConflict("google_id").DoUpdate(
+			im.SetExcluded("email", "name", "picture", "locale", "verified_email", "last_login_at"),
+			im.Set(assign(psql.Quote("updated_at"), psql.Raw("NOW()"))),
+		),
+	).One(ctx, s.exec)
 	if err != nil {
 		return nil, err
 	}
@@ -112,3 +175,17 @@ func assign(column bob.Expression, value bob.Expression) bob.Expression {
 		Exprs: []bob.Expression{column, value},
 	}
 }
+
+func (s *store) List(ctx context.Context, query *pagination.Query[*bobmodel.User]) ([]*User, pagination.Pagination, error) {
+	rows, err := bobmodel.Users.Query(query.Mods()...).All(ctx, s.exec)
+	if err != nil {
+		return nil, pagination.Pagination{}, err
+	}
+
+	rows, page := query.Page(rows)
+	users := make([]*User, len(rows))
+	for i, row := range rows {
+		users[i] = convertUser(row)
+	}
+	return users, page, nil
+}
@@ -0,0 +1,177 @@
+package waitliststore
+
+import (
+	"context"
+	"time"
+
+	bobmodel "api-core/internal/bob"
+	"api-core/internal/datastore"
+	"api-core/pkg/errorx"
+
+	"github.com/aarondl/opt/omit"
+	"github.com/aarondl/opt/omitnull"
+	"github.com/stephenafamo/bob"
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/dialect"
+	"github.com/stephenafamo/bob/dialect/psql/im"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+	"github.com/stephenafamo/bob/dialect/psql/um"
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+type Store interface {
+	GetByEmail(ctx context.Context, email string) (*Entry, error)
+	// Join adds the email to the waitlist, an existing entry is returned
+	// untouched and created is false.
+	Join(ctx context.Context, email, provider string) (entry *Entry, created bool, err error)
+	// Invite pre-approves the email, overriding any previous decision.
+	Invite(ctx context.Context, email, invitedBy string) (*Entry, error)
+	Decide(ctx context.Context, id int64, status Status, decidedBy string) (*Entry, error)
+	List(ctx context.Context, params ListParams) ([]*Entry, error)
+}
+
+type store struct {
+	exec bob.Executor
+}
+
+func New(pool datastore.PGXPool) Store {
+	return NewWithExecutor(datastore.NewBobExecutor(pool))
+}
+
+func NewWithExecutor(exec bob.Executor) Store {
+	return &store{
+		exec: exec,
+	}
+}
+
+type Entry struct {
+	ID        int64      `json:"id"`
+	Email     string     `json:"email"`
+	Provider  string     `json:"provider"`
+	Status    Status     `json:"status"`
+	DecidedBy *string    `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type ListParams struct {
+	// Status filters entries, empty lists every entry.
+	Status Status
+	Limit  int
+	Offset int
+}
+
+func (s *store) GetByEmail(ctx context.Context, email string) (*Entry, error) {
+	row, err := bobmodel.SignupWaitlists.Query(
+		sm.Where(bobmodel.SignupWaitlists.Columns.Email.EQ(psql.Arg(email))),
+	).One(ctx, s.exec)
+	if err != nil {
+		return nil, err
+	}
+	return convertEntry(row), nil
+}
+
+func (s *store) Join(ctx context.Context, email, provider string) (*Entry, bool, error) {
+	setter := &bobmodel.SignupWaitlistSetter{
+		Email:    omit.From(email),
+		Provider: omit.From(provider),
+		Status:   omit.From(string(StatusPending)),
+	}
+
+	row, err := bobmodel.SignupWaitlists.Insert(
+		setter,
+		im.OnConflict("email").DoNothing(),
+	).One(ctx, s.exec)
+	if errorx.IsNoRows(err) {
+		// the email is already on the waitlist, nothing was returned
+		entry, err := s.GetByEmail(ctx, email)
+		return entry, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return convertEntry(row), true, nil
+}
+
+func (s *store) Invite(ctx context.Context, email, invitedBy string) (*Entry, error) {
+	now := time.Now().UTC()
+	setter := &bobmodel.SignupWaitlistSetter{
+		Email:     omit.From(email),
+		Provider:  omit.From("invite"),
+		Status:    omit.From(string(StatusApproved)),
+		DecidedBy: omitnull.From(invitedBy),
+		DecidedAt: omitnull.From(now),
+		UpdatedAt: omit.From(now),
+	}
+
+	row, err := bobmodel.SignupWaitlists.Insert(
+		setter,
+		im.OnConflict("email").DoUpdate(im.SetExcluded("status", "decided_by", "decided_at", "updated_at")),
+	).One(ctx, s.exec)
+	if err != nil {
+		return nil, err
+	}
+	return convertEntry(row), nil
+}
+
+func (s *store) Decide(ctx context.Context, id int64, status Status, decidedBy string) (*Entry, error) {
+	now := time.Now().UTC()
+	setter := &bobmodel.SignupWaitlistSetter{
+		Status:    omit.From(string(status)),
+		DecidedBy: omitnull.From(decidedBy),
+		DecidedAt: omitnull.From(now),
+		UpdatedAt: omit.From(now),
+	}
+
+	row, err := bobmodel.SignupWaitlists.Update(
+		setter.UpdateMod(),
+		um.Where(bobmodel.SignupWaitlists.Columns.ID.EQ(psql.Arg(id))),
+	).One(ctx, s.exec)
+	if err != nil {
+		return nil, err
+	}
+	return convertEntry(row), nil
+}
+
+func (s *store) List(ctx context.Context, params ListParams) ([]*Entry, error) {
+	mods := []bob.Mod[*dialect.SelectQuery]{
+		sm.OrderBy(bobmodel.SignupWaitlists.Columns.CreatedAt).Asc(),
+		sm.Limit(params.Limit),
+		sm.Offset(params.Offset),
+	}
+	if params.Status != "" {
+		mods = append(mods, sm.Where(bobmodel.SignupWaitlists.Columns.Status.EQ(psql.Arg(string(params.Status)))))
+	}
+
+	rows, err := bobmodel.SignupWaitlists.Query(mods...).All(ctx, s.exec)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, len(rows))
+	for i, row := range rows {
+		entries[i] = convertEntry(row)
+	}
+	return entries, nil
+}
+
+func convertEntry(model *bobmodel.SignupWaitlist) *Entry {
+	return &Entry{
+		ID:        model.ID,
+		Email:     model.Email,
+		Provider:  model.Provider,
+		Status:    Status(model.Status),
+		DecidedBy: model.DecidedBy.Ptr(),
+		DecidedAt: model.DecidedAt.Ptr(),
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
@@ -14,6 +14,11 @@ type Cache interface {
 	Delete(ctx context.Context, key string) error
 }
 
+// SharedCache is a Cache read and written in Redis only. A local tier keeps
+// values for a minute on each instance, so deletions such as token
+// revocations would not be seen by the other instances until then.
+type SharedCache Cache
+
 func UseCache[T any](ctx context.Context, cash Cache, key string, ttl time.Duration, callback func() (T, error)) (T, error) {
 	var v T
 	err := cash.Get(ctx, key, &v)
@@ -70,9 +75,24 @@ func (c *CacheRedis) Delete(ctx context.Context, key string) error {
 	return c.instance.Delete(ctx, key)
 }
 
+// Stats returns the cumulative hits and misses, local and redis lookups alike.
+func (c *CacheRedis) Stats() (hits, misses uint64) {
+	stats := c.instance.Stats()
+	return stats.Hits, stats.Misses
+}
+
 func NewCacheRedis(client *redis.Client) (*CacheRedis, error) {
 	return &CacheRedis{cache.New(&cache.Options{
-		Redis:      client,
-		LocalCache: cache.NewTinyLFU(10000, time.Minute),
+		Redis:        client,
+		LocalCache:   cache.NewTinyLFU(10000, time.Minute),
+		StatsEnabled: true,
+	})}, nil
+}
+
+// NewSharedCacheRedis returns a cache without the local tier.
+func NewSharedCacheRedis(client *redis.Client) (*CacheRedis, error) {
+	return &CacheRedis{cache.New(&cache.Options{
+		Redis:        client,
+		StatsEnabled: true,
 	})}, nil
 }
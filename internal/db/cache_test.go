@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSharedCacheStats(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache, err := NewSharedCacheRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	if err != nil {
+		t.Fatalf("new cache: %v", err)
+	}
+
+	ctx := context.Background()
+	var v string
+	if _, err := GetCache[string](ctx, cache, "key"); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if err := cache.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := cache.Get(ctx, "key", &v); err != nil || v != "value" {
+		t.Fatalf("get = %q, %v, want value", v, err)
+	}
+
+	if hits, misses := cache.Stats(); hits != 1 || misses != 1 {
+		t.Fatalf("stats = %d hits, %d misses, want 1 and 1", hits, misses)
+	}
+}
+
+func TestRedisShutdown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := &Redis{Client: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+
+	if err := client.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := client.Ping(context.Background()).Err(); err == nil {
+		t.Fatal("expected the client to be closed")
+	}
+	// closing again would fail with redis: client is closed
+	if err := client.Shutdown(); err != nil {
+		t.Fatalf("second shutdown: %v, want nil", err)
+	}
+}
@@ -21,6 +21,9 @@ type DatabaseConfig struct {
 
 	// MaxConns is the maximum number of connections in the pool.
 	MaxConns int `mapstructure:"MaxConns"`
+
+	// SQLComments appends the request id to the statements of the stores.
+	SQLComments bool `mapstructure:"SQLComments"`
 }
 
 // DSN builds a postgres connection string.
@@ -3,67 +3,125 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"runtime"
+	"sync"
 
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
+// Postgres is the pool of the container. It implements do.Shutdownable, the
+// container closes it after every service using it.
+type Postgres struct {
+	*pgxpool.Pool
+}
+
+// Shutdown waits for the acquired connections to be released and closes the
+// pool.
+func (p *Postgres) Shutdown() error {
+	p.Close()
+	return nil
+}
+
+// Redis is the client of the container. It implements do.Shutdownable, the
+// container closes it after every service using it.
+type Redis struct {
+	*redis.Client
+	once sync.Once
+}
+
+// Shutdown closes the client. do keeps a service whose shutdown failed, so
+// only the first call closes and reports the error.
+func (r *Redis) Shutdown() error {
+	var err error
+	r.once.Do(func() {
+		err = r.Close()
+	})
+	return err
+}
+
 // NewSQLDB creates a new SQL DB
 func NewSQLDB(cfg DatabaseConfig) (*pgxpool.Pool, error) {
-	dsn := cfg.DSN()
-	dsnSafe := fmt.Sprintf("postgres://%s:****@%s:%s/%s?sslmode=disable", cfg.User, cfg.Host, cfg.Port, cfg.Name)
+	logger := slog.With("host", cfg.Host, "port", cfg.Port, "database", cfg.Name, "user", cfg.User)
 
-	log.Printf("postgres: initializing pool for %s", dsnSafe)
+	logger.Info("postgres: initializing pool")
+	pool, err := NewSQLPool(cfg)
+	if err != nil {
+		logger.Error("postgres: failed to create pool", "error", err)
+		return nil, err
+	}
 
-	config, err := pgxpool.ParseConfig(dsn)
+	err = pool.Ping(context.Background())
 	if err != nil {
-		log.Printf("postgres: failed to parse DSN %s: %v", dsnSafe, err)
+		logger.Error("postgres: ping failed", "error", err)
 		return nil, err
 	}
 
+	logger.Info("postgres: connected successfully")
+	return pool, err
+}
+
+// NewSQLPool creates the pool without connecting, connections are opened on
+// first use.
+func NewSQLPool(cfg DatabaseConfig) (*pgxpool.Pool, error) {
+	dsn := cfg.DSN()
+
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse DSN: %w", err)
+	}
+
 	max := runtime.NumCPU() * 4
 	if cfg.MaxConns > 0 {
 		max = cfg.MaxConns
 	}
 	config.MaxConns = int32(max)
+	// a span per statement, named by the operation, the statement text is an
+	// attribute without the arguments. The request id is added as an attribute
+	// once the span is started
+	config.ConnConfig.Tracer = multitracer.New(otelpgx.NewTracer(otelpgx.WithTrimSQLInSpanName()), requestIDTracer{})
+
+	return pgxpool.NewWithConfig(context.Background(), config)
+}
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+// NewRedis creates a new REDIS DB
+func NewRedis(cfg RedisConfig) (*redis.Client, error) {
+	redisAddr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
+
+	logger := slog.With("addr", redisAddr)
+
+	logger.Info("redis: initializing client")
+
+	rdb, err := NewRedisClient(cfg)
 	if err != nil {
-		log.Printf("postgres: failed to create pool for %s: %v", dsnSafe, err)
 		return nil, err
 	}
-
-	err = pool.Ping(context.Background())
+	err = rdb.Ping(context.Background()).Err()
 	if err != nil {
-		log.Printf("postgres: ping failed for %s: %v", dsnSafe, err)
+		logger.Error("redis: ping failed", "error", err)
 		return nil, err
 	}
 
-	log.Printf("postgres: connected successfully to %s", dsnSafe)
-	return pool, err
+	logger.Info("redis: connected successfully")
+	return rdb, nil
 }
 
-// NewRedis creates a new REDIS DB
-func NewRedis(cfg RedisConfig) (*redis.Client, error) {
-	redisAddr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
-
-	log.Printf("redis: initializing client for %s", redisAddr)
-
+// NewRedisClient creates the client without connecting.
+func NewRedisClient(cfg RedisConfig) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
+		Addr:     fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
 		Password: cfg.Password,
 		DB:       0,
 	})
-
-	err := rdb.Ping(context.Background()).Err()
-	if err != nil {
-		log.Printf("redis: ping failed for %s: %v", redisAddr, err)
-		return nil, err
+	// commands are traced without their arguments, which hold tokens and
+	// cached values
+	if err := redisotel.InstrumentTracing(rdb, redisotel.WithDBStatement(false)); err != nil {
+		return nil, fmt.Errorf("redis: instrument tracing: %w", err)
 	}
-
-	log.Printf("redis: connected successfully to %s", redisAddr)
 	return rdb, nil
 }
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"api-core/pkg/requestid"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDTracer tags the statement span started by otelpgx with the request
+// id, the statements of the stores also carry it as a comment, see
+// datastore.WithSQLComments.
+type requestIDTracer struct{}
+
+func (requestIDTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	if id := requestid.RequestID(ctx); id != "" {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request_id", id))
+	}
+	return ctx
+}
+
+func (requestIDTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
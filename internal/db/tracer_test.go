@@ -0,0 +1,35 @@
+package db
+
+import (
+	"api-core/pkg/requestid"
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestIDTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	for _, id := range []string{"req-1", ""} {
+		ctx := requestid.NewContext(context.Background(), requestid.IDs{RequestID: id})
+		ctx, span := tracer.Start(ctx, "query")
+		requestIDTracer{}.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+		span.End()
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	attrs := spans[0].Attributes()
+	if len(attrs) != 1 || attrs[0].Key != "request_id" || attrs[0].Value.AsString() != "req-1" {
+		t.Fatalf("attributes = %v, want request_id=req-1", attrs)
+	}
+	if attrs := spans[1].Attributes(); len(attrs) != 0 {
+		t.Fatalf("attributes = %v, want none outside a request", attrs)
+	}
+}
@@ -0,0 +1,365 @@
+package devidp
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"api-core/internal/config"
+	"api-core/internal/datastore/userstore"
+	authservice "api-core/internal/service/auth"
+	"api-core/internal/service/oauthtoken"
+	"api-core/internal/service/signup"
+	appaudit "api-core/pkg/audit"
+	appauth "api-core/pkg/auth"
+	"api-core/pkg/errorx"
+	"api-core/pkg/jwtx"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+func newTestServer(t *testing.T, emails ...string) (*Provider, *appauth.GoogleOAuth) {
+	t.Helper()
+
+	e := echo.New()
+	group := e.Group("/dev/idp")
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+
+	provider, err := NewProvider(srv.URL+"/dev/idp", emails)
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	Register(group, provider)
+
+	googleOAuth, err := appauth.NewGoogleOAuth(appauth.GoogleOAuthConfig{
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		RedirectURL:  "http://app.test/callback",
+		Endpoint:     provider.Endpoint(),
+		UserInfoURL:  provider.UserInfoURL(),
+		Issuers:      []string{provider.Issuer()},
+	})
+	if err != nil {
+		t.Fatalf("new google oauth: %v", err)
+	}
+
+	return provider, googleOAuth
+}
+
+func noRedirectClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func TestLoginFlow(t *testing.T) {
+	_, googleOAuth := newTestServer(t, "alice@example.com", "bob@example.com")
+
+	authURL := googleOAuth.AuthCodeURL("state-1", oauth2.SetAuthURLParam("login_hint", "bob@example.com"))
+	resp, err := noRedirectClient().Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parse redirect: %v", err)
+	}
+	if got := location.Query().Get("state"); got != "state-1" {
+		t.Fatalf("state = %q, want %q", got, "state-1")
+	}
+
+	ctx := context.Background()
+	token, err := googleOAuth.Exchange(ctx, location.Query().Get("code"))
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+
+	info, err := googleOAuth.FetchUserInfo(ctx, token)
+	if err != nil {
+		t.Fatalf("userinfo: %v", err)
+	}
+	if info.Email != "bob@example.com" || !info.VerifiedEmail || info.ID == "" {
+		t.Fatalf("unexpected userinfo: %+v", info)
+	}
+
+	// codes are single use
+	if _, err := googleOAuth.Exchange(ctx, location.Query().Get("code")); err == nil {
+		t.Fatal("expected second exchange to fail")
+	}
+}
+
+func TestLoginPageListsUsers(t *testing.T) {
+	_, googleOAuth := newTestServer(t, "alice@example.com", "bob@example.com")
+
+	resp, err := noRedirectClient().Get(googleOAuth.AuthCodeURL("state-1"))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if !strings.Contains(string(body), email) {
+			t.Fatalf("login page does not list %s", email)
+		}
+	}
+}
+
+func TestAuthorizeRejectsUnknownUser(t *testing.T) {
+	_, googleOAuth := newTestServer(t, "alice@example.com")
+
+	authURL := googleOAuth.AuthCodeURL("state-1", oauth2.SetAuthURLParam("login_hint", "mallory@example.com"))
+	resp, err := noRedirectClient().Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+// memoryUserStore keeps the upserted Google users, the other methods are not
+// used by the login.
+type memoryUserStore struct {
+	userstore.Store
+
+	mu    sync.Mutex
+	users []*userstore.User
+}
+
+func (s *memoryUserStore) GetByGoogleID(_ context.Context, googleID string) (*userstore.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.GoogleID == googleID {
+			return u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *memoryUserStore) UpsertGoogleUser(ctx context.Context, params userstore.UpsertGoogleUserParams) (*userstore.User, error) {
+	u, err := s.GetByGoogleID(ctx, params.GoogleID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		u = &userstore.User{ID: int64(len(s.users) + 1), GoogleID: params.GoogleID}
+		s.users = append(s.users, u)
+	}
+	u.Email = params.Email
+	u.VerifiedEmail = params.VerifiedEmail
+	u.LastLoginAt = &params.LoginAt
+	return u, nil
+}
+
+func newTestAuthService(t *testing.T, googleOAuth *appauth.GoogleOAuth) (*authservice.Service, *memoryUserStore, *jwtx.HMACIssuer) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	issuer, err := jwtx.NewHMACIssuer("test-secret", "api-core", time.Hour)
+	if err != nil {
+		t.Fatalf("new issuer: %v", err)
+	}
+	users := &memoryUserStore{}
+	engine := signup.NewEngine(config.SignupConfig{Mode: config.SignupModeOpen}, nil, nil, nil)
+	svc := authservice.NewService(users, googleOAuth, issuer, client, engine, &oauthtoken.Service{},
+		appaudit.Nop(), slog.New(slog.DiscardHandler), config.GoogleConfig{})
+	return svc, users, issuer
+}
+
+// authorize signs in at the dev IdP and returns the state and code of the
+// callback.
+func authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+
+	resp, err := noRedirectClient().Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parse redirect: %v", err)
+	}
+	return location.Query().Get("state"), location.Query().Get("code")
+}
+
+func TestServiceLoginRoundTrip(t *testing.T) {
+	_, googleOAuth := newTestServer(t, "alice@example.com", "bob@example.com")
+	svc, _, issuer := newTestAuthService(t, googleOAuth)
+
+	ctx := context.Background()
+	loginURL, err := svc.GenerateLoginURL(ctx, "bob@example.com")
+	if err != nil {
+		t.Fatalf("generate login url: %v", err)
+	}
+	state, code := authorize(t, loginURL)
+
+	auth, err := svc.HandleCallback(ctx, state, code)
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if auth.User.Email != "bob@example.com" {
+		t.Fatalf("user = %+v, want bob@example.com", auth.User)
+	}
+
+	_, claims, err := jwtx.Verify(issuer, auth.Token)
+	if err != nil {
+		t.Fatalf("verify token: %v", err)
+	}
+	if claims.Subject != strconv.FormatInt(auth.User.ID, 10) || claims.Email != "bob@example.com" {
+		t.Fatalf("claims = %+v, want the signed in user", claims)
+	}
+	if claims.AuthTime == nil {
+		t.Fatal("token has no auth_time")
+	}
+
+	// the state is single use
+	if _, err := svc.HandleCallback(ctx, state, code); err == nil {
+		t.Fatal("expected the replayed callback to fail")
+	}
+}
+
+func TestReauthRejectsOtherAccount(t *testing.T) {
+	_, googleOAuth := newTestServer(t, "alice@example.com", "bob@example.com")
+	svc, users, _ := newTestAuthService(t, googleOAuth)
+	ctx := context.Background()
+
+	loginURL, err := svc.GenerateLoginURL(ctx, "bob@example.com")
+	if err != nil {
+		t.Fatalf("generate login url: %v", err)
+	}
+	state, code := authorize(t, loginURL)
+	bob, err := svc.HandleCallback(ctx, state, code)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// bob starts a re-authentication but signs in as alice
+	reauthURL, err := svc.GenerateReauthURL(ctx, bob.User.ID, "alice@example.com")
+	if err != nil {
+		t.Fatalf("generate reauth url: %v", err)
+	}
+	state, code = authorize(t, reauthURL)
+	_, err = svc.HandleCallback(ctx, state, code)
+	var target *errorx.Error
+	if !errors.As(err, &target) || !target.Of(errorx.Authz) {
+		t.Fatalf("err = %v, want %s", err, errorx.Authz)
+	}
+	if len(users.users) != 1 {
+		t.Fatalf("users = %d, want 1: the other account must not be created", len(users.users))
+	}
+}
+
+func TestReauthRequiresNewAuthentication(t *testing.T) {
+	provider, googleOAuth := newTestServer(t, "alice@example.com")
+	svc, _, issuer := newTestAuthService(t, googleOAuth)
+	ctx := context.Background()
+
+	loginURL, err := svc.GenerateLoginURL(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("generate login url: %v", err)
+	}
+	state, code := authorize(t, loginURL)
+	login, err := svc.HandleCallback(ctx, state, code)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// the Google session is an hour old
+	sessionAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	provider.mu.Lock()
+	provider.sessions["alice@example.com"] = sessionAt
+	provider.mu.Unlock()
+
+	authTime := func(token string) time.Time {
+		t.Helper()
+		_, claims, err := jwtx.Verify(issuer, token)
+		if err != nil {
+			t.Fatalf("verify token: %v", err)
+		}
+		if claims.AuthTime == nil {
+			t.Fatal("token has no auth_time")
+		}
+		return claims.AuthTime.Time
+	}
+
+	// a plain login reuses the session and reports its time
+	loginURL, err = svc.GenerateLoginURL(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("generate login url: %v", err)
+	}
+	state, code = authorize(t, loginURL)
+	relogin, err := svc.HandleCallback(ctx, state, code)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if got := authTime(relogin.Token); !got.Equal(sessionAt) {
+		t.Fatalf("auth_time = %v, want the session time %v", got, sessionAt)
+	}
+
+	// the provider ignoring prompt=login and max_age=0 is rejected
+	reauthURL, err := svc.GenerateReauthURL(ctx, login.User.ID, "alice@example.com")
+	if err != nil {
+		t.Fatalf("generate reauth url: %v", err)
+	}
+	ignored, err := url.Parse(reauthURL)
+	if err != nil {
+		t.Fatalf("parse reauth url: %v", err)
+	}
+	query := ignored.Query()
+	query.Del("prompt")
+	query.Del("max_age")
+	ignored.RawQuery = query.Encode()
+	state, code = authorize(t, ignored.String())
+	_, err = svc.HandleCallback(ctx, state, code)
+	var target *errorx.Error
+	if !errors.As(err, &target) || !target.Of(errorx.Reauthentication) {
+		t.Fatalf("err = %v, want %s", err, errorx.Reauthentication)
+	}
+
+	// a real re-authentication issues a fresh auth_time
+	reauthURL, err = svc.GenerateReauthURL(ctx, login.User.ID, "alice@example.com")
+	if err != nil {
+		t.Fatalf("generate reauth url: %v", err)
+	}
+	state, code = authorize(t, reauthURL)
+	reauth, err := svc.HandleCallback(ctx, state, code)
+	if err != nil {
+		t.Fatalf("reauth: %v", err)
+	}
+	if got := authTime(reauth.Token); time.Since(got) > time.Minute {
+		t.Fatalf("auth_time = %v, want a fresh one", got)
+	}
+}
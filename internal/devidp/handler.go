@@ -0,0 +1,109 @@
+package devidp
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Dev identity provider</title></head>
+<body>
+<h1>Sign in as a test user</h1>
+<ul>
+{{range .Users}}<li><a href="{{$.Action}}&login_hint={{.Email | urlquery}}">{{.Name}} &lt;{{.Email}}&gt;</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+// Register mounts the authorize, token and userinfo endpoints on the group.
+func Register(group *echo.Group, provider *Provider) {
+	group.GET("/authorize", provider.handleAuthorize)
+	group.POST("/token", provider.handleToken)
+	group.GET("/userinfo", provider.handleUserInfo)
+}
+
+// handleAuthorize shows the test user picker, or redirects straight back to the
+// client when login_hint selects a user, which is what integration tests use.
+func (p *Provider) handleAuthorize(c echo.Context) error {
+	if c.QueryParam("client_id") != ClientID {
+		return c.String(http.StatusBadRequest, "unknown client_id")
+	}
+	if c.QueryParam("response_type") != "code" {
+		return c.String(http.StatusBadRequest, "unsupported response_type")
+	}
+
+	redirectURI := c.QueryParam("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || redirectURI == "" {
+		return c.String(http.StatusBadRequest, "invalid redirect_uri")
+	}
+
+	email := c.QueryParam("login_hint")
+	if email == "" {
+		query := c.QueryParams()
+		query.Del("login_hint")
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+		return loginPage.Execute(c.Response(), map[string]any{
+			"Action": c.Path() + "?" + query.Encode(),
+			"Users":  p.Users(),
+		})
+	}
+
+	login := c.QueryParam("prompt") == "login" || c.QueryParam("max_age") == "0"
+	code, err := p.Authorize(email, redirectURI, login)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	query := target.Query()
+	query.Set("code", code)
+	query.Set("state", c.QueryParam("state"))
+	target.RawQuery = query.Encode()
+	return c.Redirect(http.StatusFound, target.String())
+}
+
+func (p *Provider) handleToken(c echo.Context) error {
+	clientID, clientSecret, ok := c.Request().BasicAuth()
+	if !ok {
+		clientID, clientSecret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+	if clientID != ClientID || clientSecret != ClientSecret {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+	}
+	if c.FormValue("grant_type") != "authorization_code" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+	}
+
+	token, err := p.Exchange(c.FormValue("code"), c.FormValue("redirect_uri"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"access_token":  token.AccessToken,
+		"token_type":    token.TokenType,
+		"refresh_token": token.RefreshToken,
+		"expires_in":    int(time.Until(token.Expiry).Seconds()),
+		"id_token":      token.Extra("id_token"),
+	})
+}
+
+func (p *Provider) handleUserInfo(c echo.Context) error {
+	accessToken, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+	}
+
+	user, err := p.UserInfo(accessToken)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+	}
+	return c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,214 @@
+// Package devidp is a fake OAuth 2.0 identity provider that mimics the subset of
+// Google used by the auth service, so sign-in works offline in dev and tests.
+package devidp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	appauth "api-core/pkg/auth"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+)
+
+// ClientID and ClientSecret are the fixed credentials the dev IdP accepts.
+const (
+	ClientID     = "dev-idp-client"
+	ClientSecret = "dev-idp-secret"
+)
+
+const (
+	codeTTL  = 5 * time.Minute
+	tokenTTL = time.Hour
+)
+
+var (
+	ErrUnknownUser  = errors.New("devidp: unknown test user")
+	ErrInvalidCode  = errors.New("devidp: invalid or expired code")
+	ErrInvalidToken = errors.New("devidp: invalid or expired token")
+)
+
+type grant struct {
+	email       string
+	redirectURI string
+	authTime    time.Time
+	expiresAt   time.Time
+}
+
+// Provider keeps codes and tokens in memory, it is meant for a single process.
+type Provider struct {
+	baseURL string
+	users   map[string]*appauth.GoogleUserInfo
+	emails  []string
+
+	mu     sync.Mutex
+	codes  map[string]grant
+	tokens map[string]grant
+	// sessions holds the last authentication of each user, reused like a
+	// Google session unless the client forces a new login.
+	sessions map[string]time.Time
+}
+
+func NewProvider(baseURL string, emails []string) (*Provider, error) {
+	if len(emails) == 0 {
+		return nil, errors.New("devidp: at least one test user is required")
+	}
+
+	users := make(map[string]*appauth.GoogleUserInfo, len(emails))
+	for _, email := range emails {
+		users[email] = newTestUser(email)
+	}
+
+	return &Provider{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		users:    users,
+		emails:   emails,
+		codes:    map[string]grant{},
+		tokens:   map[string]grant{},
+		sessions: map[string]time.Time{},
+	}, nil
+}
+
+// Endpoint returns the OAuth endpoints to plug into appauth.GoogleOAuthConfig.
+func (p *Provider) Endpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:   p.baseURL + "/authorize",
+		TokenURL:  p.baseURL + "/token",
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+}
+
+// UserInfoURL returns the userinfo endpoint to plug into appauth.GoogleOAuthConfig.
+func (p *Provider) UserInfoURL() string {
+	return p.baseURL + "/userinfo"
+}
+
+// Issuer returns the iss of the id_tokens to plug into appauth.GoogleOAuthConfig.
+func (p *Provider) Issuer() string {
+	return p.baseURL
+}
+
+// Users lists the test accounts in configuration order.
+func (p *Provider) Users() []*appauth.GoogleUserInfo {
+	users := make([]*appauth.GoogleUserInfo, 0, len(p.emails))
+	for _, email := range p.emails {
+		users = append(users, p.users[email])
+	}
+	return users
+}
+
+// Authorize issues an authorization code for the selected test user. The
+// user's session is reused unless login forces a new authentication, as
+// prompt=login or max_age=0 do at Google.
+func (p *Provider) Authorize(email, redirectURI string, login bool) (string, error) {
+	if _, ok := p.users[email]; !ok {
+		return "", ErrUnknownUser
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	authTime, ok := p.sessions[email]
+	if !ok || login {
+		authTime = now
+		p.sessions[email] = now
+	}
+	p.codes[code] = grant{email: email, redirectURI: redirectURI, authTime: authTime, expiresAt: now.Add(codeTTL)}
+	return code, nil
+}
+
+// Exchange redeems an authorization code for an access token, codes are single use.
+func (p *Provider) Exchange(code, redirectURI string) (*oauth2.Token, error) {
+	p.mu.Lock()
+	g, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(g.expiresAt) || g.redirectURI != redirectURI {
+		return nil, ErrInvalidCode
+	}
+
+	accessToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(tokenTTL)
+	idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":       p.baseURL,
+		"sub":       p.users[g.email].ID,
+		"aud":       ClientID,
+		"email":     g.email,
+		"iat":       now.Unix(),
+		"exp":       expiresAt.Unix(),
+		"auth_time": g.authTime.Unix(),
+	}).SignedString([]byte(ClientSecret))
+	if err != nil {
+		return nil, fmt.Errorf("devidp: sign id_token: %w", err)
+	}
+
+	p.mu.Lock()
+	p.tokens[accessToken] = grant{email: g.email, expiresAt: expiresAt}
+	p.mu.Unlock()
+
+	token := &oauth2.Token{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		RefreshToken: refreshToken,
+		Expiry:       expiresAt,
+	}
+	return token.WithExtra(map[string]any{"id_token": idToken}), nil
+}
+
+// UserInfo resolves the profile behind an access token.
+func (p *Provider) UserInfo(accessToken string) (*appauth.GoogleUserInfo, error) {
+	p.mu.Lock()
+	g, ok := p.tokens[accessToken]
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(g.expiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return p.users[g.email], nil
+}
+
+// newTestUser derives a stable profile from the email so the same test user
+// maps to the same row across runs.
+func newTestUser(email string) *appauth.GoogleUserInfo {
+	sum := sha256.Sum256([]byte(email))
+	name, _, _ := strings.Cut(email, "@")
+	return &appauth.GoogleUserInfo{
+		ID:            "dev-" + hex.EncodeToString(sum[:8]),
+		Email:         email,
+		VerifiedEmail: true,
+		Name:          name,
+		GivenName:     name,
+		Locale:        "en",
+	}
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("devidp: generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
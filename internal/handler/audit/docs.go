@@ -0,0 +1,27 @@
+package audit
+
+import (
+	"time"
+
+	"api-core/internal/datastore/auditstore"
+	"api-core/pkg/errorx"
+	"api-core/pkg/openapi"
+)
+
+type listEventsQuery struct {
+	Actor  string    `query:"actor"`
+	Action string    `query:"action"`
+	From   time.Time `query:"from"`
+	To     time.Time `query:"to"`
+	Limit  int       `query:"limit" validate:"omitempty,min=1"`
+	Offset int       `query:"offset" validate:"omitempty,min=0"`
+}
+
+var ListEventsDoc = openapi.Spec{
+	Summary:  "List audit events",
+	Tags:     []string{"audit"},
+	Query:    listEventsQuery{},
+	Response: []auditstore.Event{},
+	Errors:   []errorx.Kind{errorx.Invalid, errorx.Authz},
+	Security: []string{openapi.SecurityBearer},
+}
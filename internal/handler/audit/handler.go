@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"api-core/internal/datastore/auditstore"
+	auditservice "api-core/internal/service/audit"
+	httpx "api-core/pkg/httpx_echo"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	service *auditservice.Service
+}
+
+func NewHandler(service *auditservice.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ListEvents returns audit events, newest first, filtered by actor, action and
+// an RFC 3339 from/to time range.
+func (h *Handler) ListEvents(c echo.Context) error {
+	query, err := httpx.BindAndValidate[listEventsQuery](c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+
+	events, err := h.service.List(c.Request().Context(), auditstore.ListParams{
+		Actor:  query.Actor,
+		Action: query.Action,
+		From:   query.From,
+		To:     query.To,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	})
+	return httpx.RestAbort(c, events, err)
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	authservice "api-core/internal/service/auth"
+	appauth "api-core/pkg/auth"
+	httpx "api-core/pkg/httpx_echo"
+
+	"github.com/labstack/echo/v4"
+)
+
+type deviceConfirmRequest struct {
+	UserCode string `json:"user_code" form:"user_code" validate:"required"`
+	Approve  bool   `json:"approve" form:"approve"`
+}
+
+// DeviceAuthorize issues a device and user code pair (RFC 8628 section 3.2).
+// The response follows the RFC shape so standard OAuth clients can consume it.
+func (h *Handler) DeviceAuthorize(c echo.Context) error {
+	resp, err := h.device.Authorize(c.Request().Context(), c.FormValue("client_id"), c.FormValue("scope"))
+	if err != nil {
+		return oauthAbort(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeviceToken is polled by the device until the user completes verification.
+func (h *Handler) DeviceToken(c echo.Context) error {
+	resp, err := h.device.Token(
+		c.Request().Context(),
+		c.FormValue("grant_type"),
+		c.FormValue("device_code"),
+		c.FormValue("client_id"),
+	)
+	if err != nil {
+		return oauthAbort(c, err)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeviceLookup returns the pending request so the verification page can show it.
+func (h *Handler) DeviceLookup(c echo.Context) error {
+	query, err := httpx.BindAndValidate[deviceLookupQuery](c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	resp, err := h.device.Lookup(c.Request().Context(), query.UserCode)
+	return httpx.RestAbort(c, resp, err)
+}
+
+// DeviceConfirm approves or denies a device request on behalf of the signed in user.
+func (h *Handler) DeviceConfirm(c echo.Context) error {
+	req, err := httpx.BindAndValidate[deviceConfirmRequest](c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+
+	ctx := c.Request().Context()
+	subject, err := appauth.ResolveValidSubject(ctx)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+
+	err = h.device.Confirm(ctx, req.UserCode, subject, appauth.ResolveEmail(ctx), req.Approve)
+	return httpx.RestAbort(c, deviceConfirmResponse{Approved: req.Approve}, err)
+}
+
+func oauthAbort(c echo.Context, err error) error {
+	var oauthErr *authservice.OAuthError
+	if !errors.As(err, &oauthErr) {
+		slog.ErrorContext(c.Request().Context(), "device flow failed", "error", err)
+		return c.JSON(http.StatusInternalServerError, &authservice.OAuthError{Code: "server_error"})
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == authservice.OAuthErrInvalidClient {
+		status = http.StatusUnauthorized
+	}
+	return c.JSON(status, oauthErr)
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	authservice "api-core/internal/service/auth"
+	"api-core/pkg/errorx"
+	"api-core/pkg/openapi"
+)
+
+type urlResponse struct {
+	URL string `json:"url"`
+}
+
+type deviceConfirmResponse struct {
+	Approved bool `json:"approved"`
+}
+
+type logoutResponse struct {
+	Revoked bool `json:"revoked"`
+}
+
+type googleLoginQuery struct {
+	LoginHint string `query:"login_hint" validate:"omitempty,email"`
+}
+
+type googleCallbackQuery struct {
+	State string `query:"state" validate:"required"`
+	Code  string `query:"code" validate:"required"`
+}
+
+type googleConsentQuery struct {
+	// Scope lists Google scopes separated by spaces or commas.
+	Scope string `query:"scope" validate:"required"`
+}
+
+type deviceLookupQuery struct {
+	UserCode string `query:"user_code" validate:"required"`
+}
+
+type deviceAuthorizeForm struct {
+	ClientID string `form:"client_id" validate:"required"`
+	Scope    string `form:"scope,omitempty"`
+}
+
+type deviceTokenForm struct {
+	GrantType  string `form:"grant_type" validate:"required,oneof=urn:ietf:params:oauth:grant-type:device_code"`
+	DeviceCode string `form:"device_code" validate:"required"`
+	ClientID   string `form:"client_id" validate:"required"`
+}
+
+type introspectForm struct {
+	Token string `form:"token" validate:"required"`
+	// ClientID and ClientSecret are accepted when basic auth is not used.
+	ClientID     string `form:"client_id,omitempty"`
+	ClientSecret string `form:"client_secret,omitempty"`
+}
+
+var (
+	GoogleLoginDoc = openapi.Spec{
+		Summary:  "Google sign in URL",
+		Tags:     []string{"auth"},
+		Query:    googleLoginQuery{},
+		Response: urlResponse{},
+	}
+	GoogleCallbackDoc = openapi.Spec{
+		Summary:     "Complete the Google sign in",
+		Description: "Exchanges the authorization code and issues an API token. Unknown users join the waitlist.",
+		Tags:        []string{"auth"},
+		Query:       googleCallbackQuery{},
+		Response:    authservice.AuthResponse{},
+		Errors:      []errorx.Kind{errorx.Invalid, errorx.Authn, errorx.Authz, errorx.Reauthentication},
+	}
+	GoogleConsentDoc = openapi.Spec{
+		Summary:  "Google URL granting additional scopes",
+		Tags:     []string{"auth"},
+		Query:    googleConsentQuery{},
+		Response: urlResponse{},
+		Errors:   []errorx.Kind{errorx.Invalid},
+		Security: []string{openapi.SecurityBearer},
+	}
+	GoogleReauthDoc = openapi.Spec{
+		Summary:     "Google URL to authenticate again",
+		Description: "The callback issues a token accepted by routes requiring a recent authentication.",
+		Tags:        []string{"auth"},
+		Response:    urlResponse{},
+		Security:    []string{openapi.SecurityBearer},
+	}
+
+	DeviceAuthorizeDoc = openapi.Spec{
+		Summary:   "Start the device authorization flow (RFC 8628)",
+		Tags:      []string{"device"},
+		Form:      deviceAuthorizeForm{},
+		Response:  authservice.DeviceAuthorization{},
+		Raw:       true,
+		Errors:    []errorx.Kind{errorx.Invalid, errorx.Authn},
+		ErrorBody: authservice.OAuthError{},
+	}
+	DeviceTokenDoc = openapi.Spec{
+		Summary:     "Poll for the device access token",
+		Description: "Returns authorization_pending until the user confirms the request.",
+		Tags:        []string{"device"},
+		Form:        deviceTokenForm{},
+		Response:    authservice.DeviceTokenResponse{},
+		Raw:         true,
+		Errors:      []errorx.Kind{errorx.Invalid, errorx.Authn},
+		ErrorBody:   authservice.OAuthError{},
+	}
+	DeviceLookupDoc = openapi.Spec{
+		Summary:  "Pending device request",
+		Tags:     []string{"device"},
+		Query:    deviceLookupQuery{},
+		Response: authservice.DeviceRequest{},
+		Errors:   []errorx.Kind{errorx.NotExist},
+		Security: []string{openapi.SecurityBearer},
+	}
+	DeviceConfirmDoc = openapi.Spec{
+		Summary:  "Approve or deny a device request",
+		Tags:     []string{"device"},
+		Request:  deviceConfirmRequest{},
+		Response: deviceConfirmResponse{},
+		Errors:   []errorx.Kind{errorx.NotExist, errorx.Exist, errorx.Reauthentication},
+		Security: []string{openapi.SecurityBearer},
+	}
+
+	IntrospectDoc = openapi.Spec{
+		Summary:   "Token introspection (RFC 7662)",
+		Tags:      []string{"oauth"},
+		Form:      introspectForm{},
+		Response:  authservice.Introspection{},
+		Raw:       true,
+		Errors:    []errorx.Kind{errorx.Invalid, errorx.Authn},
+		ErrorBody: authservice.OAuthError{},
+		Security:  []string{openapi.SecurityBasic},
+	}
+	UserInfoDoc = openapi.Spec{
+		Summary:  "OIDC userinfo of the token owner",
+		Tags:     []string{"oauth"},
+		Response: authservice.UserInfo{},
+		Raw:      true,
+		Errors:   []errorx.Kind{errorx.NotExist},
+		Security: []string{openapi.SecurityBearer},
+	}
+	LogoutDoc = openapi.Spec{
+		Summary:  "Revoke the bearer token",
+		Tags:     []string{"auth"},
+		Response: logoutResponse{},
+		Security: []string{openapi.SecurityBearer},
+	}
+)
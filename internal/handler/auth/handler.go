@@ -1,30 +1,87 @@
 package auth
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+
 	authservice "api-core/internal/service/auth"
+	appauth "api-core/pkg/auth"
+	"api-core/pkg/errorx"
 	httpx "api-core/pkg/httpx_echo"
 
 	"github.com/labstack/echo/v4"
 )
 
 type Handler struct {
-	service *authservice.Service
+	service       *authservice.Service
+	device        *authservice.DeviceService
+	introspection *authservice.IntrospectionService
 }
 
-func NewHandler(service *authservice.Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(
+	service *authservice.Service,
+	device *authservice.DeviceService,
+	introspection *authservice.IntrospectionService,
+) *Handler {
+	return &Handler{service: service, device: device, introspection: introspection}
 }
 
 func (h *Handler) GoogleLogin(c echo.Context) error {
-	url, err := h.service.GenerateLoginURL(c.Request().Context())
-	return httpx.RestAbort(c, map[string]string{
-		"url": url,
-	}, err)
+	query, err := httpx.BindAndValidate[googleLoginQuery](c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	url, err := h.service.GenerateLoginURL(c.Request().Context(), query.LoginHint)
+	return httpx.RestAbort(c, urlResponse{URL: url}, err)
 }
 
 func (h *Handler) GoogleCallback(c echo.Context) error {
-	state := c.QueryParam("state")
-	code := c.QueryParam("code")
-	resp, err := h.service.HandleCallback(c.Request().Context(), state, code)
+	query, err := httpx.BindAndValidate[googleCallbackQuery](c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	resp, err := h.service.HandleCallback(c.Request().Context(), query.State, query.Code)
 	return httpx.RestAbort(c, resp, err)
 }
+
+// GoogleConsent returns the URL asking the signed in user for additional Google
+// scopes, passed as a space or comma separated scope query param.
+func (h *Handler) GoogleConsent(c echo.Context) error {
+	ctx := c.Request().Context()
+	subject, err := appauth.ResolveValidSubject(ctx)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	userID, err := strconv.ParseInt(subject, 10, 64)
+	if err != nil {
+		return httpx.RestAbort(c, nil, errorx.Wrap(errors.New("invalid subject"), errorx.Authn))
+	}
+	query, err := httpx.BindAndValidate[googleConsentQuery](c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+
+	scopes := strings.FieldsFunc(query.Scope, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	url, err := h.service.GenerateConsentURL(ctx, userID, scopes)
+	return httpx.RestAbort(c, urlResponse{URL: url}, err)
+}
+
+// GoogleReauth returns the URL to sign in with Google again, the callback issues
+// a token accepted by routes requiring a recent authentication.
+func (h *Handler) GoogleReauth(c echo.Context) error {
+	ctx := c.Request().Context()
+	subject, err := appauth.ResolveValidSubject(ctx)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	userID, err := strconv.ParseInt(subject, 10, 64)
+	if err != nil {
+		return httpx.RestAbort(c, nil, errorx.Wrap(errors.New("invalid subject"), errorx.Authn))
+	}
+
+	url, err := h.service.GenerateReauthURL(ctx, userID, appauth.ResolveEmail(ctx))
+	return httpx.RestAbort(c, urlResponse{URL: url}, err)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"net/http"
+
+	appauth "api-core/pkg/auth"
+	httpx "api-core/pkg/httpx_echo"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Introspect implements RFC 7662 for machine clients authenticated with
+// HTTP basic auth or client_id/client_secret form parameters.
+func (h *Handler) Introspect(c echo.Context) error {
+	clientID, clientSecret, ok := c.Request().BasicAuth()
+	if !ok {
+		clientID, clientSecret = c.FormValue("client_id"), c.FormValue("client_secret")
+	}
+	if err := h.introspection.AuthenticateClient(clientID, clientSecret); err != nil {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="introspection"`)
+		return oauthAbort(c, err)
+	}
+
+	resp, err := h.introspection.Introspect(c.Request().Context(), c.FormValue("token"))
+	if err != nil {
+		return oauthAbort(c, err)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, resp)
+}
+
+// UserInfo returns the OIDC userinfo claims of the bearer token owner.
+func (h *Handler) UserInfo(c echo.Context) error {
+	ctx := c.Request().Context()
+	resp, err := h.introspection.UserInfo(ctx, appauth.ResolveClaims(ctx))
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Logout revokes the bearer token so introspection reports it inactive.
+func (h *Handler) Logout(c echo.Context) error {
+	ctx := c.Request().Context()
+	err := h.introspection.Revoke(ctx, appauth.ResolveClaims(ctx))
+	return httpx.RestAbort(c, logoutResponse{Revoked: err == nil}, err)
+}
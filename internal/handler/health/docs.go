@@ -0,0 +1,22 @@
+package health
+
+import (
+	apphealth "api-core/internal/health"
+	"api-core/pkg/openapi"
+)
+
+var (
+	LiveDoc = openapi.Spec{
+		Summary:  "Liveness probe",
+		Tags:     []string{"meta"},
+		Response: apphealth.Report{},
+		Raw:      true,
+	}
+	ReadyDoc = openapi.Spec{
+		Summary:     "Readiness probe",
+		Description: "Checks Postgres, Redis and the migrations, and fails while the server drains before shutting down. Answers 503 with the same body when a check fails.",
+		Tags:        []string{"meta"},
+		Response:    apphealth.Report{},
+		Raw:         true,
+	}
+)
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"net/http"
+
+	apphealth "api-core/internal/health"
+
+	"github.com/labstack/echo/v4"
+	"github.com/samber/do"
+)
+
+type Handler struct {
+	injector *do.Injector
+}
+
+func NewHandler(injector *do.Injector) *Handler {
+	return &Handler{injector: injector}
+}
+
+// Live reports that the process serves requests, it checks no dependency so a
+// database outage does not restart every instance.
+func (h *Handler) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, apphealth.Report{Status: apphealth.StatusOK})
+}
+
+// Ready runs the dependency checks, a failing check answers 503 so the
+// instance is taken out of the load balancer.
+func (h *Handler) Ready(c echo.Context) error {
+	report := apphealth.Ready(h.injector)
+	status := http.StatusOK
+	if report.Status != apphealth.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(status, report)
+}
@@ -1,81 +1,328 @@
 package handler
 
 import (
+	"api-core/internal/devidp"
+	audithandler "api-core/internal/handler/audit"
 	authhandler "api-core/internal/handler/auth"
+	healthhandler "api-core/internal/handler/health"
+	signuphandler "api-core/internal/handler/signup"
+	userhandler "api-core/internal/handler/user"
+	authservice "api-core/internal/service/auth"
+	signupservice "api-core/internal/service/signup"
+	"api-core/pkg/audit"
+	"api-core/pkg/auth"
+	httpx "api-core/pkg/httpx_echo"
+	"api-core/pkg/metrics"
+	"api-core/pkg/openapi"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/samber/do"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
 type Config struct {
 	Container *do.Injector
 
-	Origins []string
+	// CORS applies to every route, no origins disables it.
+	CORS httpx.CORSConfig
+	// Security sets the security headers of every response.
+	Security middleware.SecureConfig
+	// BodyLimit rejects larger request bodies with a 413, zero disables it.
+	BodyLimit int64
+	// Timeouts bound the /api/v1 handlers.
+	Timeouts httpx.TimeoutConfig
+
+	// FreshAuthMaxAge is how recent the sign-in must be on the sensitive
+	// routes, see httpx.RequireFreshAuth.
+	FreshAuthMaxAge time.Duration
+
+	// DevIdP mounts the fake identity provider, only set in the dev profile.
+	DevIdP bool
+
+	// ErrorRenderer formats error responses, defaults to the envelope body.
+	ErrorRenderer httpx.ErrorRenderer
+
+	RateLimits RateLimits
+
+	// IdempotencyTTL is how long responses to Idempotency-Key requests are
+	// replayed.
+	IdempotencyTTL time.Duration
+
+	CacheTTLs CacheTTLs
+
+	// Docs serves the OpenAPI document and the docs UI. Routes are documented
+	// either way, for the openapi command.
+	Docs bool
+
+	Validation Validation
+
+	// Metrics records the HTTP metrics in the registry, nil disables them.
+	// The registry is served on the separate metrics listener, never here.
+	Metrics *prometheus.Registry
+
+	// Tracing starts a server span per request, see the tracing package.
+	Tracing bool
+}
+
+// Validation checks the /api/v1 traffic against the OpenAPI document.
+type Validation struct {
+	Requests bool
+	// Responses fails responses drifting from the document, only with
+	// Requests.
+	Responses bool
+}
+
+// CacheTTLs are the TTLs of the cached GET routes, zero TTLs only answer
+// conditional requests.
+type CacheTTLs struct {
+	// Waitlist applies to the waitlist listing, invalidated by the signup
+	// service.
+	Waitlist time.Duration
+}
+
+// RateLimits are the limits applied to the route groups, zero rates are
+// disabled.
+type RateLimits struct {
+	// API applies to every /api/v1 request, per client address.
+	API httpx.Rate
+	// Auth applies to the /auth endpoints, per client address and route.
+	Auth httpx.Rate
+	// User applies to authenticated admin requests, per user.
+	User httpx.Rate
 }
 
 func New(cfg *Config) (http.Handler, error) {
 	r := echo.New()
 
 	r.IPExtractor = echo.ExtractIPFromXFFHeader()
-	r.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: "${time_rfc3339}\t${method}\t${uri}\t${status}\t${latency_human}\n",
-	}))
+	r.Validator = httpx.NewValidator()
+	if cfg.ErrorRenderer == nil {
+		cfg.ErrorRenderer = httpx.EnvelopeRenderer{}
+	}
+	httpx.RegisterErrorRenderer(r, cfg.ErrorRenderer)
+	logger, err := do.Invoke[*slog.Logger](cfg.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Tracing {
+		// first, so the request ids follow the server span
+		r.Use(otelecho.Middleware("api-core", otelecho.WithSkipper(untraced)))
+	}
+	r.Use(httpx.RequestID())
+	if cfg.Metrics != nil {
+		// before Recover, so panics are recorded with their 500
+		r.Use(metrics.HTTP(cfg.Metrics))
+	}
+	r.Use(httpx.RequestLogger(logger))
 	r.Use(middleware.Recover())
+	r.Use(httpx.AuditRequest())
+	r.Use(middleware.SecureWithConfig(cfg.Security))
+	if len(cfg.CORS.AllowOrigins) > 0 {
+		// before the rate limits, so preflight requests are not counted
+		r.Use(httpx.CORS(cfg.CORS))
+	}
+	if cfg.BodyLimit > 0 {
+		r.Use(middleware.BodyLimit(strconv.FormatInt(cfg.BodyLimit, 10)))
+	}
+
+	guard, err := do.Invoke[*auth.Guard](cfg.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	introspection, err := do.Invoke[*authservice.IntrospectionService](cfg.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	auditor, err := do.Invoke[*audit.Async](cfg.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	authorized := httpx.Authn(guard, introspection, auditor)
+	fresh := httpx.RequireFreshAuth(cfg.FreshAuthMaxAge)
+
+	docs, err := do.Invoke[*openapi.Registry](cfg.Container)
+	if err != nil {
+		return nil, err
+	}
 
-	cors := middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     cfg.Origins,
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
-		AllowCredentials: true,
-		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions},
-		MaxAge:           60 * 60,
-	})
+	// the validator runs on the group for public routes, and after the
+	// authentication for secured ones
+	var validate echo.MiddlewareFunc
+	if cfg.Validation.Requests {
+		validate = openapi.Validate(docs, openapi.ValidationConfig{Responses: cfg.Validation.Responses, MaxBodyBytes: cfg.BodyLimit})
+		authn := authorized
+		authorized = func(next echo.HandlerFunc) echo.HandlerFunc {
+			return authn(validate(next))
+		}
+	}
 
-	//guard, err := do.Invoke[*auth.Guard](cfg.Container)
-	//if err != nil {
-	//	return nil, err
-	//}
+	limiter, err := do.Invoke[httpx.RateLimiter](cfg.Container)
+	if err != nil {
+		return nil, err
+	}
 
-	//authorized := httpx.Authn(guard)
+	if err := registerHealthRoutes(r, cfg.Container, docs); err != nil {
+		return nil, err
+	}
 
 	routesAPIv1 := r.Group("/api/v1")
 	{
-		routesAPIv1.Use(cors)
-		routesAPIv1.GET("/ping", func(c echo.Context) error {
+		routesAPIv1.Use(httpx.Timeout(cfg.Timeouts))
+		routesAPIv1.Use(httpx.RateLimit(limiter, httpx.RateLimitConfig{Name: "api", Rate: cfg.RateLimits.API}))
+		if validate != nil {
+			routesAPIv1.Use(validate)
+		}
+		docs.Route(routesAPIv1.GET("/ping", func(c echo.Context) error {
 			return c.String(http.StatusOK, "hello world")
+		}), openapi.Spec{
+			Summary:     "Liveness check",
+			Tags:        []string{"meta"},
+			Response:    "",
+			Raw:         true,
+			ContentType: echo.MIMETextPlainCharsetUTF8,
 		})
+		if cfg.Docs {
+			routesAPIv1.GET("/openapi.json", openapi.SpecHandler(docs))
+			routesAPIv1.GET("/docs", openapi.DocsHandler("api-core", "/api/v1/openapi.json", "/api/v1/docs/"+openapi.DocsScriptName))
+			routesAPIv1.GET("/docs/"+openapi.DocsScriptName, openapi.DocsScriptHandler())
+		}
 	}
 
-	authGroup := routesAPIv1.Group("/auth")
-	if err := registerAuthRoutes(authGroup, cfg.Container); err != nil {
+	authGroup := routesAPIv1.Group("/auth", httpx.RateLimit(limiter, httpx.RateLimitConfig{
+		Name: "auth",
+		Rate: cfg.RateLimits.Auth,
+		Key:  httpx.RateLimitKeys(httpx.RateLimitByIP, httpx.RateLimitByRoute),
+	}))
+	if err := registerAuthRoutes(authGroup, cfg.Container, docs, authorized, fresh); err != nil {
+		return nil, err
+	}
+
+	if err := registerUserInfoRoutes(routesAPIv1, cfg.Container, docs, authorized); err != nil {
+		return nil, err
+	}
+
+	adminGroup := routesAPIv1.Group("/admin", authorized, httpx.RateLimit(limiter, httpx.RateLimitConfig{
+		Name: "user",
+		Rate: cfg.RateLimits.User,
+		Key:  httpx.RateLimitByUser,
+	}))
+
+	idempotencyStore, err := do.Invoke[httpx.IdempotencyStore](cfg.Container)
+	if err != nil {
+		return nil, err
+	}
+	adminGroup.Use(httpx.Idempotency(idempotencyStore, httpx.IdempotencyConfig{TTL: cfg.IdempotencyTTL}))
+	responseCache, err := do.Invoke[*httpx.ResponseCache](cfg.Container)
+	if err != nil {
+		return nil, err
+	}
+	if err := registerAdminRoutes(adminGroup, cfg.Container, docs, guard, responseCache, cfg.CacheTTLs, fresh); err != nil {
 		return nil, err
 	}
 
+	if cfg.DevIdP {
+		if err := registerDevIdPRoutes(r.Group("/dev/idp"), cfg.Container); err != nil {
+			return nil, err
+		}
+	}
+
 	return r, nil
 }
 
-func queryParamInt(c echo.Context, name string, val int) int {
-	v := c.QueryParam(name)
-	if v == "" {
-		return val
+// untraced skips the probes, they would outnumber the traces of the API
+// requests.
+func untraced(c echo.Context) bool {
+	switch c.Path() {
+	case "/healthz", "/readyz":
+		return true
+	}
+	return false
+}
+
+// registerHealthRoutes mounts the probes at the root, outside the rate limits.
+func registerHealthRoutes(r *echo.Echo, injector *do.Injector, docs *openapi.Registry) error {
+	healthHandler, err := do.Invoke[*healthhandler.Handler](injector)
+	if err != nil {
+		return err
 	}
+	docs.Route(r.GET("/healthz", healthHandler.Live), healthhandler.LiveDoc)
+	docs.Route(r.GET("/readyz", healthHandler.Ready), healthhandler.ReadyDoc)
+	return nil
+}
 
-	i, err := strconv.Atoi(v)
+func registerAuthRoutes(group *echo.Group, injector *do.Injector, docs *openapi.Registry, authorized, fresh echo.MiddlewareFunc) error {
+	authHandler, err := do.Invoke[*authhandler.Handler](injector)
 	if err != nil {
-		return val
+		return err
 	}
+	docs.Route(group.GET("/google/login", authHandler.GoogleLogin), authhandler.GoogleLoginDoc)
+	docs.Route(group.GET("/google/callback", authHandler.GoogleCallback), authhandler.GoogleCallbackDoc)
+	docs.Route(group.GET("/google/consent", authHandler.GoogleConsent, authorized), authhandler.GoogleConsentDoc)
+	docs.Route(group.GET("/google/reauth", authHandler.GoogleReauth, authorized), authhandler.GoogleReauthDoc)
+
+	docs.Route(group.POST("/device/code", authHandler.DeviceAuthorize), authhandler.DeviceAuthorizeDoc)
+	docs.Route(group.POST("/device/token", authHandler.DeviceToken), authhandler.DeviceTokenDoc)
+	docs.Route(group.GET("/device/verify", authHandler.DeviceLookup, authorized), authhandler.DeviceLookupDoc)
+	docs.Route(group.POST("/device/verify", authHandler.DeviceConfirm, authorized, fresh), authhandler.DeviceConfirmDoc)
 
-	return i
+	docs.Route(group.POST("/introspect", authHandler.Introspect), authhandler.IntrospectDoc)
+	docs.Route(group.POST("/logout", authHandler.Logout, authorized), authhandler.LogoutDoc)
+	return nil
 }
 
-func registerAuthRoutes(group *echo.Group, injector *do.Injector) error {
+func registerUserInfoRoutes(group *echo.Group, injector *do.Injector, docs *openapi.Registry, authorized echo.MiddlewareFunc) error {
 	authHandler, err := do.Invoke[*authhandler.Handler](injector)
 	if err != nil {
 		return err
 	}
-	group.GET("/google/login", authHandler.GoogleLogin)
-	group.GET("/google/callback", authHandler.GoogleCallback)
+	docs.Route(group.GET("/userinfo", authHandler.UserInfo, authorized), authhandler.UserInfoDoc)
+	return nil
+}
+
+func registerDevIdPRoutes(group *echo.Group, injector *do.Injector) error {
+	provider, err := do.Invoke[*devidp.Provider](injector)
+	if err != nil {
+		return err
+	}
+	devidp.Register(group, provider)
+	return nil
+}
+
+// registerAdminRoutes mounts the administration routes, the writes require a
+// fresh sign-in.
+func registerAdminRoutes(group *echo.Group, injector *do.Injector, docs *openapi.Registry, guard *auth.Guard, cache *httpx.ResponseCache, ttls CacheTTLs, fresh echo.MiddlewareFunc) error {
+	signupHandler, err := do.Invoke[*signuphandler.Handler](injector)
+	if err != nil {
+		return err
+	}
+	docs.Route(group.GET("/waitlist", signupHandler.ListWaitlist,
+		httpx.Authz(guard, "waitlist", auth.ReadAuthzAction),
+		cache.Cache(httpx.ResponseCacheConfig{Name: signupservice.WaitlistCache, TTL: ttls.Waitlist}),
+	), signuphandler.ListWaitlistDoc)
+	docs.Route(group.POST("/waitlist/invite", signupHandler.Invite, httpx.Authz(guard, "waitlist", auth.WriteAuthzAction), fresh), signuphandler.InviteDoc)
+	docs.Route(group.POST("/waitlist/:id/approve", signupHandler.Approve, httpx.Authz(guard, "waitlist", auth.WriteAuthzAction), fresh), signuphandler.ApproveDoc)
+	docs.Route(group.POST("/waitlist/:id/reject", signupHandler.Reject, httpx.Authz(guard, "waitlist", auth.WriteAuthzAction), fresh), signuphandler.RejectDoc)
+
+	auditHandler, err := do.Invoke[*audithandler.Handler](injector)
+	if err != nil {
+		return err
+	}
+	docs.Route(group.GET("/audit", auditHandler.ListEvents, httpx.Authz(guard, "audit", auth.ReadAuthzAction)), audithandler.ListEventsDoc)
+
+	userHandler, err := do.Invoke[*userhandler.Handler](injector)
+	if err != nil {
+		return err
+	}
+	docs.Route(group.GET("/users", userHandler.ListUsers, httpx.Authz(guard, "users", auth.ReadAuthzAction)), userhandler.ListUsersDoc)
 	return nil
 }
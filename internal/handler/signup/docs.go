@@ -0,0 +1,61 @@
+package signup
+
+import (
+	"api-core/internal/datastore/waitliststore"
+	"api-core/pkg/errorx"
+	"api-core/pkg/openapi"
+)
+
+type listWaitlistQuery struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending approved rejected"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1"`
+	Offset int    `query:"offset" validate:"omitempty,min=0"`
+}
+
+type idempotencyHeader struct {
+	IdempotencyKey string `header:"Idempotency-Key" validate:"omitempty,max=255"`
+}
+
+type entryPath struct {
+	ID int64 `param:"id" validate:"required"`
+}
+
+var (
+	ListWaitlistDoc = openapi.Spec{
+		Summary:  "List waitlist entries",
+		Tags:     []string{"waitlist"},
+		Query:    listWaitlistQuery{},
+		Response: []waitliststore.Entry{},
+		Cached:   true,
+		Errors:   []errorx.Kind{errorx.Authz},
+		Security: []string{openapi.SecurityBearer},
+	}
+	InviteDoc = openapi.Spec{
+		Summary:     "Invite an email",
+		Description: "Pre-approves the email, overriding any previous decision.",
+		Tags:        []string{"waitlist"},
+		Request:     inviteRequest{},
+		Header:      idempotencyHeader{},
+		Response:    waitliststore.Entry{},
+		Errors:      []errorx.Kind{errorx.Authz, errorx.Reauthentication, errorx.IdempotencyMismatch, errorx.IdempotencyInProgress},
+		Security:    []string{openapi.SecurityBearer},
+	}
+	ApproveDoc = openapi.Spec{
+		Summary:  "Approve a waitlist entry",
+		Tags:     []string{"waitlist"},
+		Path:     entryPath{},
+		Header:   idempotencyHeader{},
+		Response: waitliststore.Entry{},
+		Errors:   []errorx.Kind{errorx.Invalid, errorx.NotExist, errorx.Authz, errorx.Reauthentication, errorx.IdempotencyMismatch, errorx.IdempotencyInProgress},
+		Security: []string{openapi.SecurityBearer},
+	}
+	RejectDoc = openapi.Spec{
+		Summary:  "Reject a waitlist entry",
+		Tags:     []string{"waitlist"},
+		Path:     entryPath{},
+		Header:   idempotencyHeader{},
+		Response: waitliststore.Entry{},
+		Errors:   []errorx.Kind{errorx.Invalid, errorx.NotExist, errorx.Authz, errorx.Reauthentication, errorx.IdempotencyMismatch, errorx.IdempotencyInProgress},
+		Security: []string{openapi.SecurityBearer},
+	}
+)
@@ -0,0 +1,82 @@
+package signup
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"api-core/internal/datastore/waitliststore"
+	signupservice "api-core/internal/service/signup"
+	appauth "api-core/pkg/auth"
+	"api-core/pkg/errorx"
+	httpx "api-core/pkg/httpx_echo"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	service *signupservice.Service
+}
+
+func NewHandler(service *signupservice.Service) *Handler {
+	return &Handler{service: service}
+}
+
+type inviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (h *Handler) ListWaitlist(c echo.Context) error {
+	query, err := httpx.BindAndValidate[listWaitlistQuery](c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	entries, err := h.service.List(c.Request().Context(), waitliststore.ListParams{
+		Status: waitliststore.Status(query.Status),
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	})
+	return httpx.RestAbort(c, entries, err)
+}
+
+func (h *Handler) Approve(c echo.Context) error {
+	id, err := paramID(c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	ctx := c.Request().Context()
+	entry, err := h.service.Approve(ctx, id, resolveAdmin(ctx))
+	return httpx.RestAbort(c, entry, err)
+}
+
+func (h *Handler) Reject(c echo.Context) error {
+	id, err := paramID(c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	ctx := c.Request().Context()
+	entry, err := h.service.Reject(ctx, id, resolveAdmin(ctx))
+	return httpx.RestAbort(c, entry, err)
+}
+
+func (h *Handler) Invite(c echo.Context) error {
+	req, err := httpx.BindAndValidate[inviteRequest](c)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+	ctx := c.Request().Context()
+	entry, err := h.service.Invite(ctx, req.Email, resolveAdmin(ctx))
+	return httpx.RestAbort(c, entry, err)
+}
+
+func resolveAdmin(ctx context.Context) signupservice.Admin {
+	return signupservice.Admin{ID: appauth.ResolveSubject(ctx), Email: appauth.ResolveEmail(ctx)}
+}
+
+func paramID(c echo.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, errorx.Wrap(errors.New("invalid id"), errorx.Invalid)
+	}
+	return id, nil
+}
@@ -0,0 +1,42 @@
+package user
+
+import (
+	"time"
+
+	userservice "api-core/internal/service/user"
+	"api-core/pkg/errorx"
+	"api-core/pkg/openapi"
+)
+
+// listUsersQuery documents the parameters whitelisted by userstore.ListConfig.
+type listUsersQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1"`
+	Cursor string `query:"cursor"`
+	// Sort is a comma separated list of id, email and created_at, descending
+	// with a - prefix.
+	Sort string `query:"sort"`
+
+	IDEq            int64     `query:"filter[id][eq]"`
+	IDIn            string    `query:"filter[id][in]"`
+	EmailEq         string    `query:"filter[email][eq]"`
+	EmailContains   string    `query:"filter[email][contains]"`
+	CreatedAtGT     time.Time `query:"filter[created_at][gt]"`
+	CreatedAtGTE    time.Time `query:"filter[created_at][gte]"`
+	CreatedAtLT     time.Time `query:"filter[created_at][lt]"`
+	CreatedAtLTE    time.Time `query:"filter[created_at][lte]"`
+	LastLoginAtGT   time.Time `query:"filter[last_login_at][gt]"`
+	LastLoginAtGTE  time.Time `query:"filter[last_login_at][gte]"`
+	LastLoginAtLT   time.Time `query:"filter[last_login_at][lt]"`
+	LastLoginAtLTE  time.Time `query:"filter[last_login_at][lte]"`
+	VerifiedEmailEq bool      `query:"filter[verified_email][eq]"`
+}
+
+var ListUsersDoc = openapi.Spec{
+	Summary:   "List users",
+	Tags:      []string{"users"},
+	Query:     listUsersQuery{},
+	Response:  []userservice.Account{},
+	Paginated: true,
+	Errors:    []errorx.Kind{errorx.Invalid, errorx.Validation, errorx.Authz},
+	Security:  []string{openapi.SecurityBearer},
+}
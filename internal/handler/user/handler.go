@@ -0,0 +1,29 @@
+package user
+
+import (
+	"api-core/internal/datastore/userstore"
+	userservice "api-core/internal/service/user"
+	httpx "api-core/pkg/httpx_echo"
+	"api-core/pkg/pagination"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Handler struct {
+	service *userservice.Service
+}
+
+func NewHandler(service *userservice.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ListUsers returns a page of users, newest first unless sorted otherwise.
+func (h *Handler) ListUsers(c echo.Context) error {
+	query, err := pagination.Parse(c.QueryParams(), userstore.ListConfig)
+	if err != nil {
+		return httpx.RestAbort(c, nil, err)
+	}
+
+	accounts, page, err := h.service.List(c.Request().Context(), query)
+	return httpx.RestPage(c, accounts, page, err)
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"api-core/internal/migrate"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis pings the server.
+func Redis(client redis.UniversalClient) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// Migrations fails while the database is behind the migrations embedded in
+// the binary.
+func Migrations(pool *pgxpool.Pool) func(ctx context.Context) error {
+	return migrationsProbe(func(ctx context.Context) (int64, int64, error) {
+		return migrate.Versions(ctx, pool)
+	})
+}
+
+func migrationsProbe(versions func(ctx context.Context) (current, target int64, err error)) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		current, target, err := versions(ctx)
+		if err != nil {
+			return err
+		}
+		if current < target {
+			return fmt.Errorf("pending migrations: database at version %d, latest is %d", current, target)
+		}
+		return nil
+	}
+}
+
+// Drain fails the readiness once the server starts shutting down, so the load
+// balancer stops routing new requests to the instance before its listener
+// closes.
+type Drain struct {
+	draining atomic.Bool
+}
+
+// Start begins the drain, the check fails from now on.
+func (d *Drain) Start() {
+	d.draining.Store(true)
+}
+
+func (d *Drain) Probe(context.Context) error {
+	if d.draining.Load() {
+		return errors.New("shutting down")
+	}
+	return nil
+}
+
+// Run starts the drain and keeps the server serving for the period, so the
+// load balancer stops routing to the instance before the listener closes. A
+// value on stop, such as a second signal, ends it early. A zero period skips
+// the drain.
+func (d *Drain) Run(period time.Duration, stop <-chan os.Signal) {
+	if period <= 0 {
+		return
+	}
+
+	d.Start()
+	slog.Info("health: draining", "period", period)
+	timer := time.NewTimer(period)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case sig := <-stop:
+		slog.Info("health: drain interrupted", "signal", sig.String())
+	}
+}
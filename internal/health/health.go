@@ -0,0 +1,109 @@
+// Package health runs the readiness checks. Checks are container services
+// named with the health. prefix, implementing do.Healthcheckable, so the
+// readiness report follows what the container provides.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api-core/pkg/logx"
+
+	"github.com/samber/do"
+)
+
+const servicePrefix = "health."
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// Check is one dependency probe, bounded by its timeout.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Probe   func(ctx context.Context) error
+}
+
+// HealthCheck implements do.Healthcheckable. The probe is abandoned when it
+// ignores the context past the timeout.
+func (c *Check) HealthCheck() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Probe(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", c.Timeout)
+	}
+}
+
+// Provide registers the check in the container.
+func Provide(i *do.Injector, check *Check) {
+	do.ProvideNamedValue(i, servicePrefix+check.Name, check)
+}
+
+type CheckResult struct {
+	Status     Status `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Ready runs the checks provided in the container concurrently, the report
+// fails when any check does.
+func Ready(i *do.Injector) Report {
+	var names []string
+	for _, name := range i.ListProvidedServices() {
+		if strings.HasPrefix(name, servicePrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(names))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := do.HealthCheckNamed(i, name)
+			result := CheckResult{Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				result.Status = StatusFail
+				// the report is served unauthenticated
+				result.Error = logx.Redact(err.Error())
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[strings.TrimPrefix(name, servicePrefix)] = result
+			if err != nil {
+				report.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
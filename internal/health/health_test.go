@@ -0,0 +1,163 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/do"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   func(ctx context.Context) error
+		wantErr string
+	}{
+		{name: "ok", probe: func(context.Context) error { return nil }},
+		{name: "failing", probe: func(context.Context) error { return errors.New("down") }, wantErr: "down"},
+		{
+			name: "ignores the deadline",
+			probe: func(context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+			wantErr: "timed out after 20ms",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := &Check{Name: tt.name, Timeout: 20 * time.Millisecond, Probe: tt.probe}
+
+			start := time.Now()
+			err := check.HealthCheck()
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Fatalf("check took %s, want it bounded by the timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestReady(t *testing.T) {
+	i := do.New()
+	do.ProvideValue(i, "not a check")
+	Provide(i, &Check{Name: "redis", Timeout: time.Second, Probe: func(context.Context) error { return nil }})
+	Provide(i, &Check{Name: "postgres", Timeout: time.Second, Probe: func(context.Context) error {
+		return errors.New("dial postgres://app:s3cr3t@db:5432/app: refused")
+	}})
+
+	report := Ready(i)
+	if report.Status != StatusFail || len(report.Checks) != 2 {
+		t.Fatalf("report = %+v, want two checks and a failure", report)
+	}
+	if report.Checks["redis"].Status != StatusOK {
+		t.Fatalf("redis = %+v, want ok", report.Checks["redis"])
+	}
+	postgres := report.Checks["postgres"]
+	if postgres.Status != StatusFail || strings.Contains(postgres.Error, "s3cr3t") {
+		t.Fatalf("postgres = %+v, want a failure without the password", postgres)
+	}
+
+	if report := Ready(do.New()); report.Status != StatusOK || len(report.Checks) != 0 {
+		t.Fatalf("report = %+v, want ok without checks", report)
+	}
+}
+
+func TestRedisProbe(t *testing.T) {
+	mr := miniredis.RunT(t)
+	probe := Redis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	if err := probe(context.Background()); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+
+	mr.Close()
+	if err := probe(context.Background()); err == nil {
+		t.Fatal("expected a stopped server to fail")
+	}
+}
+
+func TestMigrationsProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		current int64
+		target  int64
+		err     error
+		wantErr bool
+	}{
+		{name: "up to date", current: 5, target: 5},
+		// a newer database is fine during a rollback of the binary
+		{name: "ahead", current: 6, target: 5},
+		{name: "pending", current: 4, target: 5, wantErr: true},
+		{name: "unreachable", err: errors.New("connection refused"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := migrationsProbe(func(context.Context) (int64, int64, error) {
+				return tt.current, tt.target, tt.err
+			})
+			if err := probe(context.Background()); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	drain := &Drain{}
+	i := do.New()
+	Provide(i, &Check{Name: "shutdown", Timeout: time.Second, Probe: drain.Probe})
+
+	if report := Ready(i); report.Status != StatusOK {
+		t.Fatalf("report = %+v, want ready before the drain", report)
+	}
+
+	drain.Start()
+	report := Ready(i)
+	if report.Status != StatusFail || report.Checks["shutdown"].Error != "shutting down" {
+		t.Fatalf("report = %+v, want the shutdown check failing", report)
+	}
+}
+
+func TestDrainRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		period       time.Duration
+		signal       bool
+		wantDraining bool
+		wantMin      time.Duration
+	}{
+		{name: "disabled", period: 0},
+		{name: "full period", period: 50 * time.Millisecond, wantDraining: true, wantMin: 50 * time.Millisecond},
+		// a second signal ends the drain early
+		{name: "interrupted", period: time.Minute, signal: true, wantDraining: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drain := &Drain{}
+			stop := make(chan os.Signal, 1)
+			if tt.signal {
+				stop <- syscall.SIGTERM
+			}
+
+			start := time.Now()
+			drain.Run(tt.period, stop)
+			elapsed := time.Since(start)
+
+			if draining := drain.Probe(context.Background()) != nil; draining != tt.wantDraining {
+				t.Fatalf("draining = %v, want %v", draining, tt.wantDraining)
+			}
+			if elapsed < tt.wantMin || elapsed > tt.wantMin+time.Second {
+				t.Fatalf("drain took %s, want about %s", elapsed, tt.wantMin)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"api-core/internal/db"
@@ -26,7 +26,8 @@ const (
 // Run executes goose migrations using the embedded SQL files.
 func Run(ctx context.Context, cfg db.DatabaseConfig, command Command) error {
 	dsn := cfg.DSN()
-	log.Printf("migrate: start command=%s dsn=%s", command, dsn)
+	logger := slog.With("command", command, "host", cfg.Host, "database", cfg.Name)
+	logger.InfoContext(ctx, "migrate: start")
 
 	sqlDB, err := sql.Open("pgx", dsn)
 	if err != nil {
@@ -42,8 +43,8 @@ func Run(ctx context.Context, cfg db.DatabaseConfig, command Command) error {
 	if err := goose.SetDialect("postgres"); err != nil {
 		return fmt.Errorf("set goose dialect: %w", err)
 	}
-	goose.SetTableName("schema_migrations")
-	goose.SetLogger(log.New(log.Writer(), "goose: ", log.LstdFlags))
+	goose.SetTableName(tableName)
+	goose.SetLogger(slog.NewLogLogger(slog.Default().Handler(), slog.LevelInfo))
 	goose.SetBaseFS(migrationsFS)
 
 	var runErr error
@@ -61,10 +62,10 @@ func Run(ctx context.Context, cfg db.DatabaseConfig, command Command) error {
 	}
 
 	if runErr != nil {
-		log.Printf("migrate: command=%s failed: %v", command, runErr)
+		logger.ErrorContext(ctx, "migrate: failed", "error", runErr)
 		return runErr
 	}
 
-	log.Printf("migrate: command=%s finished successfully", command)
+	logger.InfoContext(ctx, "migrate: finished successfully")
 	return nil
 }
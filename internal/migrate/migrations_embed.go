@@ -5,4 +5,7 @@ import "embed"
 //go:embed sql/*.sql
 var migrationsFS embed.FS
 
-const migrationsDir = "sql"
+const (
+	migrationsDir = "sql"
+	tableName     = "schema_migrations"
+)
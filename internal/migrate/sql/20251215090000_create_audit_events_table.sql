@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    resource TEXT NOT NULL DEFAULT '',
+    ip TEXT,
+    user_agent TEXT,
+    metadata JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action, occurred_at);
+
+-- events are append-only, rows are only ever deleted by the retention purge
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION audit_events_deny_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_deny_update();
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS audit_events_deny_update();
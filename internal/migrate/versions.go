@@ -0,0 +1,41 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+)
+
+// Versions returns the version of the database and the latest version embedded
+// in the binary, they differ while migrations are pending.
+func Versions(ctx context.Context, pool *pgxpool.Pool) (current, target int64, err error) {
+	sqlDB := stdlib.OpenDBFromPool(pool)
+	defer sqlDB.Close()
+
+	provider, err := newProvider(sqlDB)
+	if err != nil {
+		return 0, 0, err
+	}
+	return provider.GetVersions(ctx)
+}
+
+// newProvider reads the embedded migrations, it does not connect.
+func newProvider(sqlDB *sql.DB) (*goose.Provider, error) {
+	sources, err := fs.Sub(migrationsFS, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := goose.NewProvider(goose.DialectPostgres, sqlDB, sources,
+		goose.WithTableName(tableName),
+		goose.WithDisableGlobalRegistry(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create migration provider: %w", err)
+	}
+	return provider, nil
+}
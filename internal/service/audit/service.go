@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"api-core/internal/config"
+	"api-core/internal/datastore/auditstore"
+	appaudit "api-core/pkg/audit"
+	"api-core/pkg/errorx"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+	// recordTimeout bounds the insert of an event, which outlives the request.
+	recordTimeout = 5 * time.Second
+)
+
+// Service stores audit events in the append-only audit_events table and
+// exposes them to administrators.
+type Service struct {
+	store  auditstore.Store
+	logger *slog.Logger
+	cfg    config.AuditConfig
+}
+
+var _ appaudit.Auditor = (*Service)(nil)
+
+func NewService(store auditstore.Store, logger *slog.Logger, auditCfg config.AuditConfig) *Service {
+	return &Service{store: store, logger: logger, cfg: auditCfg}
+}
+
+// Record writes the event, a failure is logged and never surfaced to the
+// request that triggered it.
+func (s *Service) Record(ctx context.Context, event appaudit.Event) {
+	event = appaudit.FromRequest(ctx, event)
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	metadata := json.RawMessage("{}")
+	if len(event.Metadata) > 0 {
+		raw, err := json.Marshal(event.Metadata)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "audit: encode metadata", "action", event.Action, "error", err)
+		} else {
+			metadata = raw
+		}
+	}
+
+	params := auditstore.InsertParams{
+		OccurredAt: event.OccurredAt,
+		Actor:      event.Actor,
+		Action:     event.Action,
+		Outcome:    string(event.Outcome),
+		Resource:   event.Resource,
+		Metadata:   metadata,
+	}
+	if event.IP != "" {
+		params.IP = &event.IP
+	}
+	if event.UserAgent != "" {
+		params.UserAgent = &event.UserAgent
+	}
+
+	// the event must be kept even when the client went away
+	insertCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+	if err := s.store.Insert(insertCtx, params); err != nil {
+		s.logger.ErrorContext(ctx, "audit: record event", "action", event.Action, "actor", event.Actor, "error", err)
+	}
+}
+
+func (s *Service) List(ctx context.Context, params auditstore.ListParams) ([]*auditstore.Event, error) {
+	if params.Limit <= 0 {
+		params.Limit = defaultListLimit
+	}
+	params.Limit = min(params.Limit, maxListLimit)
+	params.Offset = max(params.Offset, 0)
+
+	if !params.From.IsZero() && !params.To.IsZero() && !params.From.Before(params.To) {
+		return nil, errorx.Wrap(errors.New("from must be before to"), errorx.Invalid)
+	}
+
+	events, err := s.store.List(ctx, params)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.Database)
+	}
+	return events, nil
+}
+
+// Purge deletes the events older than the configured retention.
+func (s *Service) Purge(ctx context.Context) (int64, error) {
+	if s.cfg.Retention <= 0 {
+		return 0, errors.New("audit retention is not configured")
+	}
+
+	deleted, err := s.store.Purge(ctx, time.Now().UTC().Add(-s.cfg.Retention))
+	if err != nil {
+		return 0, fmt.Errorf("purge audit events: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"api-core/internal/config"
+	"api-core/internal/datastore/auditstore"
+	appaudit "api-core/pkg/audit"
+	"api-core/pkg/errorx"
+	"api-core/pkg/requestid"
+)
+
+// fakeStore records the calls of the service.
+type fakeStore struct {
+	inserted []auditstore.InsertParams
+	// the state of the insert context during the insert
+	insertErr   error
+	hasDeadline bool
+	listed      *auditstore.ListParams
+	before      time.Time
+	err         error
+}
+
+func (s *fakeStore) Insert(ctx context.Context, params auditstore.InsertParams) error {
+	s.insertErr = ctx.Err()
+	_, s.hasDeadline = ctx.Deadline()
+	s.inserted = append(s.inserted, params)
+	return s.err
+}
+
+func (s *fakeStore) List(_ context.Context, params auditstore.ListParams) ([]*auditstore.Event, error) {
+	s.listed = &params
+	return nil, s.err
+}
+
+func (s *fakeStore) Purge(_ context.Context, before time.Time) (int64, error) {
+	s.before = before
+	return 7, s.err
+}
+
+func newTestService(store auditstore.Store, retention time.Duration) *Service {
+	return NewService(store, slog.New(slog.NewTextHandler(io.Discard, nil)), config.AuditConfig{Retention: retention})
+}
+
+func TestRecord(t *testing.T) {
+	store := &fakeStore{}
+	s := newTestService(store, 0)
+
+	ctx := appaudit.WithRequest(context.Background(), "203.0.113.7", "curl/8")
+	ctx = requestid.NewContext(ctx, requestid.IDs{RequestID: "req-1"})
+	ctx, cancel := context.WithCancel(ctx)
+	// the client went away
+	cancel()
+	s.Record(ctx, appaudit.Event{Action: appaudit.ActionLogin, Outcome: appaudit.OutcomeFailure})
+
+	if len(store.inserted) != 1 {
+		t.Fatalf("inserted = %d, want 1", len(store.inserted))
+	}
+	params := store.inserted[0]
+	if params.IP == nil || *params.IP != "203.0.113.7" || string(params.Metadata) != `{"request_id":"req-1"}` {
+		t.Fatalf("params = %+v, metadata %s", params, params.Metadata)
+	}
+	if store.insertErr != nil {
+		t.Fatalf("insert context: %v, want it to outlive the request", store.insertErr)
+	}
+	if !store.hasDeadline {
+		t.Fatal("insert without a deadline")
+	}
+}
+
+func TestList(t *testing.T) {
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		params     auditstore.ListParams
+		storeErr   error
+		wantLimit  int
+		wantOffset int
+		wantKind   errorx.Kind
+	}{
+		{name: "default limit", params: auditstore.ListParams{}, wantLimit: defaultListLimit},
+		{name: "limit capped", params: auditstore.ListParams{Limit: 10000}, wantLimit: maxListLimit},
+		{name: "negative offset", params: auditstore.ListParams{Limit: 10, Offset: -5}, wantLimit: 10},
+		{name: "offset kept", params: auditstore.ListParams{Limit: 10, Offset: 30}, wantLimit: 10, wantOffset: 30},
+		{name: "range", params: auditstore.ListParams{From: from, To: from.Add(time.Hour)}, wantLimit: defaultListLimit},
+		{name: "empty range", params: auditstore.ListParams{From: from, To: from}, wantKind: errorx.Invalid},
+		{name: "reversed range", params: auditstore.ListParams{From: from, To: from.Add(-time.Hour)}, wantKind: errorx.Invalid},
+		{name: "store failure", storeErr: errors.New("connection refused"), wantKind: errorx.Database},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{err: tt.storeErr}
+			_, err := newTestService(store, 0).List(context.Background(), tt.params)
+			if tt.wantKind != errorx.Other {
+				var target *errorx.Error
+				if !errors.As(err, &target) || !target.Of(tt.wantKind) {
+					t.Fatalf("err = %v, want kind %s", err, tt.wantKind.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if store.listed.Limit != tt.wantLimit || store.listed.Offset != tt.wantOffset {
+				t.Fatalf("params = %+v, want limit %d offset %d", store.listed, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	store := &fakeStore{}
+	deleted, err := newTestService(store, 90*24*time.Hour).Purge(context.Background())
+	if err != nil || deleted != 7 {
+		t.Fatalf("purge = %d, %v, want 7", deleted, err)
+	}
+	if age := time.Since(store.before); age < 90*24*time.Hour || age > 90*24*time.Hour+time.Minute {
+		t.Fatalf("before = %s, want 90 days ago", store.before)
+	}
+
+	if _, err := newTestService(&fakeStore{}, 0).Purge(context.Background()); err == nil {
+		t.Fatal("expected an error without a retention")
+	}
+	store = &fakeStore{err: errors.New("connection refused")}
+	if _, err := newTestService(store, time.Hour).Purge(context.Background()); err == nil {
+		t.Fatal("expected the store error")
+	}
+}
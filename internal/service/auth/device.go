@@ -0,0 +1,524 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"api-core/internal/config"
+	appaudit "api-core/pkg/audit"
+	"api-core/pkg/errorx"
+	"api-core/pkg/jwtx"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// DeviceCodeGrantType is the grant_type value defined by RFC 8628.
+const DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// OAuth error codes returned by the device token endpoint (RFC 8628 section 3.5).
+const (
+	OAuthErrAuthorizationPending = "authorization_pending"
+	OAuthErrSlowDown             = "slow_down"
+	OAuthErrAccessDenied         = "access_denied"
+	OAuthErrExpiredToken         = "expired_token"
+	OAuthErrInvalidRequest       = "invalid_request"
+	OAuthErrInvalidClient        = "invalid_client"
+	OAuthErrInvalidGrant         = "invalid_grant"
+	OAuthErrInvalidScope         = "invalid_scope"
+	OAuthErrUnsupportedGrantType = "unsupported_grant_type"
+)
+
+// userCodeAlphabet avoids vowels and look-alike characters as suggested by RFC 8628 section 6.1.
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+const (
+	userCodeLength    = 8
+	slowDownIncrement = 5 * time.Second
+)
+
+type DeviceStatus string
+
+const (
+	DeviceStatusPending  DeviceStatus = "pending"
+	DeviceStatusApproved DeviceStatus = "approved"
+	DeviceStatusDenied   DeviceStatus = "denied"
+)
+
+// OAuthError is rendered as an RFC 6749 error response instead of the errorx envelope.
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func newOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+type DeviceService struct {
+	codePrefix     string
+	userCodePrefix string
+
+	tokenIssuer *jwtx.HMACIssuer
+	redis       *redis.Client
+	auditor     appaudit.Auditor
+	cfg         config.DeviceConfig
+}
+
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type DeviceTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// DeviceRequest is the pending authorization shown to the user on the verification page.
+type DeviceRequest struct {
+	UserCode  string       `json:"user_code"`
+	ClientID  string       `json:"client_id"`
+	Scope     string       `json:"scope,omitempty"`
+	Status    DeviceStatus `json:"status"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// deviceRecord is the state stored in redis under the device code.
+type deviceRecord struct {
+	ClientID   string        `json:"client_id"`
+	Scope      string        `json:"scope,omitempty"`
+	UserCode   string        `json:"user_code"`
+	Status     DeviceStatus  `json:"status"`
+	Subject    string        `json:"subject,omitempty"`
+	Email      string        `json:"email,omitempty"`
+	Interval   time.Duration `json:"interval"`
+	LastPollAt time.Time     `json:"last_poll_at,omitempty"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+}
+
+func NewDeviceService(
+	tokenIssuer *jwtx.HMACIssuer,
+	redis *redis.Client,
+	auditor appaudit.Auditor,
+	deviceCfg config.DeviceConfig,
+) *DeviceService {
+	if deviceCfg.CodeTTL <= 0 {
+		deviceCfg.CodeTTL = 10 * time.Minute
+	}
+	if deviceCfg.PollInterval <= 0 {
+		deviceCfg.PollInterval = 5 * time.Second
+	}
+	return &DeviceService{
+		codePrefix:     "device_code:",
+		userCodePrefix: "device_user_code:",
+		tokenIssuer:    tokenIssuer,
+		redis:          redis,
+		auditor:        auditor,
+		cfg:            deviceCfg,
+	}
+}
+
+func (s *DeviceService) codeKey(deviceCode string) string {
+	return s.codePrefix + deviceCode
+}
+
+func (s *DeviceService) userCodeKey(userCode string) string {
+	return s.userCodePrefix + userCode
+}
+
+// Authorize starts a device flow for the given client (RFC 8628 section 3.1).
+func (s *DeviceService) Authorize(ctx context.Context, clientID, scope string) (*DeviceAuthorization, error) {
+	if clientID == "" || !slices.Contains(s.cfg.ClientIDs, clientID) {
+		return nil, newOAuthError(OAuthErrInvalidClient, "unknown client")
+	}
+	if err := s.checkScope(clientID, scope); err != nil {
+		return nil, err
+	}
+	if s.cfg.VerificationURI == "" {
+		return nil, errors.New("device verification uri not configured")
+	}
+
+	deviceCode, err := randomDeviceCode()
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := randomUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	record := deviceRecord{
+		ClientID:  clientID,
+		Scope:     scope,
+		UserCode:  userCode,
+		Status:    DeviceStatusPending,
+		Interval:  s.cfg.PollInterval,
+		ExpiresAt: time.Now().UTC().Add(s.cfg.CodeTTL),
+	}
+
+	ok, err := s.redis.SetNX(ctx, s.userCodeKey(userCode), deviceCode, s.cfg.CodeTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("store device user code: %w", err)
+	}
+	if !ok {
+		return nil, errors.New("device user code collision")
+	}
+	if err := s.saveRecord(ctx, deviceCode, &record); err != nil {
+		return nil, err
+	}
+
+	return &DeviceAuthorization{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         s.cfg.VerificationURI,
+		VerificationURIComplete: s.cfg.VerificationURI + "?user_code=" + userCode,
+		ExpiresIn:               int(s.cfg.CodeTTL.Seconds()),
+		Interval:                int(s.cfg.PollInterval.Seconds()),
+	}, nil
+}
+
+// Lookup returns the pending request for the verification page.
+func (s *DeviceService) Lookup(ctx context.Context, userCode string) (*DeviceRequest, error) {
+	_, record, err := s.loadByUserCode(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceRequest{
+		UserCode:  record.UserCode,
+		ClientID:  record.ClientID,
+		Scope:     record.Scope,
+		Status:    record.Status,
+		ExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// Confirm records the decision of the signed in user for the given user code.
+func (s *DeviceService) Confirm(ctx context.Context, userCode, subject, email string, approve bool) error {
+	if subject == "" {
+		return errorx.Wrap(errors.New("missing subject"), errorx.Authn)
+	}
+
+	// the user code is consumed first, so only one decision is recorded
+	deviceCode, err := s.consumeUserCode(ctx, userCode)
+	if err != nil {
+		return err
+	}
+	record, raw, err := s.loadRecord(ctx, deviceCode)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return errorx.Wrap(errors.New("unknown or expired user code"), errorx.NotExist)
+	}
+	if record.Status != DeviceStatusPending {
+		return errorx.Wrap(errors.New("device code already used"), errorx.Invalid)
+	}
+
+	record.Status = DeviceStatusDenied
+	if approve {
+		record.Status = DeviceStatusApproved
+		record.Subject = subject
+		record.Email = email
+	}
+	// a poll may have updated the record meanwhile, the decision must not be
+	// lost to it
+	for {
+		saved, err := s.swapRecord(ctx, deviceCode, raw, record)
+		if err != nil {
+			return err
+		}
+		if saved {
+			break
+		}
+		current, currentRaw, err := s.loadRecord(ctx, deviceCode)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.Status != DeviceStatusPending {
+			return errorx.Wrap(errors.New("device code already used"), errorx.Invalid)
+		}
+		record.LastPollAt, record.Interval, raw = current.LastPollAt, current.Interval, currentRaw
+	}
+
+	outcome := appaudit.OutcomeDenied
+	if approve {
+		outcome = appaudit.OutcomeSuccess
+	}
+	s.auditor.Record(ctx, appaudit.Event{
+		Actor:    subject,
+		Action:   appaudit.ActionDeviceApproval,
+		Outcome:  outcome,
+		Metadata: map[string]any{"client_id": record.ClientID, "scope": record.Scope},
+	})
+	return nil
+}
+
+// Token is polled by the device until the user approves or denies the request
+// (RFC 8628 section 3.4).
+func (s *DeviceService) Token(ctx context.Context, grantType, deviceCode, clientID string) (*DeviceTokenResponse, error) {
+	if grantType != DeviceCodeGrantType {
+		return nil, newOAuthError(OAuthErrUnsupportedGrantType, "")
+	}
+	if deviceCode == "" || clientID == "" {
+		return nil, newOAuthError(OAuthErrInvalidRequest, "missing device_code or client_id")
+	}
+
+	record, raw, err := s.loadRecord(ctx, deviceCode)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, newOAuthError(OAuthErrExpiredToken, "")
+	}
+	if record.ClientID != clientID {
+		return nil, newOAuthError(OAuthErrInvalidGrant, "device code was issued to another client")
+	}
+
+	now := time.Now().UTC()
+	switch record.Status {
+	case DeviceStatusDenied:
+		//nolint:errcheck
+		s.redis.Del(ctx, s.codeKey(deviceCode))
+		return nil, newOAuthError(OAuthErrAccessDenied, "")
+	case DeviceStatusApproved:
+		// the allowlist may have shrunk since the code was issued
+		if err := s.checkScope(clientID, record.Scope); err != nil {
+			//nolint:errcheck
+			s.redis.Del(ctx, s.codeKey(deviceCode))
+			return nil, err
+		}
+		consumed, err := s.swapRecord(ctx, deviceCode, raw, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !consumed {
+			// a concurrent poll already redeemed the code
+			return nil, newOAuthError(OAuthErrInvalidGrant, "device code already used")
+		}
+		token, err := s.tokenIssuer.IssueClaims(jwtx.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: record.Subject},
+			Email:            record.Email,
+			Scope:            jwtx.ParseScopes(record.Scope),
+			Extra:            map[string]any{"client_id": clientID},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("issue token: %w", err)
+		}
+		s.auditor.Record(ctx, appaudit.Event{
+			Actor:    record.Subject,
+			Action:   appaudit.ActionTokenIssued,
+			Outcome:  appaudit.OutcomeSuccess,
+			Metadata: map[string]any{"grant": DeviceCodeGrantType, "client_id": clientID},
+		})
+		return &DeviceTokenResponse{
+			AccessToken: token,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(s.tokenIssuer.Expiration().Seconds()),
+		}, nil
+	}
+
+	tooFast := !record.LastPollAt.IsZero() && now.Sub(record.LastPollAt) < record.Interval
+	record.LastPollAt = now
+	if tooFast {
+		record.Interval += slowDownIncrement
+	}
+	saved, err := s.swapRecord(ctx, deviceCode, raw, record)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		// the user decided meanwhile, the next poll reads the decision
+		return nil, newOAuthError(OAuthErrAuthorizationPending, "")
+	}
+	if tooFast {
+		return nil, newOAuthError(OAuthErrSlowDown, "")
+	}
+
+	return nil, newOAuthError(OAuthErrAuthorizationPending, "")
+}
+
+// checkScope rejects scopes outside the allowlist of the client (RFC 6749
+// section 3.3).
+func (s *DeviceService) checkScope(clientID, scope string) error {
+	allowed := s.cfg.ClientScopes[clientID]
+	for _, requested := range jwtx.ParseScopes(scope) {
+		if !slices.Contains(allowed, requested) {
+			return newOAuthError(OAuthErrInvalidScope, "scope "+requested+" is not allowed for the client")
+		}
+	}
+	return nil
+}
+
+func (s *DeviceService) loadByUserCode(ctx context.Context, userCode string) (string, *deviceRecord, error) {
+	userCode = normalizeUserCode(userCode)
+	if userCode == "" {
+		return "", nil, errorx.Wrap(errors.New("missing user code"), errorx.Invalid)
+	}
+
+	deviceCode, err := s.redis.Get(ctx, s.userCodeKey(userCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil, errorx.Wrap(errors.New("unknown or expired user code"), errorx.NotExist)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("load device user code: %w", err)
+	}
+
+	record, _, err := s.loadRecord(ctx, deviceCode)
+	if err != nil {
+		return "", nil, err
+	}
+	if record == nil {
+		return "", nil, errorx.Wrap(errors.New("unknown or expired user code"), errorx.NotExist)
+	}
+
+	return deviceCode, record, nil
+}
+
+// consumeUserCode returns the device code of the user code and deletes it,
+// a user code is used once.
+func (s *DeviceService) consumeUserCode(ctx context.Context, userCode string) (string, error) {
+	userCode = normalizeUserCode(userCode)
+	if userCode == "" {
+		return "", errorx.Wrap(errors.New("missing user code"), errorx.Invalid)
+	}
+
+	deviceCode, err := s.redis.GetDel(ctx, s.userCodeKey(userCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", errorx.Wrap(errors.New("unknown or expired user code"), errorx.NotExist)
+	}
+	if err != nil {
+		return "", fmt.Errorf("consume device user code: %w", err)
+	}
+	return deviceCode, nil
+}
+
+// loadRecord returns the record with its stored form, which swapRecord
+// compares against.
+func (s *DeviceService) loadRecord(ctx context.Context, deviceCode string) (*deviceRecord, string, error) {
+	raw, err := s.redis.Get(ctx, s.codeKey(deviceCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("load device code: %w", err)
+	}
+
+	var record deviceRecord
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		return nil, "", fmt.Errorf("decode device code: %w", err)
+	}
+	return &record, raw, nil
+}
+
+// swapRecordScript replaces or, without a new value, deletes the key when it
+// still holds the expected value.
+var swapRecordScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if ARGV[2] == "" then
+	redis.call("DEL", KEYS[1])
+else
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+end
+return 1
+`)
+
+// swapRecord stores the record, or consumes the code when record is nil, only
+// when nobody changed it since it was read as old. Polls and decisions race
+// on the same code, the loser reads it again.
+func (s *DeviceService) swapRecord(ctx context.Context, deviceCode, old string, record *deviceRecord) (bool, error) {
+	var (
+		raw []byte
+		ttl time.Duration
+		err error
+	)
+	if record != nil {
+		ttl = time.Until(record.ExpiresAt)
+		if ttl < time.Millisecond {
+			return false, newOAuthError(OAuthErrExpiredToken, "")
+		}
+		if raw, err = json.Marshal(record); err != nil {
+			return false, fmt.Errorf("encode device code: %w", err)
+		}
+	}
+
+	swapped, err := swapRecordScript.Run(ctx, s.redis, []string{s.codeKey(deviceCode)}, old, string(raw), ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("store device code: %w", err)
+	}
+	return swapped == 1, nil
+}
+
+// saveRecord keeps the original expiry so polling never extends the lifetime of a code.
+func (s *DeviceService) saveRecord(ctx context.Context, deviceCode string, record *deviceRecord) error {
+	ttl := time.Until(record.ExpiresAt)
+	if ttl <= 0 {
+		return newOAuthError(OAuthErrExpiredToken, "")
+	}
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode device code: %w", err)
+	}
+	if err := s.redis.Set(ctx, s.codeKey(deviceCode), raw, ttl).Err(); err != nil {
+		return fmt.Errorf("store device code: %w", err)
+	}
+	return nil
+}
+
+func randomDeviceCode() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate device code: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// randomUserCode returns a code formatted as XXXX-XXXX.
+func randomUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	var b strings.Builder
+	for i := 0; i < userCodeLength; i++ {
+		if i == userCodeLength/2 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("generate user code: %w", err)
+		}
+		b.WriteByte(userCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// normalizeUserCode accepts codes typed in lower case or without the dash.
+func normalizeUserCode(userCode string) string {
+	code := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+	if len(code) != userCodeLength {
+		return ""
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"api-core/internal/config"
+	appaudit "api-core/pkg/audit"
+	"api-core/pkg/errorx"
+	"api-core/pkg/jwtx"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testClientID = "api-core-cli"
+
+func newTestDeviceService(t *testing.T) *DeviceService {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	issuer, err := jwtx.NewHMACIssuer("test-secret", "api-core", time.Hour)
+	if err != nil {
+		t.Fatalf("new issuer: %v", err)
+	}
+	return NewDeviceService(issuer, client, appaudit.Nop(), config.DeviceConfig{
+		VerificationURI: "http://app.test/device",
+		ClientIDs:       []string{testClientID, "tv-app"},
+		ClientScopes:    map[string][]string{testClientID: {"profile", "email"}},
+	})
+}
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("err = %v, want %s", err, code)
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDeviceService(t)
+
+	auth, err := s.Authorize(ctx, testClientID, "profile")
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	_, err = s.Token(ctx, DeviceCodeGrantType, auth.DeviceCode, testClientID)
+	assertOAuthError(t, err, OAuthErrAuthorizationPending)
+
+	if err := s.Confirm(ctx, auth.UserCode, "42", "alice@example.com", true); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	token, err := s.Token(ctx, DeviceCodeGrantType, auth.DeviceCode, testClientID)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	if token.AccessToken == "" || token.TokenType != "Bearer" {
+		t.Fatalf("token = %+v, want a bearer token", token)
+	}
+
+	_, err = s.Token(ctx, DeviceCodeGrantType, auth.DeviceCode, testClientID)
+	assertOAuthError(t, err, OAuthErrExpiredToken)
+}
+
+func TestDeviceScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		clientID string
+		scope    string
+		wantErr  string
+	}{
+		{name: "no scope", clientID: testClientID},
+		{name: "allowed scope", clientID: testClientID, scope: "profile"},
+		{name: "allowed scopes", clientID: testClientID, scope: " email  profile "},
+		{name: "scope outside the allowlist", clientID: testClientID, scope: "profile admin", wantErr: OAuthErrInvalidScope},
+		{name: "client without scopes", clientID: "tv-app", scope: "profile", wantErr: OAuthErrInvalidScope},
+		{name: "client without scopes and no scope", clientID: "tv-app"},
+		// the client is checked before the scope
+		{name: "unknown client", clientID: "other", scope: "admin", wantErr: OAuthErrInvalidClient},
+	}
+	s := newTestDeviceService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := s.Authorize(context.Background(), tt.clientID, tt.scope)
+			if tt.wantErr != "" {
+				assertOAuthError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil || auth.DeviceCode == "" {
+				t.Fatalf("authorize = %+v, %v", auth, err)
+			}
+		})
+	}
+}
+
+func TestDeviceTokenScope(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDeviceService(t)
+
+	auth, err := s.Authorize(ctx, testClientID, "profile email")
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if err := s.Confirm(ctx, auth.UserCode, "42", "alice@example.com", true); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	// the allowlist shrank while the user was approving
+	s.cfg.ClientScopes = map[string][]string{testClientID: {"profile"}}
+	_, err = s.Token(ctx, DeviceCodeGrantType, auth.DeviceCode, testClientID)
+	assertOAuthError(t, err, OAuthErrInvalidScope)
+
+	// the code is consumed, no token is issued for it later
+	s.cfg.ClientScopes = map[string][]string{testClientID: {"profile", "email"}}
+	_, err = s.Token(ctx, DeviceCodeGrantType, auth.DeviceCode, testClientID)
+	assertOAuthError(t, err, OAuthErrExpiredToken)
+}
+
+func TestDeviceConfirmOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDeviceService(t)
+
+	auth, err := s.Authorize(ctx, testClientID, "")
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for _, approve := range []bool{true, false, true, false} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if s.Confirm(ctx, auth.UserCode, "42", "alice@example.com", approve) == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Fatalf("accepted decisions = %d, want 1", accepted)
+	}
+
+	err = s.Confirm(ctx, auth.UserCode, "42", "alice@example.com", true)
+	var target *errorx.Error
+	if !errors.As(err, &target) || !target.Of(errorx.NotExist) {
+		t.Fatalf("second confirm err = %v, want %s", err, errorx.NotExist)
+	}
+}
+
+func TestDeviceTokenRedeemedOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDeviceService(t)
+
+	auth, err := s.Authorize(ctx, testClientID, "")
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if err := s.Confirm(ctx, auth.UserCode, "42", "alice@example.com", true); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		tokens int
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Token(ctx, DeviceCodeGrantType, auth.DeviceCode, testClientID); err == nil {
+				mu.Lock()
+				tokens++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if tokens != 1 {
+		t.Fatalf("issued tokens = %d, want 1", tokens)
+	}
+}
+
+func TestDeviceStalePollKeepsDecision(t *testing.T) {
+	ctx := context.Background()
+	s := newTestDeviceService(t)
+
+	auth, err := s.Authorize(ctx, testClientID, "")
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	// a poll reads the pending record, then the user approves before the
+	// poll writes it back
+	record, raw, err := s.loadRecord(ctx, auth.DeviceCode)
+	if err != nil {
+		t.Fatalf("load record: %v", err)
+	}
+	if err := s.Confirm(ctx, auth.UserCode, "42", "alice@example.com", true); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	record.LastPollAt = time.Now().UTC()
+	saved, err := s.swapRecord(ctx, auth.DeviceCode, raw, record)
+	if err != nil {
+		t.Fatalf("swap record: %v", err)
+	}
+	if saved {
+		t.Fatal("stale poll overwrote the decision")
+	}
+
+	if _, err := s.Token(ctx, DeviceCodeGrantType, auth.DeviceCode, testClientID); err != nil {
+		t.Fatalf("token after approval: %v", err)
+	}
+}
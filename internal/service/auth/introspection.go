@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"api-core/internal/config"
+	"api-core/internal/datastore/userstore"
+	"api-core/internal/db"
+	appaudit "api-core/pkg/audit"
+	"api-core/pkg/errorx"
+	"api-core/pkg/jwtx"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type IntrospectionService struct {
+	revokedPrefix    string
+	introspectPrefix string
+	userInfoPrefix   string
+
+	authn     jwtx.Authenticator
+	userStore userstore.Store
+	cache     db.SharedCache
+	redis     *redis.Client
+	auditor   appaudit.Auditor
+	cfg       config.IntrospectionConfig
+}
+
+// Introspection is the RFC 7662 section 2.2 response.
+type Introspection struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+}
+
+// UserInfo is the OIDC userinfo response.
+type UserInfo struct {
+	Sub           string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Locale        string `json:"locale,omitempty"`
+	UpdatedAt     int64  `json:"updated_at"`
+}
+
+func NewIntrospectionService(
+	authn jwtx.Authenticator,
+	userStore userstore.Store,
+	cache db.SharedCache,
+	redis *redis.Client,
+	auditor appaudit.Auditor,
+	introspectionCfg config.IntrospectionConfig,
+) *IntrospectionService {
+	return &IntrospectionService{
+		revokedPrefix:    "revoked_jti:",
+		introspectPrefix: "introspect:",
+		userInfoPrefix:   "userinfo:",
+		authn:            authn,
+		userStore:        userStore,
+		cache:            cache,
+		redis:            redis,
+		auditor:          auditor,
+		cfg:              introspectionCfg,
+	}
+}
+
+// AuthenticateClient checks the credentials of a machine client calling introspection.
+func (s *IntrospectionService) AuthenticateClient(clientID, clientSecret string) error {
+	secret, ok := s.cfg.Clients[clientID]
+	if !ok || subtle.ConstantTimeCompare([]byte(secret), []byte(clientSecret)) != 1 {
+		return newOAuthError(OAuthErrInvalidClient, "client authentication failed")
+	}
+	return nil
+}
+
+// Introspect reports whether the token is active, i.e. correctly signed,
+// not expired, not revoked and still belonging to an existing user.
+func (s *IntrospectionService) Introspect(ctx context.Context, tokenStr string) (*Introspection, error) {
+	if tokenStr == "" {
+		return nil, newOAuthError(OAuthErrInvalidRequest, "missing token")
+	}
+
+	// invalid or expired tokens are not an error for the caller, just inactive
+	_, claims, err := jwtx.Verify(s.authn, tokenStr)
+	if err != nil {
+		return &Introspection{Active: false}, nil
+	}
+
+	introspect := func() (*Introspection, error) {
+		active, err := s.isActive(ctx, claims)
+		if err != nil || !active {
+			return &Introspection{Active: false}, err
+		}
+		return newIntrospection(claims), nil
+	}
+
+	ttl := s.cfg.CacheTTL
+	if claims.ExpiresAt != nil {
+		ttl = min(ttl, time.Until(claims.ExpiresAt.Time))
+	}
+	// the cache falls back to its default TTL below one second, which would
+	// outlive the token
+	if ttl < time.Second {
+		return introspect()
+	}
+
+	return db.UseCache(ctx, s.cache, s.introspectPrefix+claims.ID, ttl, introspect)
+}
+
+// UserInfo returns the current profile of the authenticated user.
+func (s *IntrospectionService) UserInfo(ctx context.Context, claims *jwtx.Claims) (*UserInfo, error) {
+	if claims == nil {
+		return nil, errorx.Wrap(errors.New("missing claims"), errorx.Authn)
+	}
+
+	revoked, err := s.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errorx.Wrap(errors.New("token revoked"), errorx.Authn)
+	}
+
+	userInfo := func() (*UserInfo, error) {
+		user, err := s.lookupUser(ctx, claims.Subject)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, errorx.Wrap(errors.New("user no longer exists"), errorx.Authn)
+		}
+		return newUserInfo(user), nil
+	}
+
+	if s.cfg.UserInfoCache < time.Second {
+		return userInfo()
+	}
+	return db.UseCache(ctx, s.cache, s.userInfoPrefix+claims.Subject, s.cfg.UserInfoCache, userInfo)
+}
+
+// Revoke denylists the token until it expires so introspection reports it inactive.
+func (s *IntrospectionService) Revoke(ctx context.Context, claims *jwtx.Claims) error {
+	if claims == nil || claims.ID == "" {
+		return errorx.Wrap(errors.New("token has no jti"), errorx.Invalid)
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := s.redis.Set(ctx, s.revokedPrefix+claims.ID, "1", ttl).Err(); err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	s.auditor.Record(ctx, appaudit.Event{
+		Actor:    claims.Subject,
+		Action:   appaudit.ActionLogout,
+		Outcome:  appaudit.OutcomeSuccess,
+		Metadata: map[string]any{"jti": claims.ID},
+	})
+
+	return s.cache.Delete(ctx, s.introspectPrefix+claims.ID)
+}
+
+func (s *IntrospectionService) isActive(ctx context.Context, claims *jwtx.Claims) (bool, error) {
+	revoked, err := s.IsRevoked(ctx, claims.ID)
+	if err != nil || revoked {
+		return false, err
+	}
+
+	user, err := s.lookupUser(ctx, claims.Subject)
+	if err != nil {
+		return false, err
+	}
+	return user != nil, nil
+}
+
+// IsRevoked reports whether the token id is denylisted, tokens without an id
+// cannot be revoked.
+func (s *IntrospectionService) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+
+	n, err := s.redis.Exists(ctx, s.revokedPrefix+jti).Result()
+	if err != nil {
+		return false, fmt.Errorf("check token revocation: %w", err)
+	}
+	return n > 0, nil
+}
+
+// lookupUser returns nil when the subject does not match an existing user.
+func (s *IntrospectionService) lookupUser(ctx context.Context, subject string) (*userstore.User, error) {
+	id, err := strconv.ParseInt(subject, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	user, err := s.userStore.GetByID(ctx, id)
+	if errorx.IsNoRows(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load user: %w", err)
+	}
+	return user, nil
+}
+
+func newIntrospection(claims *jwtx.Claims) *Introspection {
+	result := &Introspection{
+		Active:    true,
+		Scope:     claims.Scope.String(),
+		Username:  claims.Email,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	}
+	if clientID, ok := claims.Extra["client_id"].(string); ok {
+		result.ClientID = clientID
+	}
+	if claims.ExpiresAt != nil {
+		result.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		result.Iat = claims.IssuedAt.Unix()
+	}
+	return result
+}
+
+func newUserInfo(user *userstore.User) *UserInfo {
+	info := &UserInfo{
+		Sub:           strconv.FormatInt(user.ID, 10),
+		Email:         user.Email,
+		EmailVerified: user.VerifiedEmail,
+		UpdatedAt:     user.UpdatedAt.Unix(),
+	}
+	if user.Name != nil {
+		info.Name = *user.Name
+	}
+	if user.Picture != nil {
+		info.Picture = *user.Picture
+	}
+	if user.Locale != nil {
+		info.Locale = *user.Locale
+	}
+	return info
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"api-core/internal/config"
+	"api-core/internal/datastore/userstore"
+	"api-core/internal/db"
+	appaudit "api-core/pkg/audit"
+	"api-core/pkg/jwtx"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// stubUserStore knows a single user, the other methods are not used.
+type stubUserStore struct {
+	userstore.Store
+	user *userstore.User
+}
+
+func (s *stubUserStore) GetByID(_ context.Context, id int64) (*userstore.User, error) {
+	if s.user == nil || s.user.ID != id {
+		return nil, nil
+	}
+	return s.user, nil
+}
+
+func TestRevokeIsSeenByEveryInstance(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	issuer, err := jwtx.NewHMACIssuer("test-secret", "api-core", time.Hour)
+	if err != nil {
+		t.Fatalf("new issuer: %v", err)
+	}
+	users := &stubUserStore{user: &userstore.User{ID: 42, Email: "alice@example.com"}}
+
+	// two instances sharing the Redis server, each with its own cache
+	newInstance := func() *IntrospectionService {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		cache, err := db.NewSharedCacheRedis(client)
+		if err != nil {
+			t.Fatalf("new cache: %v", err)
+		}
+		return NewIntrospectionService(issuer, users, cache, client, appaudit.Nop(), config.IntrospectionConfig{
+			CacheTTL: time.Minute,
+		})
+	}
+	a, b := newInstance(), newInstance()
+
+	token, err := issuer.Issue("42", "alice@example.com")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	_, claims, err := jwtx.Verify(issuer, token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	result, err := b.Introspect(ctx, token)
+	if err != nil || !result.Active {
+		t.Fatalf("introspect = %+v, %v, want active", result, err)
+	}
+
+	if err := a.Revoke(ctx, claims); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	result, err = b.Introspect(ctx, token)
+	if err != nil || result.Active {
+		t.Fatalf("introspect after revoke = %+v, %v, want inactive", result, err)
+	}
+	revoked, err := b.IsRevoked(ctx, claims.ID)
+	if err != nil || !revoked {
+		t.Fatalf("revoked = %v, %v, want true", revoked, err)
+	}
+}
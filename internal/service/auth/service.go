@@ -2,16 +2,25 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"api-core/internal/config"
 	"api-core/internal/datastore/userstore"
+	"api-core/internal/service/oauthtoken"
+	"api-core/internal/service/signup"
+	appaudit "api-core/pkg/audit"
 	appauth "api-core/pkg/auth"
+	"api-core/pkg/errorx"
 	"api-core/pkg/jwtx"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/oauth2"
@@ -25,9 +34,31 @@ type Service struct {
 	googleOAuth *appauth.GoogleOAuth
 	tokenIssuer *jwtx.HMACIssuer
 	redis       *redis.Client
+	signup      *signup.Engine
+	tokens      *oauthtoken.Service
+	auditor     appaudit.Auditor
+	logger      *slog.Logger
 	cfg         config.GoogleConfig
 }
 
+const (
+	statePurposeLogin   = "login"
+	statePurposeConsent = "consent:"
+	statePurposeReauth  = "reauth:"
+
+	googleScopePrefix = "https://www.googleapis.com/auth/"
+
+	// authTimeSkew tolerates the clock difference with Google when checking
+	// that a re-authentication happened after it was requested.
+	authTimeSkew = time.Minute
+)
+
+// oauthState is stored under the state parameter until the callback.
+type oauthState struct {
+	Purpose   string    `json:"purpose"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type AuthResponse struct {
 	Token string          `json:"token"`
 	User  *userstore.User `json:"user"`
@@ -38,6 +69,10 @@ func NewService(
 	googleOAuth *appauth.GoogleOAuth,
 	tokenIssuer *jwtx.HMACIssuer,
 	redis *redis.Client,
+	signupEngine *signup.Engine,
+	tokens *oauthtoken.Service,
+	auditor appaudit.Auditor,
+	logger *slog.Logger,
 	googleCfg config.GoogleConfig,
 ) *Service {
 	return &Service{
@@ -47,6 +82,10 @@ func NewService(
 		googleOAuth: googleOAuth,
 		tokenIssuer: tokenIssuer,
 		redis:       redis,
+		signup:      signupEngine,
+		tokens:      tokens,
+		auditor:     auditor,
+		logger:      logger,
 		cfg:         googleCfg,
 	}
 }
@@ -55,43 +94,152 @@ func (s *Service) stateKey(state string) string {
 	return s.statePrefix + state
 }
 
-func (s *Service) GenerateLoginURL(ctx context.Context) (string, error) {
-	if s.googleOAuth == nil {
-		return "", errors.New("google oauth not configured")
+// newState stores the purpose of the authorization request, either a login or
+// a scope upgrade for an already signed in user.
+func (s *Service) newState(ctx context.Context, purpose string) (string, error) {
+	raw, err := json.Marshal(oauthState{Purpose: purpose, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return "", fmt.Errorf("encode oauth state: %w", err)
 	}
 
 	state := uuid.NewString()
-	if err := s.redis.Set(ctx, s.stateKey(state), "1", s.stateTTL).Err(); err != nil {
+	if err := s.redis.Set(ctx, s.stateKey(state), raw, s.stateTTL).Err(); err != nil {
 		return "", fmt.Errorf("store oauth state: %w", err)
 	}
-	url := s.googleOAuth.AuthCodeURL(state, oauth2.SetAuthURLParam("response_type", "code"))
-	log.Printf("auth: generated google login url: %s", url)
-	return url, nil
+	return state, nil
+}
+
+// GenerateLoginURL builds the provider login URL, loginHint preselects the
+// account (Google) or the test user (dev identity provider).
+func (s *Service) GenerateLoginURL(ctx context.Context, loginHint string) (string, error) {
+	if s.googleOAuth == nil {
+		return "", errors.New("google oauth not configured")
+	}
+
+	state, err := s.newState(ctx, statePurposeLogin)
+	if err != nil {
+		return "", err
+	}
+	opts := []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("response_type", "code")}
+	if s.tokens.Enabled() {
+		// a refresh token is only returned for offline access
+		opts = append(opts, oauth2.AccessTypeOffline)
+	}
+	if loginHint != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("login_hint", loginHint))
+	}
+	if hd := s.signup.HostedDomain(); hd != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("hd", hd))
+	}
+	s.logger.DebugContext(ctx, "auth: generated google login url", "login_hint", loginHint != "")
+	return s.googleOAuth.AuthCodeURL(state, opts...), nil
+}
+
+// GenerateConsentURL asks the signed in user to grant additional Google scopes
+// on top of the ones already granted (incremental authorization).
+func (s *Service) GenerateConsentURL(ctx context.Context, userID int64, scopes []string) (string, error) {
+	if !s.tokens.Enabled() {
+		return "", errorx.Wrap(oauthtoken.ErrNotConfigured, errorx.Service)
+	}
+	if len(scopes) == 0 {
+		return "", errorx.Wrap(errors.New("missing scope"), errorx.Invalid)
+	}
+
+	state, err := s.newState(ctx, statePurposeConsent+strconv.FormatInt(userID, 10))
+	if err != nil {
+		return "", err
+	}
+
+	requested := append(slices.Clone(s.googleOAuth.Scopes()), normalizeGoogleScopes(scopes)...)
+	return s.googleOAuth.AuthCodeURL(state,
+		oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("response_type", "code"),
+		oauth2.SetAuthURLParam("scope", strings.Join(requested, " ")),
+		oauth2.SetAuthURLParam("include_granted_scopes", "true"),
+		oauth2.SetAuthURLParam("prompt", "consent"),
+	), nil
+}
+
+// GenerateReauthURL forces the signed in user to authenticate with Google again,
+// the callback then issues a token with a fresh auth_time for step-up checks.
+func (s *Service) GenerateReauthURL(ctx context.Context, userID int64, email string) (string, error) {
+	if s.googleOAuth == nil {
+		return "", errors.New("google oauth not configured")
+	}
+
+	state, err := s.newState(ctx, statePurposeReauth+strconv.FormatInt(userID, 10))
+	if err != nil {
+		return "", err
+	}
+
+	// openid makes Google return the id_token carrying auth_time
+	scopes := append([]string{"openid"}, s.googleOAuth.Scopes()...)
+	opts := []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("response_type", "code"),
+		oauth2.SetAuthURLParam("scope", strings.Join(scopes, " ")),
+		oauth2.SetAuthURLParam("prompt", "login"),
+		oauth2.SetAuthURLParam("max_age", "0"),
+	}
+	if email != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("login_hint", email))
+	}
+	return s.googleOAuth.AuthCodeURL(state, opts...), nil
 }
 
 func (s *Service) HandleCallback(ctx context.Context, state, code string) (*AuthResponse, error) {
 	if state == "" || code == "" {
+		s.recordLogin(ctx, "", "", appaudit.OutcomeFailure, "missing state or code")
 		return nil, errors.New("missing state or code")
 	}
 
-	ok, err := s.consumeState(ctx, state)
+	stored, err := s.consumeState(ctx, state)
 	if err != nil {
 		return nil, err
 	}
-	if !ok {
+	if stored == nil {
+		// forged, replayed or expired
+		s.recordLogin(ctx, "", "", appaudit.OutcomeFailure, "invalid state")
 		return nil, errors.New("invalid state")
 	}
+	purpose := stored.Purpose
 
 	token, err := s.googleOAuth.Exchange(ctx, code)
 	if err != nil {
+		s.recordLogin(ctx, "", "", appaudit.OutcomeFailure, "code exchange failed")
 		return nil, fmt.Errorf("google exchange: %w", err)
 	}
 
 	profile, err := s.googleOAuth.FetchUserInfo(ctx, token)
 	if err != nil {
+		s.recordLogin(ctx, "", "", appaudit.OutcomeFailure, "userinfo request failed")
 		return nil, fmt.Errorf("google userinfo: %w", err)
 	}
 
+	authTime, err := s.authTime(token, profile, stored)
+	if err != nil {
+		s.recordLogin(ctx, "", profile.Email, appaudit.OutcomeDenied, err.Error())
+		return nil, err
+	}
+
+	existing, err := s.findGoogleUser(ctx, profile.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// consent and re-authentication must not sign in, create or update
+	// another account
+	if boundUserID, ok := boundUser(purpose); ok && (existing == nil || strconv.FormatInt(existing.ID, 10) != boundUserID) {
+		s.recordLogin(ctx, boundUserID, profile.Email, appaudit.OutcomeDenied, "signed in with another account")
+		return nil, errorx.Wrap(errors.New("signed in with another account"), errorx.Authz)
+	}
+
+	if existing == nil {
+		if err := s.checkSignup(ctx, profile); err != nil {
+			s.recordLogin(ctx, "", profile.Email, appaudit.OutcomeDenied, err.Error())
+			return nil, err
+		}
+	}
+
 	loginAt := time.Now().UTC()
 	user, err := s.userStore.UpsertGoogleUser(ctx, userstore.UpsertGoogleUserParams{
 		GoogleID:      profile.ID,
@@ -106,25 +254,141 @@ func (s *Service) HandleCallback(ctx context.Context, state, code string) (*Auth
 		return nil, fmt.Errorf("upsert user: %w", err)
 	}
 
-	tokenStr, err := s.tokenIssuer.Issue(fmt.Sprintf("%d", user.ID), user.Email)
+	subject := strconv.FormatInt(user.ID, 10)
+
+	if s.tokens.Enabled() {
+		if err := s.tokens.SaveGoogle(ctx, user.ID, profile.ID, token); err != nil {
+			return nil, fmt.Errorf("store google token: %w", err)
+		}
+	}
+
+	tokenStr, err := s.tokenIssuer.IssueClaims(jwtx.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+		Email:            user.Email,
+		AuthTime:         authTime,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("issue token: %w", err)
 	}
 
+	s.recordLogin(ctx, subject, user.Email, appaudit.OutcomeSuccess, "")
+	s.auditor.Record(ctx, appaudit.Event{
+		Actor:    subject,
+		Action:   appaudit.ActionTokenIssued,
+		Outcome:  appaudit.OutcomeSuccess,
+		Metadata: map[string]any{"grant": "google"},
+	})
+
 	return &AuthResponse{
 		Token: tokenStr,
 		User:  user,
 	}, nil
 }
 
-func (s *Service) consumeState(ctx context.Context, state string) (bool, error) {
+// authTime returns the auth_time of the Google id_token, nil when Google did
+// not report it. A re-authentication must report one no older than the
+// request, or the token would pass RequireFreshAuth without a new sign-in.
+func (s *Service) authTime(token *oauth2.Token, profile *appauth.GoogleUserInfo, stored *oauthState) (*jwt.NumericDate, error) {
+	_, reauth := strings.CutPrefix(stored.Purpose, statePurposeReauth)
+
+	idToken, err := s.googleOAuth.IDToken(token)
+	if errors.Is(err, appauth.ErrNoIDToken) && !reauth {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.Authn)
+	}
+	if idToken.Subject != profile.ID {
+		return nil, errorx.Wrap(errors.New("id_token subject does not match the profile"), errorx.Authn)
+	}
+
+	if reauth && (idToken.AuthTime.IsZero() || idToken.AuthTime.Before(stored.CreatedAt.Add(-authTimeSkew))) {
+		return nil, errorx.Wrap(errors.New("google did not re-authenticate the user"), errorx.Reauthentication)
+	}
+	if idToken.AuthTime.IsZero() {
+		return nil, nil
+	}
+	return jwt.NewNumericDate(idToken.AuthTime), nil
+}
+
+// findGoogleUser returns the user of the Google account, nil when it has none yet.
+func (s *Service) findGoogleUser(ctx context.Context, googleID string) (*userstore.User, error) {
+	user, err := s.userStore.GetByGoogleID(ctx, googleID)
+	if errorx.IsNoRows(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup user: %w", err)
+	}
+	return user, nil
+}
+
+// checkSignup evaluates the sign-up policies for a Google account without a user.
+func (s *Service) checkSignup(ctx context.Context, profile *appauth.GoogleUserInfo) error {
+	return s.signup.Evaluate(ctx, signup.Identity{
+		Provider:      signup.ProviderGoogle,
+		Subject:       profile.ID,
+		Email:         profile.Email,
+		EmailVerified: profile.VerifiedEmail,
+		HostedDomain:  profile.HostedDomain,
+	})
+}
+
+func (s *Service) recordLogin(ctx context.Context, actor, email string, outcome appaudit.Outcome, reason string) {
+	metadata := map[string]any{"provider": signup.ProviderGoogle}
+	if email != "" {
+		metadata["email"] = email
+	}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	s.auditor.Record(ctx, appaudit.Event{
+		Actor:    actor,
+		Action:   appaudit.ActionLogin,
+		Outcome:  outcome,
+		Metadata: metadata,
+	})
+}
+
+// boundUser returns the user a consent or re-authentication flow was started for.
+func boundUser(purpose string) (string, bool) {
+	if userID, ok := strings.CutPrefix(purpose, statePurposeConsent); ok {
+		return userID, true
+	}
+	return strings.CutPrefix(purpose, statePurposeReauth)
+}
+
+// consumeState returns the stored state, nil when unknown.
+func (s *Service) consumeState(ctx context.Context, state string) (*oauthState, error) {
 	key := s.stateKey(state)
-	val, err := s.redis.GetDel(ctx, key).Result()
+	raw, err := s.redis.GetDel(ctx, key).Bytes()
 	if errors.Is(err, redis.Nil) {
-		return false, nil
+		return nil, nil
 	}
 	if err != nil {
-		return false, fmt.Errorf("consume state: %w", err)
+		return nil, fmt.Errorf("consume state: %w", err)
+	}
+
+	var stored oauthState
+	if err := json.Unmarshal(raw, &stored); err != nil || stored.Purpose == "" {
+		return nil, nil
+	}
+	return &stored, nil
+}
+
+// normalizeGoogleScopes expands short scope names such as calendar.readonly.
+func normalizeGoogleScopes(scopes []string) []string {
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		switch {
+		case scope == "":
+			continue
+		case scope == "openid" || scope == "email" || scope == "profile" || strings.HasPrefix(scope, "https://"):
+			normalized = append(normalized, scope)
+		default:
+			normalized = append(normalized, googleScopePrefix+scope)
+		}
 	}
-	return val != "", nil
+	return normalized
 }
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"api-core/internal/config"
+	appaudit "api-core/pkg/audit"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type recordingAuditor struct {
+	events []appaudit.Event
+}
+
+func (r *recordingAuditor) Record(_ context.Context, event appaudit.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestHandleCallbackAuditsInvalidState(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	tests := []struct {
+		name       string
+		state      string
+		code       string
+		wantReason string
+	}{
+		{name: "missing state", code: "code", wantReason: "missing state or code"},
+		{name: "missing code", state: "state", wantReason: "missing state or code"},
+		// forged, replayed or expired
+		{name: "unknown state", state: "forged", code: "code", wantReason: "invalid state"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditor := &recordingAuditor{}
+			s := NewService(nil, nil, nil, client, nil, nil, auditor, slog.New(slog.NewTextHandler(io.Discard, nil)), config.GoogleConfig{})
+
+			if _, err := s.HandleCallback(context.Background(), tt.state, tt.code); err == nil {
+				t.Fatal("expected the callback to fail")
+			}
+			if len(auditor.events) != 1 {
+				t.Fatalf("events = %+v, want the failed login", auditor.events)
+			}
+			event := auditor.events[0]
+			if event.Action != appaudit.ActionLogin || event.Outcome != appaudit.OutcomeFailure || event.Metadata["reason"] != tt.wantReason {
+				t.Fatalf("event = %+v, want a failed login for %q", event, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package oauthtoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"api-core/internal/datastore/tokenstore"
+	appauth "api-core/pkg/auth"
+	"api-core/pkg/cryptox"
+	"api-core/pkg/errorx"
+
+	"golang.org/x/oauth2"
+)
+
+// ProviderGoogle is the provider key of Google tokens.
+const ProviderGoogle = "google"
+
+var (
+	ErrNotConfigured = errors.New("oauth token storage: encryption key not configured")
+	ErrNoToken       = errors.New("oauth token storage: no token for user")
+	ErrMissingScopes = errors.New("oauth token storage: missing granted scopes")
+)
+
+// Service persists provider tokens encrypted per identity and hands out token
+// sources that write refreshed tokens back, so background jobs can call
+// Google APIs on behalf of users.
+type Service struct {
+	store       tokenstore.Store
+	aead        *cryptox.AEAD
+	googleOAuth *appauth.GoogleOAuth
+}
+
+// NewService accepts a nil aead, persistence is then disabled.
+func NewService(store tokenstore.Store, aead *cryptox.AEAD, googleOAuth *appauth.GoogleOAuth) *Service {
+	return &Service{
+		store:       store,
+		aead:        aead,
+		googleOAuth: googleOAuth,
+	}
+}
+
+// Enabled reports whether an encryption key is configured.
+func (s *Service) Enabled() bool {
+	return s.aead != nil
+}
+
+// SaveGoogle stores the token returned by the code exchange, granted scopes
+// are merged with the ones already stored to support incremental consent.
+func (s *Service) SaveGoogle(ctx context.Context, userID int64, subject string, token *oauth2.Token) error {
+	if !s.Enabled() {
+		return ErrNotConfigured
+	}
+
+	existing, err := s.store.GetByUser(ctx, userID, ProviderGoogle)
+	if err != nil && !errorx.IsNoRows(err) {
+		return fmt.Errorf("load oauth token: %w", err)
+	}
+
+	var current []string
+	if existing != nil && existing.Subject == subject {
+		current = strings.Fields(existing.Scopes)
+	}
+
+	return s.save(ctx, userID, subject, token, mergeScopes(current, grantedScopes(token)))
+}
+
+// Scopes returns the scopes granted by the user to the provider.
+func (s *Service) Scopes(ctx context.Context, userID int64) ([]string, error) {
+	stored, err := s.store.GetByUser(ctx, userID, ProviderGoogle)
+	if errorx.IsNoRows(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load oauth token: %w", err)
+	}
+	return strings.Fields(stored.Scopes), nil
+}
+
+// TokenSource returns a source for the user's Google token that refreshes
+// when expired and persists the refreshed token. The context is used for
+// refresh calls, pass a long-lived one from background jobs.
+func (s *Service) TokenSource(ctx context.Context, userID int64, requiredScopes ...string) (oauth2.TokenSource, error) {
+	if !s.Enabled() {
+		return nil, ErrNotConfigured
+	}
+
+	stored, err := s.store.GetByUser(ctx, userID, ProviderGoogle)
+	if errorx.IsNoRows(err) {
+		return nil, ErrNoToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load oauth token: %w", err)
+	}
+
+	scopes := strings.Fields(stored.Scopes)
+	for _, scope := range requiredScopes {
+		if !slices.Contains(scopes, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrMissingScopes, scope)
+		}
+	}
+
+	token, err := s.decrypt(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	return &persistingTokenSource{
+		base: oauth2.ReuseTokenSource(token, s.googleOAuth.TokenSource(ctx, token)),
+		last: token.AccessToken,
+		save: func(refreshed *oauth2.Token) error {
+			return s.save(ctx, userID, stored.Subject, refreshed, scopes)
+		},
+	}, nil
+}
+
+// Client returns an HTTP client authorized as the user.
+func (s *Service) Client(ctx context.Context, userID int64, requiredScopes ...string) (*http.Client, error) {
+	ts, err := s.TokenSource(ctx, userID, requiredScopes...)
+	if err != nil {
+		return nil, err
+	}
+	return s.googleOAuth.NewClient(ctx, ts), nil
+}
+
+func (s *Service) save(ctx context.Context, userID int64, subject string, token *oauth2.Token, scopes []string) error {
+	aad := additionalData(ProviderGoogle, subject)
+	accessToken, err := s.aead.Seal([]byte(token.AccessToken), aad)
+	if err != nil {
+		return err
+	}
+
+	var refreshToken []byte
+	if token.RefreshToken != "" {
+		refreshToken, err = s.aead.Seal([]byte(token.RefreshToken), aad)
+		if err != nil {
+			return err
+		}
+	}
+
+	params := tokenstore.UpsertParams{
+		UserID:       userID,
+		Provider:     ProviderGoogle,
+		Subject:      subject,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    token.Type(),
+		Scopes:       strings.Join(scopes, " "),
+	}
+	if !token.Expiry.IsZero() {
+		expiry := token.Expiry.UTC()
+		params.Expiry = &expiry
+	}
+
+	if _, err := s.store.Upsert(ctx, params); err != nil {
+		return fmt.Errorf("store oauth token: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) decrypt(stored *tokenstore.Token) (*oauth2.Token, error) {
+	aad := additionalData(stored.Provider, stored.Subject)
+	accessToken, err := s.aead.Open(stored.AccessToken, aad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt access token: %w", err)
+	}
+
+	token := &oauth2.Token{
+		AccessToken: string(accessToken),
+		TokenType:   stored.TokenType,
+	}
+	if stored.Expiry != nil {
+		token.Expiry = *stored.Expiry
+	}
+	if len(stored.RefreshToken) > 0 {
+		refreshToken, err := s.aead.Open(stored.RefreshToken, aad)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt refresh token: %w", err)
+		}
+		token.RefreshToken = string(refreshToken)
+	}
+	return token, nil
+}
+
+// persistingTokenSource writes a token back whenever the wrapped source
+// returns a new access token.
+type persistingTokenSource struct {
+	base oauth2.TokenSource
+	save func(*oauth2.Token) error
+
+	mu   sync.Mutex
+	last string
+}
+
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := p.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if token.AccessToken != p.last {
+		if err := p.save(token); err != nil {
+			return nil, err
+		}
+		p.last = token.AccessToken
+	}
+	return token, nil
+}
+
+func additionalData(provider, subject string) []byte {
+	return []byte(provider + ":" + subject)
+}
+
+// grantedScopes reads the scope field of the token response.
+func grantedScopes(token *oauth2.Token) []string {
+	scope, _ := token.Extra("scope").(string)
+	return strings.Fields(scope)
+}
+
+func mergeScopes(current, granted []string) []string {
+	merged := slices.Clone(current)
+	for _, scope := range granted {
+		if !slices.Contains(merged, scope) {
+			merged = append(merged, scope)
+		}
+	}
+	slices.Sort(merged)
+	return merged
+}
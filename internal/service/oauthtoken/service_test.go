@@ -0,0 +1,198 @@
+package oauthtoken
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"api-core/internal/datastore/tokenstore"
+	appauth "api-core/pkg/auth"
+	"api-core/pkg/cryptox"
+
+	"golang.org/x/oauth2"
+)
+
+// memoryStore keeps one token per user like the oauth_tokens table.
+type memoryStore struct {
+	tokens  map[int64]*tokenstore.Token
+	upserts int
+}
+
+func (s *memoryStore) GetByUser(_ context.Context, userID int64, _ string) (*tokenstore.Token, error) {
+	token, ok := s.tokens[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	clone := *token
+	return &clone, nil
+}
+
+func (s *memoryStore) Upsert(_ context.Context, params tokenstore.UpsertParams) (*tokenstore.Token, error) {
+	s.upserts++
+	token := &tokenstore.Token{
+		UserID:       params.UserID,
+		Provider:     params.Provider,
+		Subject:      params.Subject,
+		AccessToken:  params.AccessToken,
+		RefreshToken: params.RefreshToken,
+		TokenType:    params.TokenType,
+		Expiry:       params.Expiry,
+		Scopes:       params.Scopes,
+	}
+	// a nil refresh token keeps the stored one
+	if previous, ok := s.tokens[params.UserID]; ok && token.RefreshToken == nil {
+		token.RefreshToken = previous.RefreshToken
+	}
+	s.tokens[params.UserID] = token
+	return token, nil
+}
+
+func newTestService(t *testing.T, tokenURL string) (*Service, *memoryStore) {
+	t.Helper()
+
+	aead, err := cryptox.NewAEAD(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("new aead: %v", err)
+	}
+	google, err := appauth.NewGoogleOAuth(appauth.GoogleOAuthConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://app.test/callback",
+		Endpoint:     oauth2.Endpoint{AuthURL: tokenURL + "/auth", TokenURL: tokenURL + "/token"},
+	})
+	if err != nil {
+		t.Fatalf("new google oauth: %v", err)
+	}
+	store := &memoryStore{tokens: map[int64]*tokenstore.Token{}}
+	return NewService(store, aead, google), store
+}
+
+func grantedToken(access, refresh, scope string) *oauth2.Token {
+	token := &oauth2.Token{AccessToken: access, RefreshToken: refresh, TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}
+	return token.WithExtra(map[string]any{"scope": scope})
+}
+
+func TestSaveGoogleMergesScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		subject    string
+		scope      string
+		wantScopes []string
+	}{
+		{name: "first consent", subject: "g-1", scope: "openid email", wantScopes: []string{"email", "openid"}},
+		// incremental consent returns only the new scope
+		{name: "incremental consent", subject: "g-1", scope: "https://www.googleapis.com/auth/drive.readonly", wantScopes: []string{"email", "https://www.googleapis.com/auth/drive.readonly", "openid"}},
+		{name: "repeated scope", subject: "g-1", scope: "email", wantScopes: []string{"email", "https://www.googleapis.com/auth/drive.readonly", "openid"}},
+		// the scopes of another Google account are not inherited
+		{name: "other account", subject: "g-2", scope: "openid", wantScopes: []string{"openid"}},
+	}
+	s, store := newTestService(t, "http://unused.test")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.SaveGoogle(context.Background(), 1, tt.subject, grantedToken("access", "refresh", tt.scope)); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			scopes, err := s.Scopes(context.Background(), 1)
+			if err != nil || !slices.Equal(scopes, tt.wantScopes) {
+				t.Fatalf("scopes = %v, %v, want %v", scopes, err, tt.wantScopes)
+			}
+		})
+	}
+
+	stored := store.tokens[1]
+	if bytes.Contains(stored.AccessToken, []byte("access")) || bytes.Contains(stored.RefreshToken, []byte("refresh")) {
+		t.Fatal("tokens stored in plaintext")
+	}
+}
+
+func TestSaveGoogleNotConfigured(t *testing.T) {
+	s := NewService(&memoryStore{tokens: map[int64]*tokenstore.Token{}}, nil, nil)
+	if err := s.SaveGoogle(context.Background(), 1, "g-1", grantedToken("access", "", "openid")); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("err = %v, want %v", err, ErrNotConfigured)
+	}
+	if _, err := s.TokenSource(context.Background(), 1); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("err = %v, want %v", err, ErrNotConfigured)
+	}
+}
+
+func TestTokenSourcePersistsRefreshedToken(t *testing.T) {
+	var refreshes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-1" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		refreshes++
+		w.Header().Set("Content-Type", "application/json")
+		// Google does not return the refresh token again
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "access-2", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	s, store := newTestService(t, server.URL)
+	expired := grantedToken("access-1", "refresh-1", "openid email")
+	expired.Expiry = time.Now().Add(-time.Minute)
+	if err := s.SaveGoogle(ctx, 1, "g-1", expired); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	ts, err := s.TokenSource(ctx, 1, "email")
+	if err != nil {
+		t.Fatalf("token source: %v", err)
+	}
+	for range 2 {
+		token, err := ts.Token()
+		if err != nil || token.AccessToken != "access-2" {
+			t.Fatalf("token = %v, %v, want the refreshed token", token, err)
+		}
+	}
+	if refreshes != 1 || store.upserts != 2 {
+		t.Fatalf("refreshes = %d, upserts = %d, want one refresh saved once", refreshes, store.upserts)
+	}
+
+	// a new source, as in the next job run, starts from the refreshed token
+	reloaded, err := s.store.GetByUser(ctx, 1, ProviderGoogle)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	token, err := s.decrypt(reloaded)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if token.AccessToken != "access-2" || token.RefreshToken != "refresh-1" || !token.Expiry.After(time.Now()) {
+		t.Fatalf("stored token = %+v, want the refreshed access token and the refresh token kept", token)
+	}
+	if reloaded.Scopes != "email openid" {
+		t.Fatalf("scopes = %q, want them kept", reloaded.Scopes)
+	}
+}
+
+func TestTokenSourceErrors(t *testing.T) {
+	ctx := context.Background()
+	s, store := newTestService(t, "http://unused.test")
+	if err := s.SaveGoogle(ctx, 1, "g-1", grantedToken("access", "refresh", "openid")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := s.TokenSource(ctx, 2); !errors.Is(err, ErrNoToken) {
+		t.Fatalf("err = %v, want %v", err, ErrNoToken)
+	}
+	if _, err := s.TokenSource(ctx, 1, "https://www.googleapis.com/auth/drive.readonly"); !errors.Is(err, ErrMissingScopes) {
+		t.Fatalf("err = %v, want %v", err, ErrMissingScopes)
+	}
+
+	// the ciphertext is bound to its identity, a row pointing at another
+	// subject cannot be decrypted
+	store.tokens[1].Subject = "g-2"
+	if _, err := s.TokenSource(ctx, 1); !errors.Is(err, cryptox.ErrInvalidCiphertext) {
+		t.Fatalf("err = %v, want %v", err, cryptox.ErrInvalidCiphertext)
+	}
+}
@@ -0,0 +1,186 @@
+package signup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"api-core/internal/config"
+	"api-core/internal/datastore/waitliststore"
+	"api-core/pkg/errorx"
+)
+
+// ProviderGoogle identifies identities coming from Google sign-in.
+const ProviderGoogle = "google"
+
+var (
+	ErrDomainNotAllowed  = errors.New("email domain is not allowed to sign up")
+	ErrHostedDomain      = errors.New("google workspace domain is not allowed to sign up")
+	ErrEmailNotVerified  = errors.New("email address is not verified")
+	ErrPendingApproval   = errors.New("sign-up is pending approval")
+	ErrSignupRejected    = errors.New("sign-up was rejected")
+	ErrWaitlistMalformed = errors.New("unknown waitlist status")
+)
+
+// Identity is what an identity provider asserts about a user signing up.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// HostedDomain is the Google Workspace hd claim, empty for other providers.
+	HostedDomain string
+}
+
+// Domain returns the lower-cased domain part of the email.
+func (i Identity) Domain() string {
+	_, domain, _ := strings.Cut(i.Email, "@")
+	return strings.ToLower(domain)
+}
+
+// Policy decides whether an identity may create a new account.
+type Policy interface {
+	Evaluate(ctx context.Context, identity Identity) error
+}
+
+// PolicyFunc adapts a function to the Policy interface.
+type PolicyFunc func(ctx context.Context, identity Identity) error
+
+func (f PolicyFunc) Evaluate(ctx context.Context, identity Identity) error {
+	return f(ctx, identity)
+}
+
+// Engine runs every policy in order, the first rejection wins.
+type Engine struct {
+	policies     []Policy
+	hostedDomain string
+}
+
+// NewEngine builds the policies enabled by the configuration. The admins pass
+// the invite-only policy, there is nobody to approve the first one.
+func NewEngine(cfg config.SignupConfig, admins []string, waitlist waitliststore.Store, cache CacheInvalidator) *Engine {
+	policies := []Policy{}
+	if len(cfg.AllowedDomains) > 0 {
+		policies = append(policies, DomainAllowlist(cfg.AllowedDomains))
+	}
+	if cfg.HostedDomain != "" {
+		policies = append(policies, HostedDomain(cfg.HostedDomain))
+	}
+	if cfg.Mode == config.SignupModeInviteOnly {
+		policies = append(policies, InviteOnly(waitlist, cache, admins))
+	}
+
+	return &Engine{
+		policies:     policies,
+		hostedDomain: cfg.HostedDomain,
+	}
+}
+
+// HostedDomain returns the Google Workspace domain to request with the hd parameter.
+func (e *Engine) HostedDomain() string {
+	return e.hostedDomain
+}
+
+// Evaluate reports a rejection as an errorx.Authz error.
+func (e *Engine) Evaluate(ctx context.Context, identity Identity) error {
+	for _, policy := range e.policies {
+		err := policy.Evaluate(ctx, identity)
+		if err == nil {
+			continue
+		}
+
+		var target *errorx.Error
+		if errors.As(err, &target) {
+			return err
+		}
+		if isRejection(err) {
+			return errorx.Wrap(err, errorx.Authz)
+		}
+		return errorx.Wrap(fmt.Errorf("signup policy: %w", err), errorx.Service)
+	}
+	return nil
+}
+
+func isRejection(err error) bool {
+	for _, target := range []error{ErrDomainNotAllowed, ErrHostedDomain, ErrEmailNotVerified, ErrPendingApproval, ErrSignupRejected} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// DomainAllowlist only admits verified emails from the given domains.
+func DomainAllowlist(domains []string) Policy {
+	allowed := make([]string, len(domains))
+	for i, d := range domains {
+		allowed[i] = strings.ToLower(strings.TrimPrefix(d, "@"))
+	}
+
+	return PolicyFunc(func(_ context.Context, identity Identity) error {
+		if !identity.EmailVerified {
+			return ErrEmailNotVerified
+		}
+		if !slices.Contains(allowed, identity.Domain()) {
+			return ErrDomainNotAllowed
+		}
+		return nil
+	})
+}
+
+// HostedDomain requires the Google hd claim to match, the hd login parameter
+// is only a hint to the account chooser and can be stripped by the user.
+// Other providers have no hd claim and fall back to the domain allowlist.
+func HostedDomain(domain string) Policy {
+	domain = strings.ToLower(domain)
+
+	return PolicyFunc(func(_ context.Context, identity Identity) error {
+		if identity.Provider != ProviderGoogle {
+			return nil
+		}
+		if strings.ToLower(identity.HostedDomain) != domain {
+			return ErrHostedDomain
+		}
+		return nil
+	})
+}
+
+// InviteOnly requires an approved waitlist entry, unknown emails are added to
+// the waitlist for an admin to review. The verified emails of the admins are
+// let through without an entry.
+func InviteOnly(waitlist waitliststore.Store, cache CacheInvalidator, admins []string) Policy {
+	preapproved := make([]string, len(admins))
+	for i, email := range admins {
+		preapproved[i] = strings.ToLower(email)
+	}
+
+	return PolicyFunc(func(ctx context.Context, identity Identity) error {
+		if !identity.EmailVerified {
+			return ErrEmailNotVerified
+		}
+		if slices.Contains(preapproved, strings.ToLower(identity.Email)) {
+			return nil
+		}
+
+		entry, created, err := waitlist.Join(ctx, strings.ToLower(identity.Email), identity.Provider)
+		if err != nil {
+			return err
+		}
+		if created {
+			// only a new entry changes the waitlist listings
+			invalidateWaitlist(ctx, cache)
+		}
+
+		switch entry.Status {
+		case waitliststore.StatusApproved:
+			return nil
+		case waitliststore.StatusPending:
+			return ErrPendingApproval
+		case waitliststore.StatusRejected:
+			return ErrSignupRejected
+		}
+		return fmt.Errorf("%w: %s", ErrWaitlistMalformed, entry.Status)
+	})
+}
@@ -0,0 +1,218 @@
+package signup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"api-core/internal/config"
+	"api-core/internal/datastore/waitliststore"
+	"api-core/pkg/errorx"
+)
+
+// joinStore returns the entry of each email, adding unknown ones as pending.
+type joinStore struct {
+	waitliststore.Store
+	entries map[string]*waitliststore.Entry
+}
+
+func (s *joinStore) Join(_ context.Context, email, provider string) (*waitliststore.Entry, bool, error) {
+	if entry, ok := s.entries[email]; ok {
+		return entry, false, nil
+	}
+	entry := &waitliststore.Entry{Email: email, Provider: provider, Status: waitliststore.StatusPending}
+	s.entries[email] = entry
+	return entry, true, nil
+}
+
+type countingInvalidator struct {
+	calls int
+}
+
+func (c *countingInvalidator) Invalidate(context.Context, ...string) error {
+	c.calls++
+	return nil
+}
+
+func TestInviteOnlyInvalidatesNewEntries(t *testing.T) {
+	store := &joinStore{entries: map[string]*waitliststore.Entry{
+		"approved@example.com": {Status: waitliststore.StatusApproved},
+	}}
+	cache := &countingInvalidator{}
+	policy := InviteOnly(store, cache, nil)
+
+	tests := []struct {
+		name      string
+		email     string
+		want      error
+		wantCalls int
+	}{
+		{name: "new email", email: "new@example.com", want: ErrPendingApproval, wantCalls: 1},
+		// retried sign-ins of a pending email leave the cache alone
+		{name: "pending email", email: "new@example.com", want: ErrPendingApproval, wantCalls: 1},
+		{name: "approved email", email: "approved@example.com", wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Evaluate(context.Background(), Identity{Provider: "google", Email: tt.email, EmailVerified: true})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if cache.calls != tt.wantCalls {
+				t.Fatalf("invalidations = %d, want %d", cache.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDomainAllowlist(t *testing.T) {
+	policy := DomainAllowlist([]string{"@Example.com", "corp.example.org"})
+
+	tests := []struct {
+		name     string
+		identity Identity
+		want     error
+	}{
+		{name: "allowed", identity: Identity{Email: "a@example.com", EmailVerified: true}},
+		{name: "upper case domain", identity: Identity{Email: "a@EXAMPLE.COM", EmailVerified: true}},
+		{name: "second domain", identity: Identity{Email: "a@corp.example.org", EmailVerified: true}},
+		{name: "other domain", identity: Identity{Email: "a@evil.com", EmailVerified: true}, want: ErrDomainNotAllowed},
+		// a subdomain is not the allowed domain
+		{name: "subdomain", identity: Identity{Email: "a@mail.example.com", EmailVerified: true}, want: ErrDomainNotAllowed},
+		{name: "unverified", identity: Identity{Email: "a@example.com"}, want: ErrEmailNotVerified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := policy.Evaluate(context.Background(), tt.identity); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostedDomain(t *testing.T) {
+	policy := HostedDomain("Example.com")
+
+	tests := []struct {
+		name     string
+		identity Identity
+		want     error
+	}{
+		{name: "matching claim", identity: Identity{Provider: ProviderGoogle, HostedDomain: "example.com"}},
+		{name: "upper case claim", identity: Identity{Provider: ProviderGoogle, HostedDomain: "EXAMPLE.COM"}},
+		{name: "other claim", identity: Identity{Provider: ProviderGoogle, HostedDomain: "evil.com"}, want: ErrHostedDomain},
+		// a personal Google account has no hd claim
+		{name: "no claim", identity: Identity{Provider: ProviderGoogle, Email: "a@example.com"}, want: ErrHostedDomain},
+		{name: "other provider", identity: Identity{Provider: "github", Email: "a@evil.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := policy.Evaluate(context.Background(), tt.identity); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestInviteOnly(t *testing.T) {
+	store := &joinStore{entries: map[string]*waitliststore.Entry{
+		"approved@example.com": {Status: waitliststore.StatusApproved},
+		"pending@example.com":  {Status: waitliststore.StatusPending},
+		"rejected@example.com": {Status: waitliststore.StatusRejected},
+		"odd@example.com":      {Status: "unknown"},
+	}}
+	policy := InviteOnly(store, &countingInvalidator{}, []string{"Admin@example.com"})
+
+	tests := []struct {
+		name         string
+		identity     Identity
+		want         error
+		wantWaitlist bool
+	}{
+		{name: "approved", identity: Identity{Email: "approved@example.com", EmailVerified: true}},
+		{name: "pending", identity: Identity{Email: "pending@example.com", EmailVerified: true}, want: ErrPendingApproval},
+		{name: "rejected", identity: Identity{Email: "rejected@example.com", EmailVerified: true}, want: ErrSignupRejected},
+		{name: "unknown status", identity: Identity{Email: "odd@example.com", EmailVerified: true}, want: ErrWaitlistMalformed},
+		{name: "upper case email", identity: Identity{Email: "Approved@Example.com", EmailVerified: true}},
+		{name: "unverified", identity: Identity{Email: "approved@example.com"}, want: ErrEmailNotVerified},
+		// nobody could approve the first admin
+		{name: "admin", identity: Identity{Email: "admin@EXAMPLE.com", EmailVerified: true}},
+		{name: "unverified admin", identity: Identity{Email: "admin@example.com"}, want: ErrEmailNotVerified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := policy.Evaluate(context.Background(), tt.identity); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if _, ok := store.entries["admin@example.com"]; ok {
+		t.Fatal("admin was added to the waitlist")
+	}
+}
+
+type failingStore struct {
+	waitliststore.Store
+}
+
+func (failingStore) Join(context.Context, string, string) (*waitliststore.Entry, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func TestEngine(t *testing.T) {
+	domains := config.SignupConfig{Mode: config.SignupModeOpen, AllowedDomains: []string{"example.com"}}
+	inviteOnly := config.SignupConfig{Mode: config.SignupModeInviteOnly}
+	invalid := errorx.Wrap(errors.New("bad identity"), errorx.Invalid)
+
+	tests := []struct {
+		name     string
+		engine   *Engine
+		identity Identity
+		want     error
+		wantKind errorx.Kind
+	}{
+		{name: "open", engine: NewEngine(config.SignupConfig{Mode: config.SignupModeOpen}, nil, nil, nil), identity: Identity{Email: "a@evil.com"}},
+		{name: "allowed", engine: NewEngine(domains, nil, nil, nil), identity: Identity{Email: "a@example.com", EmailVerified: true}},
+		{name: "rejection", engine: NewEngine(domains, nil, nil, nil), identity: Identity{Email: "a@evil.com", EmailVerified: true}, want: ErrDomainNotAllowed, wantKind: errorx.Authz},
+		{name: "pending", engine: NewEngine(inviteOnly, nil, &joinStore{entries: map[string]*waitliststore.Entry{}}, &countingInvalidator{}), identity: Identity{Email: "a@example.com", EmailVerified: true}, want: ErrPendingApproval, wantKind: errorx.Authz},
+		{name: "store failure", engine: NewEngine(inviteOnly, nil, failingStore{}, &countingInvalidator{}), identity: Identity{Email: "a@example.com", EmailVerified: true}, wantKind: errorx.Service},
+		{
+			// a policy's own kind is kept
+			name: "classified error",
+			engine: &Engine{policies: []Policy{PolicyFunc(func(context.Context, Identity) error {
+				return invalid
+			})}},
+			want:     invalid,
+			wantKind: errorx.Invalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.engine.Evaluate(context.Background(), tt.identity)
+			if tt.wantKind == errorx.Other {
+				if err != nil {
+					t.Fatalf("err = %v, want none", err)
+				}
+				return
+			}
+			var target *errorx.Error
+			if !errors.As(err, &target) || !target.Of(tt.wantKind) {
+				t.Fatalf("err = %v, want kind %s", err, tt.wantKind.String())
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineHostedDomain(t *testing.T) {
+	engine := NewEngine(config.SignupConfig{Mode: config.SignupModeOpen, HostedDomain: "example.com"}, nil, nil, nil)
+	if got := engine.HostedDomain(); got != "example.com" {
+		t.Fatalf("hosted domain = %q, want example.com", got)
+	}
+	err := engine.Evaluate(context.Background(), Identity{Provider: ProviderGoogle, HostedDomain: "evil.com"})
+	if !errors.Is(err, ErrHostedDomain) {
+		t.Fatalf("err = %v, want %v", err, ErrHostedDomain)
+	}
+}
@@ -0,0 +1,114 @@
+package signup
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"api-core/internal/datastore/waitliststore"
+	appaudit "api-core/pkg/audit"
+	"api-core/pkg/errorx"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// WaitlistCache names the cached waitlist responses.
+const WaitlistCache = "waitlist"
+
+// CacheInvalidator drops cached responses by name, see httpx.ResponseCache.
+type CacheInvalidator interface {
+	Invalidate(ctx context.Context, names ...string) error
+}
+
+// Service exposes waitlist administration.
+type Service struct {
+	waitlist waitliststore.Store
+	auditor  appaudit.Auditor
+	cache    CacheInvalidator
+}
+
+func NewService(waitlist waitliststore.Store, auditor appaudit.Auditor, cache CacheInvalidator) *Service {
+	return &Service{waitlist: waitlist, auditor: auditor, cache: cache}
+}
+
+func (s *Service) List(ctx context.Context, params waitliststore.ListParams) ([]*waitliststore.Entry, error) {
+	if params.Limit <= 0 {
+		params.Limit = defaultListLimit
+	}
+	params.Limit = min(params.Limit, maxListLimit)
+	params.Offset = max(params.Offset, 0)
+
+	switch params.Status {
+	case "", waitliststore.StatusPending, waitliststore.StatusApproved, waitliststore.StatusRejected:
+	default:
+		return nil, errorx.Wrap(errors.New("invalid status filter"), errorx.Invalid)
+	}
+
+	return s.waitlist.List(ctx, params)
+}
+
+// Admin is the administrator acting on the waitlist. The id is the audit actor,
+// the email is stored on the entry.
+type Admin struct {
+	ID    string
+	Email string
+}
+
+func (s *Service) Approve(ctx context.Context, id int64, admin Admin) (*waitliststore.Entry, error) {
+	return s.decide(ctx, id, waitliststore.StatusApproved, admin)
+}
+
+func (s *Service) Reject(ctx context.Context, id int64, admin Admin) (*waitliststore.Entry, error) {
+	return s.decide(ctx, id, waitliststore.StatusRejected, admin)
+}
+
+// Invite approves an email ahead of its first sign-in.
+func (s *Service) Invite(ctx context.Context, email string, admin Admin) (*waitliststore.Entry, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !strings.Contains(email, "@") {
+		return nil, errorx.Wrap(errors.New("invalid email"), errorx.Invalid)
+	}
+
+	entry, err := s.waitlist.Invite(ctx, email, admin.Email)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.Database)
+	}
+	invalidateWaitlist(ctx, s.cache)
+	s.auditor.Record(ctx, appaudit.Event{
+		Actor:    admin.ID,
+		Action:   appaudit.ActionWaitlistInvite,
+		Outcome:  appaudit.OutcomeSuccess,
+		Resource: "waitlist:" + strconv.FormatInt(entry.ID, 10),
+		Metadata: map[string]any{"email": email},
+	})
+	return entry, nil
+}
+
+func (s *Service) decide(ctx context.Context, id int64, status waitliststore.Status, admin Admin) (*waitliststore.Entry, error) {
+	entry, err := s.waitlist.Decide(ctx, id, status, admin.Email)
+	if err != nil {
+		return nil, errorx.Wrap(err, errorx.Database)
+	}
+	invalidateWaitlist(ctx, s.cache)
+	s.auditor.Record(ctx, appaudit.Event{
+		Actor:    admin.ID,
+		Action:   appaudit.ActionWaitlistDecide,
+		Outcome:  appaudit.OutcomeSuccess,
+		Resource: "waitlist:" + strconv.FormatInt(id, 10),
+		Metadata: map[string]any{"email": entry.Email, "status": status},
+	})
+	return entry, nil
+}
+
+// invalidateWaitlist drops the cached waitlist responses. The change is
+// already stored, a failure only leaves them stale until they expire.
+func invalidateWaitlist(ctx context.Context, cache CacheInvalidator) {
+	if err := cache.Invalidate(ctx, WaitlistCache); err != nil {
+		slog.ErrorContext(ctx, "signup: invalidate waitlist cache", "error", err)
+	}
+}
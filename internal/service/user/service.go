@@ -0,0 +1,53 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	bobmodel "api-core/internal/bob"
+	"api-core/internal/datastore/userstore"
+	"api-core/pkg/errorx"
+	"api-core/pkg/pagination"
+)
+
+// Service exposes the user accounts to administrators.
+type Service struct {
+	users userstore.Store
+}
+
+func NewService(users userstore.Store) *Service {
+	return &Service{users: users}
+}
+
+// Account is the administrator view of a user, without the Google identifiers.
+type Account struct {
+	ID            int64      `json:"id"`
+	Email         string     `json:"email"`
+	Name          *string    `json:"name,omitempty"`
+	Picture       *string    `json:"picture,omitempty"`
+	VerifiedEmail bool       `json:"verified_email"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
+}
+
+// List returns a page of the users matching the query.
+func (s *Service) List(ctx context.Context, query *pagination.Query[*bobmodel.User]) ([]*Account, pagination.Pagination, error) {
+	users, page, err := s.users.List(ctx, query)
+	if err != nil {
+		return nil, pagination.Pagination{}, errorx.Wrap(err, errorx.Database)
+	}
+
+	accounts := make([]*Account, len(users))
+	for i, u := range users {
+		accounts[i] = &Account{
+			ID:            u.ID,
+			Email:         u.Email,
+			Name:          u.Name,
+			Picture:       u.Picture,
+			VerifiedEmail: u.VerifiedEmail,
+			CreatedAt:     u.CreatedAt,
+			LastLoginAt:   u.LastLoginAt,
+		}
+	}
+	return accounts, page, nil
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Async records the events of another Auditor in the background, for events
+// any client can trigger at will such as failed authentications. A full
+// buffer drops the events instead of blocking the request.
+type Async struct {
+	auditor Auditor
+	events  chan asyncEvent
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type asyncEvent struct {
+	ctx   context.Context
+	event Event
+}
+
+var _ Auditor = (*Async)(nil)
+
+// NewAsync starts the worker, Shutdown records the buffered events and stops it.
+func NewAsync(auditor Auditor, buffer int) *Async {
+	a := &Async{
+		auditor: auditor,
+		events:  make(chan asyncEvent, max(buffer, 1)),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *Async) run() {
+	defer close(a.done)
+	for e := range a.events {
+		a.auditor.Record(e.ctx, e.event)
+	}
+}
+
+// Record queues the event, the request details are taken from the context
+// right away.
+func (a *Async) Record(ctx context.Context, event Event) {
+	event = FromRequest(ctx, event)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		a.dropped.Add(1)
+		return
+	}
+	select {
+	case a.events <- asyncEvent{ctx: context.WithoutCancel(ctx), event: event}:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events lost to a full buffer or a shutdown.
+func (a *Async) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Shutdown records the buffered events, later ones are dropped.
+func (a *Async) Shutdown() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.events)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+	if dropped := a.Dropped(); dropped > 0 {
+		slog.Warn("audit: dropped events", "count", dropped)
+	}
+	return nil
+}
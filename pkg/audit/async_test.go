@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// recorder keeps the events, an unblock channel holds the first Record.
+type recorder struct {
+	mu      sync.Mutex
+	events  []Event
+	unblock chan struct{}
+}
+
+func (r *recorder) Record(_ context.Context, event Event) {
+	if r.unblock != nil {
+		<-r.unblock
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestAsyncShutdownFlushes(t *testing.T) {
+	rec := &recorder{}
+	a := NewAsync(rec, 16)
+
+	ctx := WithRequest(context.Background(), "203.0.113.7", "curl/8")
+	for range 10 {
+		a.Record(ctx, Event{Action: ActionAuthenticate, Outcome: OutcomeFailure})
+	}
+	if err := a.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if len(rec.events) != 10 {
+		t.Fatalf("recorded events = %d, want 10", len(rec.events))
+	}
+	if got := rec.events[0]; got.IP != "203.0.113.7" || got.UserAgent != "curl/8" {
+		t.Fatalf("event = %+v, want the request details", got)
+	}
+
+	a.Record(ctx, Event{Action: ActionAuthenticate})
+	if a.Dropped() != 1 {
+		t.Fatalf("dropped after shutdown = %d, want 1", a.Dropped())
+	}
+}
+
+func TestAsyncDropsWhenFull(t *testing.T) {
+	rec := &recorder{unblock: make(chan struct{})}
+	a := NewAsync(rec, 2)
+
+	// the worker holds one event, the buffer two, the rest is dropped
+	for range 6 {
+		a.Record(context.Background(), Event{Action: ActionAuthenticate})
+	}
+	close(rec.unblock)
+	if err := a.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if got := len(rec.events) + int(a.Dropped()); got != 6 {
+		t.Fatalf("recorded + dropped = %d, want 6", got)
+	}
+	if a.Dropped() < 3 {
+		t.Fatalf("dropped = %d, want at least 3", a.Dropped())
+	}
+}
@@ -0,0 +1,102 @@
+// Package audit defines security audit events and the Auditor recording them,
+// so pkg middlewares and internal services can emit events without depending
+// on the storage.
+package audit
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"api-core/pkg/requestid"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeDenied  Outcome = "denied"
+)
+
+const (
+	ActionLogin          = "auth.login"
+	ActionTokenIssued    = "auth.token_issued"
+	ActionLogout         = "auth.logout"
+	ActionAuthenticate   = "auth.authenticate"
+	ActionDeviceApproval = "auth.device_approval"
+	ActionAuthorize      = "authz.allow"
+	ActionWaitlistInvite = "admin.waitlist.invite"
+	ActionWaitlistDecide = "admin.waitlist.decide"
+)
+
+// MetadataRequestID is the metadata key of the request id, see FromRequest.
+const MetadataRequestID = "request_id"
+
+// Event is a single audit record. Actor is the user id, empty when there is no
+// user yet (e.g. a rejected sign-up), emails go to the metadata. IP and
+// UserAgent are filled from the request context when empty.
+type Event struct {
+	OccurredAt time.Time
+	Actor      string
+	Action     string
+	Outcome    Outcome
+	Resource   string
+	IP         string
+	UserAgent  string
+	Metadata   map[string]any
+}
+
+// Auditor records events. Recording never fails the caller, implementations
+// report storage errors themselves.
+type Auditor interface {
+	Record(ctx context.Context, event Event)
+}
+
+type nop struct{}
+
+func (nop) Record(context.Context, Event) {}
+
+// Nop returns an Auditor discarding every event.
+func Nop() Auditor {
+	return nop{}
+}
+
+type requestKey struct{}
+
+type requestInfo struct {
+	ip        string
+	userAgent string
+}
+
+// WithRequest attaches the client address and user agent to the context.
+func WithRequest(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, requestKey{}, requestInfo{ip: ip, userAgent: userAgent})
+}
+
+// FromRequest fills the event IP and UserAgent from the context when empty,
+// and adds the request id to the metadata.
+func FromRequest(ctx context.Context, event Event) Event {
+	if id := requestid.RequestID(ctx); id != "" {
+		if _, ok := event.Metadata[MetadataRequestID]; !ok {
+			metadata := maps.Clone(event.Metadata)
+			if metadata == nil {
+				metadata = map[string]any{}
+			}
+			metadata[MetadataRequestID] = id
+			event.Metadata = metadata
+		}
+	}
+
+	info, ok := ctx.Value(requestKey{}).(requestInfo)
+	if !ok {
+		return event
+	}
+	if event.IP == "" {
+		event.IP = info.ip
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = info.userAgent
+	}
+	return event
+}
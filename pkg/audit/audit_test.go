@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"api-core/pkg/requestid"
+)
+
+func TestFromRequest(t *testing.T) {
+	ctx := WithRequest(context.Background(), "203.0.113.7", "curl/8")
+	ctx = requestid.NewContext(ctx, requestid.IDs{RequestID: "req-1"})
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		event         Event
+		wantIP        string
+		wantRequestID any
+	}{
+		{name: "request", ctx: ctx, event: Event{Metadata: map[string]any{"email": "a@example.com"}}, wantIP: "203.0.113.7", wantRequestID: "req-1"},
+		{name: "no metadata", ctx: ctx, wantIP: "203.0.113.7", wantRequestID: "req-1"},
+		{name: "event values kept", ctx: ctx, event: Event{IP: "198.51.100.1", Metadata: map[string]any{MetadataRequestID: "other"}}, wantIP: "198.51.100.1", wantRequestID: "other"},
+		{name: "outside a request", ctx: context.Background()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller := tt.event.Metadata
+			before := len(caller)
+			event := FromRequest(tt.ctx, tt.event)
+			if event.IP != tt.wantIP {
+				t.Fatalf("ip = %q, want %q", event.IP, tt.wantIP)
+			}
+			if got := event.Metadata[MetadataRequestID]; got != tt.wantRequestID {
+				t.Fatalf("request id = %v, want %v", got, tt.wantRequestID)
+			}
+			// the caller's map is not modified
+			if len(caller) != before {
+				t.Fatalf("caller metadata = %v, want it unchanged", caller)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"api-core/pkg/tracing"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/golang-jwt/jwt/v4"
@@ -28,11 +30,20 @@ type AuthnCognito struct {
 }
 
 func NewAuthnCognito(userPoolID, region string) (*AuthnCognito, error) {
+	return NewAuthnCognitoContext(context.Background(), userPoolID, region)
+}
+
+// NewAuthnCognitoContext fetches the JWKs with ctx, propagating its request id.
+func NewAuthnCognitoContext(ctx context.Context, userPoolID, region string) (*AuthnCognito, error) {
 	var cognitoJWKs CognitoJWKs
 
 	// Fetch the Cognito JWKs once at the start
 	cognitoURL := fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s/.well-known/jwks.json", region, userPoolID)
-	resp, err := http.Get(cognitoURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cognitoURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Cognito JWKs: %s", err)
+	}
+	resp, err := tracing.NewHTTPClient(nil).Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Cognito JWKs: %s", err)
 	}
@@ -54,3 +65,12 @@ func NewAuthnCognito(userPoolID, region string) (*AuthnCognito, error) {
 func (authn *AuthnCognito) AuthenticateJWT(tokenStr string) (*jwt.Token, error) {
 	return VerifyToken(authn.CognitoJWKs, tokenStr, authn.Region, authn.UserPoolId)
 }
+
+// HealthCheck fails when no signing key was fetched, it backs the JWKS
+// readiness check of containers providing the authenticator.
+func (authn *AuthnCognito) HealthCheck() error {
+	if len(authn.CognitoJWKs.Keys) == 0 {
+		return fmt.Errorf("no Cognito JWKs loaded for user pool %s", authn.UserPoolId)
+	}
+	return nil
+}
@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"api-core/pkg/tracing"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
@@ -18,12 +22,20 @@ type GoogleOAuthConfig struct {
 	RedirectURL  string
 	Scopes       []string
 	HTTPClient   *http.Client
+	// Endpoint, UserInfoURL and Issuers default to Google, they are
+	// overridden to point at the dev identity provider.
+	Endpoint    oauth2.Endpoint
+	UserInfoURL string
+	// Issuers are the accepted iss of the id_token.
+	Issuers []string
 }
 
 // GoogleOAuth wraps oauth2.Config to simplify Google sign-in/retrieval.
 type GoogleOAuth struct {
 	oauthConfig *oauth2.Config
 	httpClient  *http.Client
+	userInfoURL string
+	issuers     []string
 }
 
 // DefaultGoogleScopes defines the minimal profile information we request.
@@ -43,22 +55,34 @@ func NewGoogleOAuth(cfg GoogleOAuthConfig) (*GoogleOAuth, error) {
 		scopes = DefaultGoogleScopes
 	}
 
+	endpoint := cfg.Endpoint
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" {
+		endpoint = google.Endpoint
+	}
+
+	userInfoURL := cfg.UserInfoURL
+	if userInfoURL == "" {
+		userInfoURL = googleUserInfoEndpoint
+	}
+
+	issuers := cfg.Issuers
+	if len(issuers) == 0 {
+		issuers = googleIssuers
+	}
+
 	config := &oauth2.Config{
 		ClientID:     cfg.ClientID,
 		ClientSecret: cfg.ClientSecret,
 		RedirectURL:  cfg.RedirectURL,
 		Scopes:       scopes,
-		Endpoint:     google.Endpoint,
-	}
-
-	httpClient := cfg.HTTPClient
-	if httpClient == nil {
-		httpClient = http.DefaultClient
+		Endpoint:     endpoint,
 	}
 
 	return &GoogleOAuth{
 		oauthConfig: config,
-		httpClient:  httpClient,
+		httpClient:  tracing.NewHTTPClient(cfg.HTTPClient),
+		userInfoURL: userInfoURL,
+		issuers:     issuers,
 	}, nil
 }
 
@@ -69,7 +93,7 @@ func (g *GoogleOAuth) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) s
 
 // Exchange swaps the authorization code for access+refresh tokens.
 func (g *GoogleOAuth) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
-	token, err := g.oauthConfig.Exchange(ctx, code)
+	token, err := g.oauthConfig.Exchange(g.context(ctx), code)
 	if err != nil {
 		return nil, fmt.Errorf("google oauth exchange: %w", err)
 	}
@@ -81,7 +105,17 @@ func (g *GoogleOAuth) Client(ctx context.Context, token *oauth2.Token) *http.Cli
 	if g.httpClient != nil && token == nil {
 		return g.httpClient
 	}
-	return g.oauthConfig.Client(ctx, token)
+	return g.oauthConfig.Client(g.context(ctx), token)
+}
+
+// NewClient returns an HTTP client authorized by the token source.
+func (g *GoogleOAuth) NewClient(ctx context.Context, ts oauth2.TokenSource) *http.Client {
+	return oauth2.NewClient(g.context(ctx), ts)
+}
+
+// context makes the oauth2 package send its requests through httpClient.
+func (g *GoogleOAuth) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, g.httpClient)
 }
 
 // GoogleUserInfo is the payload returned by Google userinfo endpoint.
@@ -94,6 +128,8 @@ type GoogleUserInfo struct {
 	FamilyName    string `json:"family_name"`
 	Picture       string `json:"picture"`
 	Locale        string `json:"locale"`
+	// HostedDomain is the Google Workspace domain, empty for consumer accounts.
+	HostedDomain string `json:"hd,omitempty"`
 }
 
 const googleUserInfoEndpoint = "https://www.googleapis.com/oauth2/v2/userinfo"
@@ -104,8 +140,12 @@ func (g *GoogleOAuth) FetchUserInfo(ctx context.Context, token *oauth2.Token) (*
 		return nil, errors.New("google oauth: nil token")
 	}
 
-	client := g.oauthConfig.Client(ctx, token)
-	resp, err := client.Get(googleUserInfoEndpoint)
+	client := g.oauthConfig.Client(g.context(ctx), token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.userInfoURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("google oauth userinfo request: %w", err)
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("google oauth userinfo request: %w", err)
 	}
@@ -123,13 +163,69 @@ func (g *GoogleOAuth) FetchUserInfo(ctx context.Context, token *oauth2.Token) (*
 	return &info, nil
 }
 
+// googleIssuers are the iss values of Google id_tokens.
+var googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// GoogleIDToken holds the id_token claims the API relies on.
+type GoogleIDToken struct {
+	Subject string
+	// AuthTime is when the user last authenticated with Google, zero when
+	// the id_token has no auth_time (only set when max_age is requested).
+	AuthTime time.Time
+}
+
+var ErrNoIDToken = errors.New("google oauth: no id_token")
+
+// IDToken returns the claims of the id_token returned by the code exchange.
+// It comes straight from the token endpoint over TLS, so the signature is not
+// checked (OpenID Connect Core 3.1.3.7), the issuer, audience and expiry are.
+func (g *GoogleOAuth) IDToken(token *oauth2.Token) (*GoogleIDToken, error) {
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return nil, ErrNoIDToken
+	}
+
+	var claims struct {
+		jwt.RegisteredClaims
+		AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	}
+	if _, _, err := jwt.NewParser().ParseUnverified(raw, &claims); err != nil {
+		return nil, fmt.Errorf("google oauth id_token: %w", err)
+	}
+	if err := claims.Valid(); err != nil {
+		return nil, fmt.Errorf("google oauth id_token: %w", err)
+	}
+	if !slices.Contains(g.issuers, claims.Issuer) {
+		return nil, fmt.Errorf("google oauth id_token: unexpected issuer %q", claims.Issuer)
+	}
+	if !claims.VerifyAudience(g.oauthConfig.ClientID, true) {
+		return nil, errors.New("google oauth id_token: unexpected audience")
+	}
+
+	idToken := &GoogleIDToken{Subject: claims.Subject}
+	if claims.AuthTime != nil {
+		idToken.AuthTime = claims.AuthTime.Time
+	}
+	return idToken, nil
+}
+
+// TokenSource returns a source that refreshes the token when it expires.
+func (g *GoogleOAuth) TokenSource(ctx context.Context, token *oauth2.Token) oauth2.TokenSource {
+	return g.oauthConfig.TokenSource(g.context(ctx), token)
+}
+
+// Scopes returns the scopes requested at sign-in.
+func (g *GoogleOAuth) Scopes() []string {
+	return g.oauthConfig.Scopes
+}
+
 // RefreshToken refreshes the OAuth token if a refresh token is present.
 func (g *GoogleOAuth) RefreshToken(ctx context.Context, token *oauth2.Token) (*oauth2.Token, error) {
 	if token == nil || token.RefreshToken == "" {
 		return nil, errors.New("google oauth: no refresh token")
 	}
 
-	ts := g.oauthConfig.TokenSource(ctx, token)
+	ts := g.oauthConfig.TokenSource(g.context(ctx), token)
 	newToken, err := ts.Token()
 	if err != nil {
 		return nil, fmt.Errorf("google oauth refresh token: %w", err)
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+)
+
+func TestGoogleIDToken(t *testing.T) {
+	g, err := NewGoogleOAuth(GoogleOAuthConfig{
+		ClientID:     "client-1",
+		ClientSecret: "secret",
+		RedirectURL:  "http://app.test/callback",
+	})
+	if err != nil {
+		t.Fatalf("new google oauth: %v", err)
+	}
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":       "https://accounts.google.com",
+			"sub":       "g-42",
+			"aud":       "client-1",
+			"iat":       now.Unix(),
+			"exp":       now.Add(time.Hour).Unix(),
+			"auth_time": now.Add(-time.Minute).Unix(),
+		}
+	}
+	sign := func(claims jwt.MapClaims) *oauth2.Token {
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("any"))
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return (&oauth2.Token{AccessToken: "at"}).WithExtra(map[string]any{"id_token": raw})
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(jwt.MapClaims)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(jwt.MapClaims) {}},
+		{name: "short issuer", modify: func(c jwt.MapClaims) { c["iss"] = "accounts.google.com" }},
+		{name: "audience array", modify: func(c jwt.MapClaims) { c["aud"] = []string{"other", "client-1"} }},
+		{name: "other issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "other audience", modify: func(c jwt.MapClaims) { c["aud"] = "client-2" }, wantErr: true},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+
+			idToken, err := g.IDToken(sign(claims))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (idToken.Subject != "g-42" || idToken.AuthTime.Unix() != now.Add(-time.Minute).Unix()) {
+				t.Fatalf("id token = %+v", idToken)
+			}
+		})
+	}
+
+	if _, err := g.IDToken(&oauth2.Token{AccessToken: "at"}); !errors.Is(err, ErrNoIDToken) {
+		t.Fatalf("err = %v, want %v", err, ErrNoIDToken)
+	}
+}
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"api-core/pkg/audit"
+	"context"
 	"errors"
+
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/ory/ladon"
 )
@@ -11,11 +14,20 @@ type AuthzAction string
 const (
 	DeleteAuthzAction AuthzAction = "delete"
 	ReadAuthzAction   AuthzAction = "read"
+	WriteAuthzAction  AuthzAction = "write"
 )
 
+// RemoteIPContextKey is the ladon request context key holding the client address.
+const RemoteIPContextKey = "remote_ip"
+
+// SubjectContextKey is the ladon request context key holding the user id, the
+// policies match the email.
+const SubjectContextKey = "subject"
+
 type Guard struct {
-	authn AuthnChecker
-	authz AuthzChecker
+	authn   AuthnChecker
+	authz   AuthzChecker
+	auditor audit.Auditor
 }
 
 type AuthzChecker interface {
@@ -26,23 +38,43 @@ type AuthnChecker interface {
 	AuthenticateJWT(tokenStr string) (*jwt.Token, error)
 }
 
-func NewGuard(authn AuthnChecker, authz AuthzChecker) (*Guard, error) {
+// NewGuard accepts a nil auditor, denials are then not recorded.
+func NewGuard(authn AuthnChecker, authz AuthzChecker, auditor audit.Auditor) (*Guard, error) {
 	if authn == nil || authz == nil {
 		return nil, errors.New("invalid authn or authz")
 	}
+	if auditor == nil {
+		auditor = audit.Nop()
+	}
 
-	return &Guard{authn, authz}, nil
+	return &Guard{authn, authz, auditor}, nil
 }
 
-func (guard *Guard) Allow(sub string, resource string, action AuthzAction, ctx map[string]any) error {
+// Allow checks the policies, ladonCtx is the ladon request context. A denial
+// is recorded with the request context ctx.
+func (guard *Guard) Allow(ctx context.Context, sub string, resource string, action AuthzAction, ladonCtx map[string]any) error {
 	r := &ladon.Request{
 		Subject:  sub,
 		Resource: resource,
 		Action:   string(action),
-		Context:  ctx,
+		Context:  ladonCtx,
+	}
+
+	err := guard.authz.IsAllowed(r)
+	if err != nil {
+		ip, _ := ladonCtx[RemoteIPContextKey].(string)
+		subject, _ := ladonCtx[SubjectContextKey].(string)
+		guard.auditor.Record(ctx, audit.Event{
+			Actor:    subject,
+			Action:   audit.ActionAuthorize,
+			Outcome:  audit.OutcomeDenied,
+			Resource: resource,
+			IP:       ip,
+			Metadata: map[string]any{"action": string(action), "email": sub},
+		})
 	}
 
-	return guard.authz.IsAllowed(r)
+	return err
 }
 
 func (g Guard) AuthenticateJWT(tokenStr string) (*jwt.Token, error) {
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"api-core/pkg/audit"
+	"api-core/pkg/requestid"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type noAuthn struct{}
+
+func (noAuthn) AuthenticateJWT(string) (*jwt.Token, error) {
+	return nil, errors.New("not used")
+}
+
+// recorder keeps the events with the context they were recorded with.
+type recorder struct {
+	events []audit.Event
+	ctxs   []context.Context
+}
+
+func (r *recorder) Record(ctx context.Context, event audit.Event) {
+	r.ctxs = append(r.ctxs, ctx)
+	r.events = append(r.events, audit.FromRequest(ctx, event))
+}
+
+func TestGuardAllow(t *testing.T) {
+	warden, err := NewLadon(AdminPolicies([]string{"admin@example.com"}))
+	if err != nil {
+		t.Fatalf("new ladon: %v", err)
+	}
+	rec := &recorder{}
+	guard, err := NewGuard(noAuthn{}, warden, rec)
+	if err != nil {
+		t.Fatalf("new guard: %v", err)
+	}
+
+	type key struct{}
+	ctx := audit.WithRequest(context.Background(), "203.0.113.7", "curl/8")
+	ctx = requestid.NewContext(ctx, requestid.IDs{RequestID: "req-1"})
+	ctx = context.WithValue(ctx, key{}, "request")
+	ladonCtx := map[string]any{RemoteIPContextKey: "203.0.113.7", SubjectContextKey: "42"}
+
+	if err := guard.Allow(ctx, "admin@example.com", "waitlist", WriteAuthzAction, ladonCtx); err != nil {
+		t.Fatalf("admin: %v", err)
+	}
+	if len(rec.events) != 0 {
+		t.Fatalf("events = %+v, want none for an allowed request", rec.events)
+	}
+
+	if err := guard.Allow(ctx, "user@example.com", "waitlist", WriteAuthzAction, ladonCtx); err == nil {
+		t.Fatal("expected the user to be denied")
+	}
+	if len(rec.events) != 1 {
+		t.Fatalf("events = %d, want the denial", len(rec.events))
+	}
+	if rec.ctxs[0].Value(key{}) != "request" {
+		t.Fatal("denial recorded without the request context")
+	}
+	event := rec.events[0]
+	if event.Actor != "42" || event.Outcome != audit.OutcomeDenied || event.Resource != "waitlist" || event.IP != "203.0.113.7" {
+		t.Fatalf("event = %+v", event)
+	}
+	if event.UserAgent != "curl/8" || event.Metadata[audit.MetadataRequestID] != "req-1" || event.Metadata["email"] != "user@example.com" {
+		t.Fatalf("event = %+v, want the user agent, request id and email", event)
+	}
+}
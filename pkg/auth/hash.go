@@ -1,12 +1,182 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrPasswordMismatch = errors.New("password does not match")
+	ErrUnsupportedHash  = errors.New("unsupported password hash format")
+)
+
+// PasswordHasher hashes passwords and verifies them against stored hashes.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify returns ErrPasswordMismatch when the password is wrong. needsRehash
+	// is true when the stored hash uses another algorithm or outdated parameters.
+	Verify(hash, password string) (needsRehash bool, err error)
+}
+
+// Argon2Params are the Argon2id cost parameters, Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follows the OWASP recommendation (19 MiB, 2 iterations).
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Argon2idHasher produces PHC strings ($argon2id$v=19$m=..,t=..,p=..$salt$key)
+// and still verifies legacy bcrypt hashes, reporting them for rehash.
+type Argon2idHasher struct {
+	params Argon2Params
+}
+
+func NewArgon2idHasher(params Argon2Params) (*Argon2idHasher, error) {
+	if params.Memory < 8*uint32(params.Parallelism) || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, errors.New("invalid argon2id parameters")
+	}
+	if params.SaltLength < 8 || params.KeyLength < 16 {
+		return nil, errors.New("argon2id salt or key too short")
+	}
+	return &Argon2idHasher{params: params}, nil
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(hash, password string) (bool, error) {
+	if isBcryptHash(hash) {
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, ErrPasswordMismatch
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, computed) != 1 {
+		return false, ErrPasswordMismatch
+	}
+
+	return params != h.params, nil
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// VerifyAndUpgrade checks the password and, on success, hands a fresh hash to
+// upgrade when the stored one is outdated. A failing upgrade does not fail the
+// login, it is retried on the next one.
+func VerifyAndUpgrade(hasher PasswordHasher, hash, password string, upgrade func(newHash string) error) error {
+	needsRehash, err := hasher.Verify(hash, password)
+	if err != nil || !needsRehash || upgrade == nil {
+		return err
+	}
+
+	newHash, err := hasher.Hash(password)
+	if err != nil {
+		return nil
+	}
+	//nolint:errcheck
+	upgrade(newHash)
+	return nil
+}
+
+var defaultHasher atomic.Pointer[PasswordHasher]
+
+// SetDefaultHasher makes Hash and CheckPasswordHash use the hasher, the
+// container sets the one built from the configured parameters.
+func SetDefaultHasher(hasher PasswordHasher) {
+	defaultHasher.Store(&hasher)
+}
+
+func loadDefaultHasher() PasswordHasher {
+	if hasher := defaultHasher.Load(); hasher != nil {
+		return *hasher
+	}
+	return &Argon2idHasher{params: DefaultArgon2Params()}
+}
+
+// Hash hashes the password with the default hasher, Argon2id with the
+// DefaultArgon2Params until SetDefaultHasher is called.
 func Hash(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	return loadDefaultHasher().Hash(password)
 }
 
+// CheckPasswordHash verifies Argon2id and legacy bcrypt hashes.
 func CheckPasswordHash(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	_, err := loadDefaultHasher().Verify(hashedPassword, password)
+	return err
 }
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params keep the tests fast, the format is the same as the defaults.
+func testArgon2Params() Argon2Params {
+	return Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func newTestHasher(t *testing.T, params Argon2Params) *Argon2idHasher {
+	t.Helper()
+
+	hasher, err := NewArgon2idHasher(params)
+	if err != nil {
+		t.Fatalf("new hasher: %v", err)
+	}
+	return hasher
+}
+
+func TestArgon2idPHC(t *testing.T) {
+	hasher := newTestHasher(t, testArgon2Params())
+
+	hash, err := hasher.Hash("s3cret")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("hash = %q, want a PHC argon2id string", hash)
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if params != testArgon2Params() || len(salt) != 16 || len(key) != 32 {
+		t.Fatalf("decoded params = %+v, salt %d, key %d", params, len(salt), len(key))
+	}
+
+	other, err := hasher.Hash("s3cret")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if other == hash {
+		t.Fatal("hashes of the same password share the salt")
+	}
+}
+
+func TestArgon2idVerify(t *testing.T) {
+	hasher := newTestHasher(t, testArgon2Params())
+	hash, err := hasher.Hash("s3cret")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	stronger := testArgon2Params()
+	stronger.Iterations = 2
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		params     Argon2Params
+		hash       string
+		password   string
+		wantRehash bool
+		wantErr    error
+	}{
+		{name: "argon2id match", params: testArgon2Params(), hash: hash, password: "s3cret"},
+		{name: "argon2id mismatch", params: testArgon2Params(), hash: hash, password: "wrong", wantErr: ErrPasswordMismatch},
+		{name: "outdated parameters", params: stronger, hash: hash, password: "s3cret", wantRehash: true},
+		{name: "bcrypt match", params: testArgon2Params(), hash: string(bcryptHash), password: "s3cret", wantRehash: true},
+		{name: "bcrypt mismatch", params: testArgon2Params(), hash: string(bcryptHash), password: "wrong", wantErr: ErrPasswordMismatch},
+		{name: "other algorithm", params: testArgon2Params(), hash: "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5", password: "s3cret", wantErr: ErrUnsupportedHash},
+		{name: "other version", params: testArgon2Params(), hash: strings.Replace(hash, "v=19", "v=16", 1), password: "s3cret", wantErr: ErrUnsupportedHash},
+		{name: "malformed", params: testArgon2Params(), hash: "plain", password: "s3cret", wantErr: ErrUnsupportedHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needsRehash, err := newTestHasher(t, tt.params).Verify(tt.hash, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if needsRehash != tt.wantRehash {
+				t.Fatalf("needsRehash = %v, want %v", needsRehash, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestVerifyAndUpgrade(t *testing.T) {
+	hasher := newTestHasher(t, testArgon2Params())
+	legacy, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	var upgraded string
+	err = VerifyAndUpgrade(hasher, string(legacy), "s3cret", func(newHash string) error {
+		upgraded = newHash
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if needsRehash, err := hasher.Verify(upgraded, "s3cret"); err != nil || needsRehash {
+		t.Fatalf("upgraded hash verify = %v, %v, want current argon2id", needsRehash, err)
+	}
+
+	err = VerifyAndUpgrade(hasher, string(legacy), "wrong", func(string) error {
+		t.Fatal("upgrade called for a wrong password")
+		return nil
+	})
+	if !errors.Is(err, ErrPasswordMismatch) {
+		t.Fatalf("err = %v, want %v", err, ErrPasswordMismatch)
+	}
+}
+
+func TestDefaultHasher(t *testing.T) {
+	t.Cleanup(func() { defaultHasher.Store(nil) })
+	SetDefaultHasher(newTestHasher(t, testArgon2Params()))
+
+	hash, err := Hash("s3cret")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !strings.Contains(hash, "$m=64,t=1,p=1$") {
+		t.Fatalf("hash = %q, want the configured parameters", hash)
+	}
+	if err := CheckPasswordHash(hash, "s3cret"); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if err := CheckPasswordHash(hash, "wrong"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Fatalf("check wrong password = %v, want %v", err, ErrPasswordMismatch)
+	}
+}
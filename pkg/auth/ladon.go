@@ -19,3 +19,21 @@ func NewLadon(policies ladon.Policies) (*ladon.Ladon, error) {
 
 	return warden, nil
 }
+
+// AdminPolicies grants every action on every resource to the given subjects.
+func AdminPolicies(subjects []string) ladon.Policies {
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	return ladon.Policies{
+		&ladon.DefaultPolicy{
+			ID:          "admin",
+			Description: "administrators may perform any action",
+			Subjects:    subjects,
+			Resources:   []string{"<.*>"},
+			Actions:     []string{"<.*>"},
+			Effect:      ladon.AllowAccess,
+		},
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"api-core/pkg/jwtx"
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -20,7 +21,7 @@ var ErrInvalidSession = errors.New("invalid session")
 
 // context public setters are not recommended but used here to reuse the logic among 2 packages of middleware
 
-func WithAuthClaims(ctx context.Context, v *jwtx.JWTClaims) context.Context {
+func WithAuthClaims(ctx context.Context, v *jwtx.Claims) context.Context {
 	subject := v.Subject
 	id, err := uuid.Parse(subject)
 	if err != nil {
@@ -43,8 +44,17 @@ func ResolveJWT(ctx context.Context) string {
 	return jwt
 }
 
+func ResolveClaims(ctx context.Context) *jwtx.Claims {
+	claims, ok := ctx.Value(ctxKeyAuthClaims).(*jwtx.Claims)
+	if !ok {
+		return nil
+	}
+
+	return claims
+}
+
 func ResolveSubject(ctx context.Context) string {
-	claims, ok := ctx.Value(ctxKeyAuthClaims).(*jwtx.JWTClaims)
+	claims, ok := ctx.Value(ctxKeyAuthClaims).(*jwtx.Claims)
 	if !ok {
 		return ""
 	}
@@ -53,7 +63,7 @@ func ResolveSubject(ctx context.Context) string {
 }
 
 func ResolveEmail(ctx context.Context) string {
-	claims, ok := ctx.Value(ctxKeyAuthClaims).(*jwtx.JWTClaims)
+	claims, ok := ctx.Value(ctxKeyAuthClaims).(*jwtx.Claims)
 	if !ok {
 		return ""
 	}
@@ -61,6 +71,55 @@ func ResolveEmail(ctx context.Context) string {
 	return claims.Email
 }
 
+// ResolveScopes returns the scopes granted to the token.
+func ResolveScopes(ctx context.Context) []string {
+	claims := ResolveClaims(ctx)
+	if claims == nil {
+		return nil
+	}
+
+	return claims.Scope
+}
+
+func ResolveRoles(ctx context.Context) []string {
+	claims := ResolveClaims(ctx)
+	if claims == nil {
+		return nil
+	}
+
+	return claims.Roles
+}
+
+func ResolveTenant(ctx context.Context) string {
+	claims := ResolveClaims(ctx)
+	if claims == nil {
+		return ""
+	}
+
+	return claims.Tenant
+}
+
+// ResolveAuthTime returns when the user last authenticated, zero without claims.
+func ResolveAuthTime(ctx context.Context) time.Time {
+	claims := ResolveClaims(ctx)
+	if claims == nil {
+		return time.Time{}
+	}
+
+	return claims.AuthenticatedAt()
+}
+
+// ResolveClaim returns an extension claim by name.
+func ResolveClaim(ctx context.Context, name string) (any, bool) {
+	claims := ResolveClaims(ctx)
+	if claims == nil {
+		return nil, false
+	}
+
+	v, ok := claims.Extra[name]
+	return v, ok
+}
+
 func ResolveSubjectUUID(ctx context.Context) (uuid.UUID, bool) {
 	id, ok := ctx.Value(ctxKeyAuthSubject).(uuid.UUID)
 	if !ok {
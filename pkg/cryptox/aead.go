@@ -0,0 +1,68 @@
+package cryptox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCiphertext = errors.New("cryptox: invalid ciphertext")
+
+// AEAD encrypts secrets at rest with AES-256-GCM, the random nonce is
+// prepended to the ciphertext.
+type AEAD struct {
+	aead cipher.AEAD
+}
+
+// NewAEAD expects a 32 byte key.
+func NewAEAD(key []byte) (*AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("cryptox: key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cryptox: new cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cryptox: new gcm: %w", err)
+	}
+
+	return &AEAD{aead: aead}, nil
+}
+
+// NewAEADFromBase64 decodes a standard base64 key, as stored in env variables.
+func NewAEADFromBase64(encoded string) (*AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("cryptox: decode key: %w", err)
+	}
+	return NewAEAD(key)
+}
+
+// Seal encrypts plaintext, additionalData binds the ciphertext to its owner so
+// it cannot be swapped between rows.
+func (a *AEAD) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("cryptox: generate nonce: %w", err)
+	}
+	return a.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (a *AEAD) Open(ciphertext, additionalData []byte) ([]byte, error) {
+	size := a.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := a.aead.Open(nil, ciphertext[:size], ciphertext[size:], additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,94 @@
+package cryptox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newTestAEAD(t *testing.T) *AEAD {
+	t.Helper()
+
+	a, err := NewAEAD(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("new aead: %v", err)
+	}
+	return a
+}
+
+func TestSealOpen(t *testing.T) {
+	a := newTestAEAD(t)
+	aad := []byte("google:1234")
+
+	sealed, err := a.Seal([]byte("ya29.secret"), aad)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("ya29.secret")) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+	opened, err := a.Open(sealed, aad)
+	if err != nil || string(opened) != "ya29.secret" {
+		t.Fatalf("open = %q, %v, want the plaintext", opened, err)
+	}
+
+	// a random nonce per seal
+	again, err := a.Seal([]byte("ya29.secret"), aad)
+	if err != nil || bytes.Equal(again, sealed) {
+		t.Fatalf("second seal = %x, %v, want another ciphertext", again, err)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	other, err := NewAEAD(bytes.Repeat([]byte{8}, 32))
+	if err != nil {
+		t.Fatalf("new aead: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		aead       *AEAD
+		ciphertext []byte
+		aad        []byte
+	}{
+		// a token copied to the row of another identity
+		{name: "other subject", aead: a, ciphertext: sealed, aad: []byte("google:5678")},
+		{name: "other provider", aead: a, ciphertext: sealed, aad: []byte("github:1234")},
+		{name: "no additional data", aead: a, ciphertext: sealed},
+		{name: "tampered", aead: a, ciphertext: tampered, aad: aad},
+		{name: "other key", aead: other, ciphertext: sealed, aad: aad},
+		{name: "shorter than the nonce", aead: a, ciphertext: sealed[:5], aad: aad},
+		{name: "empty", aead: a, aad: aad},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.aead.Open(tt.ciphertext, tt.aad); !errors.Is(err, ErrInvalidCiphertext) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidCiphertext)
+			}
+		})
+	}
+}
+
+func TestNewAEAD(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{name: "32 bytes", encoded: base64.StdEncoding.EncodeToString(make([]byte, 32))},
+		// AES-128 and AES-192 keys are valid for AES but not AES-256
+		{name: "16 bytes", encoded: base64.StdEncoding.EncodeToString(make([]byte, 16)), wantErr: true},
+		{name: "24 bytes", encoded: base64.StdEncoding.EncodeToString(make([]byte, 24)), wantErr: true},
+		{name: "empty", encoded: "", wantErr: true},
+		{name: "not base64", encoded: "not base64!", wantErr: true},
+		{name: "url encoding", encoded: base64.URLEncoding.EncodeToString(bytes.Repeat([]byte{0xfb}, 32)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAEADFromBase64(tt.encoded); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -29,6 +29,14 @@ const (
 	Captcha
 	Database
 	Service
+	// Reauthentication asks the client to sign in again before retrying.
+	Reauthentication
+	// IdempotencyMismatch rejects an Idempotency-Key reused with another payload.
+	IdempotencyMismatch
+	// IdempotencyInProgress rejects a retry while the first request still runs.
+	IdempotencyInProgress
+	// Timeout reports a request cancelled by its deadline.
+	Timeout
 )
 
 func (k Kind) String() string {
@@ -53,6 +61,14 @@ func (k Kind) String() string {
 		return "database-query"
 	case Service:
 		return "internal-service-failure"
+	case Reauthentication:
+		return "reauthentication-required"
+	case IdempotencyMismatch:
+		return "idempotency-key-mismatch"
+	case IdempotencyInProgress:
+		return "idempotency-key-in-progress"
+	case Timeout:
+		return "timeout"
 	}
 
 	return "unknown"
@@ -62,20 +78,22 @@ func (k Kind) HTTPStatus() int {
 	switch k {
 	case Invalid:
 		return http.StatusBadRequest
-	case Validation, Captcha:
+	case Validation, Captcha, IdempotencyMismatch:
 		return http.StatusUnprocessableEntity
 	case NotExist:
 		return http.StatusNotFound
-	case Exist:
+	case Exist, IdempotencyInProgress:
 		return http.StatusConflict
 	case RateLimiting:
 		return http.StatusTooManyRequests
-	case Authn:
+	case Authn, Reauthentication:
 		return http.StatusUnauthorized
 	case Authz:
 		return http.StatusForbidden
 	case Database:
 		return http.StatusInternalServerError
+	case Timeout:
+		return http.StatusServiceUnavailable
 	}
 
 	return http.StatusInternalServerError
@@ -85,6 +103,26 @@ type Error struct {
 	err     error
 	kind    Kind
 	message string
+	fields  []FieldError
+}
+
+// FieldError describes why a single input field was rejected.
+type FieldError struct {
+	// Field is the JSON path of the value, e.g. items[0].email.
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// NewValidation returns a Validation error carrying the rejected fields.
+func NewValidation(fields []FieldError) *Error {
+	return &Error{
+		err:     errors.New("validation failed"),
+		kind:    Validation,
+		message: "validation failed",
+		fields:  fields,
+	}
 }
 
 func (e *Error) Error() string {
@@ -100,6 +138,11 @@ func (e *Error) Of(k Kind) bool {
 	return e.kind == k
 }
 
+// Fields returns the field level details of a validation error.
+func (e *Error) Fields() []FieldError {
+	return e.fields
+}
+
 func (e *Error) Code() string {
 	return e.kind.String()
 }
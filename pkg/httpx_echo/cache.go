@@ -0,0 +1,253 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/cache/v9"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfNoneMatch = "If-None-Match"
+	HeaderCacheStatus = "Cache-Status"
+
+	// cacheStatusName identifies this cache in the Cache-Status header.
+	cacheStatusName = "api-core"
+
+	// cacheVersionTTL outlives every cached response, a version expiring
+	// before its responses would make invalidated responses reachable again.
+	cacheVersionTTL = 30 * 24 * time.Hour
+)
+
+// ResponseCacheStore keeps the cached responses and the versions, Get returns
+// cache.ErrCacheMiss for missing keys. It must be shared by the instances
+// without a local tier, use db.SharedCache: a version read from a local tier
+// would keep serving the responses another instance invalidated.
+type ResponseCacheStore interface {
+	Get(ctx context.Context, key string, target any) error
+	Set(ctx context.Context, key string, value any, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// cachedResponse is the stored success response. Other headers are left to
+// the middlewares of the current request, e.g. CORS depends on its origin.
+type cachedResponse struct {
+	ContentType string
+	Body        []byte
+	ETag        string
+}
+
+// ResponseCacheConfig configures the response cache of a route.
+type ResponseCacheConfig struct {
+	// Name groups the responses invalidated together, e.g. "waitlist".
+	Name string
+	// TTL bounds how long a response is served without invalidation, zero
+	// disables the cache but still answers conditional requests.
+	TTL time.Duration
+	// Shared caches one response for every caller. By default responses are
+	// cached per token subject, as they may depend on the caller.
+	Shared bool
+}
+
+// ResponseCache caches GET responses and answers conditional requests. Each
+// name has a version in the store, part of every key, so Invalidate drops
+// every response of the name, whatever the user or the query, by replacing
+// the version.
+type ResponseCache struct {
+	store  ResponseCacheStore
+	prefix string
+}
+
+func NewResponseCache(store ResponseCacheStore) *ResponseCache {
+	return &ResponseCache{store: store, prefix: "httpcache:"}
+}
+
+// Invalidate drops the cached responses of the names, services call it when
+// the data behind them changes.
+func (rc *ResponseCache) Invalidate(ctx context.Context, names ...string) error {
+	var errs []error
+	for _, name := range names {
+		if err := rc.store.Set(ctx, rc.versionKey(name), newCacheVersion(), cacheVersionTTL); err != nil {
+			errs = append(errs, fmt.Errorf("invalidate %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Cache serves GET responses from the cache and stores the 200 responses of
+// the handler. Responses carry a strong ETag, a request whose If-None-Match
+// matches gets a 304 without body. It must run after Authn unless Shared. A
+// store failure serves the request uncached.
+func (rc *ResponseCache) Cache(cfg ResponseCacheConfig) echo.MiddlewareFunc {
+	cfg.TTL = min(cfg.TTL, cacheVersionTTL)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method != http.MethodGet {
+				return next(c)
+			}
+			ctx := req.Context()
+
+			var key string
+			if cfg.TTL > 0 {
+				key = rc.key(ctx, cfg, req)
+				var cached cachedResponse
+				err := rc.store.Get(ctx, key, &cached)
+				if err == nil {
+					return writeCached(c, cfg, &cached, "hit")
+				}
+				if !errors.Is(err, cache.ErrCacheMiss) {
+					slog.WarnContext(ctx, "response cache: read", "name", cfg.Name, "error", err)
+					key = ""
+				}
+			}
+
+			res := c.Response()
+			original := res.Writer
+			buffer := &bufferingWriter{ResponseWriter: original}
+			res.Writer = buffer
+			if err := next(c); err != nil {
+				// render now so error responses are written through uncached
+				c.Error(err)
+			}
+			res.Writer = original
+
+			if buffer.status != http.StatusOK || res.Header().Get(echo.HeaderSetCookie) != "" {
+				return buffer.flush()
+			}
+
+			// the handler response was held, it is written again below
+			res.Committed = false
+			res.Size = 0
+
+			cached := &cachedResponse{
+				ContentType: res.Header().Get(echo.HeaderContentType),
+				Body:        buffer.body.Bytes(),
+				ETag:        strongETag(buffer.body.Bytes()),
+			}
+			status := "fwd=uri-miss"
+			if key != "" {
+				// the request outlives a client that went away
+				if err := rc.store.Set(context.WithoutCancel(ctx), key, cached, cfg.TTL); err != nil {
+					slog.WarnContext(ctx, "response cache: store", "name", cfg.Name, "error", err)
+				} else {
+					status += "; stored"
+				}
+			}
+			return writeCached(c, cfg, cached, status)
+		}
+	}
+}
+
+// key scopes the request to the name version and the caller. The query is
+// sorted, so parameter order does not split the cache.
+func (rc *ResponseCache) key(ctx context.Context, cfg ResponseCacheConfig, req *http.Request) string {
+	version := "0"
+	if err := rc.store.Get(ctx, rc.versionKey(cfg.Name), &version); err != nil && !errors.Is(err, cache.ErrCacheMiss) {
+		slog.WarnContext(ctx, "response cache: read version", "name", cfg.Name, "error", err)
+	}
+
+	scope := "shared"
+	if !cfg.Shared {
+		scope = idempotencyScope(ctx)
+	}
+
+	h := sha256.Sum256([]byte(req.URL.Path + "?" + req.URL.Query().Encode()))
+	return rc.prefix + cfg.Name + ":" + version + ":" + scope + ":" + hex.EncodeToString(h[:])
+}
+
+func (rc *ResponseCache) versionKey(name string) string {
+	return rc.prefix + name + ":version"
+}
+
+// writeCached writes the response, or a 304 when the client holds it.
+func writeCached(c echo.Context, cfg ResponseCacheConfig, cached *cachedResponse, status string) error {
+	header := c.Response().Header()
+	header.Set(echo.HeaderCacheControl, "no-cache")
+	if !cfg.Shared {
+		header.Set(echo.HeaderCacheControl, "private, no-cache")
+		header.Add(echo.HeaderVary, echo.HeaderAuthorization)
+	}
+	header.Set(HeaderETag, cached.ETag)
+	header.Set(HeaderCacheStatus, cacheStatusName+"; "+status)
+
+	if etagMatches(c.Request().Header.Get(HeaderIfNoneMatch), cached.ETag) {
+		header.Del(echo.HeaderContentType)
+		header.Del(echo.HeaderContentLength)
+		return c.NoContent(http.StatusNotModified)
+	}
+	if cached.ContentType != "" {
+		header.Set(echo.HeaderContentType, cached.ContentType)
+	}
+	c.Response().WriteHeader(http.StatusOK)
+	_, err := c.Response().Write(cached.Body)
+	return err
+}
+
+// strongETag is the hash of the body, equal bodies share the ETag across
+// instances and cache entries.
+func strongETag(body []byte) string {
+	h := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// etagMatches applies the weak comparison of If-None-Match (RFC 9110 13.1.2).
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func newCacheVersion() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// bufferingWriter holds the response until the ETag is computed.
+type bufferingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// flush writes the held response unchanged.
+func (w *bufferingWriter) flush() error {
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	return err
+}
@@ -0,0 +1,198 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"api-core/pkg/auth"
+	"api-core/pkg/jwtx"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/cache/v9"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// cachedServer answers with the number of handler calls, per instance.
+type cachedServer struct {
+	e     *echo.Echo
+	cache *ResponseCache
+	calls int
+}
+
+// newCachedServer mounts /items cached per user and /public shared. The
+// X-User header stands in for the authentication.
+func newCachedServer(store ResponseCacheStore) *cachedServer {
+	s := &cachedServer{e: echo.New(), cache: NewResponseCache(store)}
+	authn := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if user := c.Request().Header.Get("X-User"); user != "" {
+				claims := &jwtx.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: user}}
+				c.SetRequest(c.Request().WithContext(auth.WithAuthClaims(c.Request().Context(), claims)))
+			}
+			return next(c)
+		}
+	}
+	handler := func(c echo.Context) error {
+		s.calls++
+		return c.JSON(http.StatusOK, map[string]string{"call": strconv.Itoa(s.calls), "user": c.Request().Header.Get("X-User")})
+	}
+	s.e.GET("/items", handler, authn, s.cache.Cache(ResponseCacheConfig{Name: "items", TTL: time.Minute}))
+	s.e.GET("/public", handler, s.cache.Cache(ResponseCacheConfig{Name: "items", TTL: time.Minute, Shared: true}))
+	return s
+}
+
+func (s *cachedServer) get(target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// newSharedStore is a Redis only store, like db.SharedCache.
+func newSharedStore(t *testing.T) ResponseCacheStore {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	return redisStore{cache.New(&cache.Options{Redis: redis.NewClient(&redis.Options{Addr: mr.Addr()})})}
+}
+
+type redisStore struct {
+	c *cache.Cache
+}
+
+func (s redisStore) Get(ctx context.Context, key string, target any) error {
+	return s.c.Get(ctx, key, target)
+}
+
+func (s redisStore) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	return s.c.Set(&cache.Item{Ctx: ctx, Key: key, Value: value, TTL: ttl})
+}
+
+func (s redisStore) Delete(ctx context.Context, key string) error {
+	return s.c.Delete(ctx, key)
+}
+
+func TestResponseCacheETag(t *testing.T) {
+	s := newCachedServer(newSharedStore(t))
+
+	first := s.get("/items?b=2&a=1", "X-User", "alice")
+	etag := first.Header().Get(HeaderETag)
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get(HeaderCacheStatus) != "api-core; fwd=uri-miss; stored" {
+		t.Fatalf("status = %d, headers = %v, want a stored response with an ETag", first.Code, first.Header())
+	}
+	if got := first.Header().Get(echo.HeaderCacheControl); got != "private, no-cache" {
+		t.Fatalf("Cache-Control = %q, want private", got)
+	}
+
+	// the query order does not split the cache
+	hit := s.get("/items?a=1&b=2", "X-User", "alice")
+	if hit.Code != http.StatusOK || hit.Body.String() != first.Body.String() || s.calls != 1 {
+		t.Fatalf("hit = %d %s after %d calls, want the cached response", hit.Code, hit.Body, s.calls)
+	}
+	if hit.Header().Get(HeaderETag) != etag || hit.Header().Get(HeaderCacheStatus) != "api-core; hit" {
+		t.Fatalf("headers = %v, want the same ETag and a hit", hit.Header())
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{name: "same etag", ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "weak etag", ifNoneMatch: "W/" + etag, want: http.StatusNotModified},
+		{name: "in a list", ifNoneMatch: `"other", ` + etag, want: http.StatusNotModified},
+		{name: "any", ifNoneMatch: "*", want: http.StatusNotModified},
+		{name: "other etag", ifNoneMatch: `"other"`, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.get("/items?a=1&b=2", "X-User", "alice", HeaderIfNoneMatch, tt.ifNoneMatch)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && (rec.Body.Len() != 0 || rec.Header().Get(echo.HeaderContentType) != "") {
+				t.Fatalf("304 = %q, headers = %v, want no body", rec.Body, rec.Header())
+			}
+		})
+	}
+}
+
+func TestResponseCachePerUser(t *testing.T) {
+	s := newCachedServer(newSharedStore(t))
+
+	alice := s.get("/items", "X-User", "alice")
+	bob := s.get("/items", "X-User", "bob")
+	anonymous := s.get("/items")
+	if s.calls != 3 || alice.Body.String() == bob.Body.String() || bob.Body.String() == anonymous.Body.String() {
+		t.Fatalf("calls = %d, bodies %s %s %s, want one response per caller", s.calls, alice.Body, bob.Body, anonymous.Body)
+	}
+	if got := bob.Header().Get(echo.HeaderVary); got != echo.HeaderAuthorization {
+		t.Fatalf("Vary = %q, want Authorization", got)
+	}
+
+	if rec := s.get("/items", "X-User", "bob"); rec.Body.String() != bob.Body.String() || s.calls != 3 {
+		t.Fatalf("body = %s after %d calls, want bob's cached response", rec.Body, s.calls)
+	}
+
+	// a shared route caches one response for every caller
+	s.get("/public", "X-User", "alice")
+	if rec := s.get("/public", "X-User", "bob"); rec.Header().Get(HeaderCacheStatus) != "api-core; hit" || s.calls != 4 {
+		t.Fatalf("headers = %v after %d calls, want the shared response", rec.Header(), s.calls)
+	}
+}
+
+func TestResponseCacheInvalidate(t *testing.T) {
+	store := newSharedStore(t)
+	// two instances on the same store
+	a, b := newCachedServer(store), newCachedServer(store)
+
+	first := a.get("/items", "X-User", "alice")
+	if rec := b.get("/items", "X-User", "alice"); rec.Body.String() != first.Body.String() || b.calls != 0 {
+		t.Fatalf("body = %s, want the response cached by the other instance", rec.Body)
+	}
+
+	if err := b.cache.Invalidate(context.Background(), "items"); err != nil {
+		t.Fatalf("invalidate: %v", err)
+	}
+	if rec := a.get("/items", "X-User", "alice", HeaderIfNoneMatch, first.Header().Get(HeaderETag)); rec.Code != http.StatusOK || a.calls != 2 {
+		t.Fatalf("status = %d after %d calls, want the handler to run again", rec.Code, a.calls)
+	}
+
+	// other names are untouched
+	if err := a.cache.Invalidate(context.Background(), "other"); err != nil {
+		t.Fatalf("invalidate: %v", err)
+	}
+	if a.get("/items", "X-User", "alice"); a.calls != 2 {
+		t.Fatalf("calls = %d, want the response still cached", a.calls)
+	}
+}
+
+func TestResponseCacheSkipsErrors(t *testing.T) {
+	store := newSharedStore(t)
+	e := echo.New()
+	calls := 0
+	e.GET("/items", func(c echo.Context) error {
+		calls++
+		return echo.NewHTTPError(http.StatusNotFound)
+	}, NewResponseCache(store).Cache(ResponseCacheConfig{Name: "items", TTL: time.Minute, Shared: true}))
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+		if rec.Code != http.StatusNotFound || rec.Header().Get(HeaderETag) != "" {
+			t.Fatalf("status = %d, headers = %v, want an uncached 404", rec.Code, rec.Header())
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d, want errors not cached", calls)
+	}
+}
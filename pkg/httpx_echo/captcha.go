@@ -0,0 +1,236 @@
+package httpx
+
+import (
+	"api-core/pkg/errorx"
+	"api-core/pkg/tracing"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	RecaptchaVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+	HCaptchaVerifyURL  = "https://api.hcaptcha.com/siteverify"
+	TurnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+	// CaptchaHeader is the default header carrying the captcha token.
+	CaptchaHeader = "X-Captcha-Token"
+
+	defaultCaptchaTimeout = 5 * time.Second
+	maxCaptchaBodySize    = 1 << 20
+)
+
+var ErrCaptchaFailed = errors.New("captcha failed")
+
+// CaptchaVerifier checks a captcha token against the provider, a rejected
+// token is reported as an errorx.Captcha error.
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// CaptchaConfig configures a siteverify style verifier. Endpoint and Timeout
+// fall back to the provider defaults, HTTPClient to a client using Timeout.
+type CaptchaConfig struct {
+	Secret     string
+	Endpoint   string
+	Timeout    time.Duration
+	HTTPClient *http.Client
+	// MinScore is only enforced for providers returning a score (reCAPTCHA v3).
+	MinScore float64
+	// Action must match the action sent by the widget when not empty.
+	Action string
+}
+
+type CaptchaVerifyResponse struct {
+	Success     bool      `json:"success"`
+	Score       float64   `json:"score"`
+	Action      string    `json:"action"`
+	ChallengeTS time.Time `json:"challenge_ts"`
+	Hostname    string    `json:"hostname"`
+	ErrorCodes  []string  `json:"error-codes"`
+}
+
+type siteVerifier struct {
+	cfg       CaptchaConfig
+	client    *http.Client
+	useScore  bool
+	useAction bool
+}
+
+// NewRecaptchaVerifier verifies Google reCAPTCHA v3 tokens, checking score and action.
+func NewRecaptchaVerifier(cfg CaptchaConfig) (CaptchaVerifier, error) {
+	return newSiteVerifier(cfg, RecaptchaVerifyURL, true, true)
+}
+
+// NewHCaptchaVerifier verifies hCaptcha tokens.
+func NewHCaptchaVerifier(cfg CaptchaConfig) (CaptchaVerifier, error) {
+	return newSiteVerifier(cfg, HCaptchaVerifyURL, false, false)
+}
+
+// NewTurnstileVerifier verifies Cloudflare Turnstile tokens, checking the action.
+func NewTurnstileVerifier(cfg CaptchaConfig) (CaptchaVerifier, error) {
+	return newSiteVerifier(cfg, TurnstileVerifyURL, false, true)
+}
+
+func newSiteVerifier(cfg CaptchaConfig, endpoint string, useScore, useAction bool) (*siteVerifier, error) {
+	if cfg.Secret == "" {
+		return nil, errors.New("missing captcha secret")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = endpoint
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultCaptchaTimeout
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	return &siteVerifier{cfg: cfg, client: tracing.NewHTTPClient(client), useScore: useScore, useAction: useAction}, nil
+}
+
+func (v *siteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return errorx.Wrap(errors.New("missing captcha token"), errorx.Captcha)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.cfg.Timeout)
+	defer cancel()
+
+	form := url.Values{
+		"secret":   {v.cfg.Secret},
+		"response": {token},
+	}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.cfg.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errorx.Wrap(err, errorx.Service)
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return errorx.Wrap(fmt.Errorf("captcha verify: %w", err), errorx.Service)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errorx.Wrap(fmt.Errorf("captcha verify: unexpected status %d", resp.StatusCode), errorx.Service)
+	}
+
+	var body CaptchaVerifyResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return errorx.Wrap(fmt.Errorf("captcha verify: %w", err), errorx.Service)
+	}
+
+	if !body.Success {
+		return errorx.Wrap(ErrCaptchaFailed, errorx.Captcha)
+	}
+	if v.useAction && v.cfg.Action != "" && body.Action != v.cfg.Action {
+		return errorx.Wrap(ErrCaptchaFailed, errorx.Captcha)
+	}
+	if v.useScore && body.Score < v.cfg.MinScore {
+		return errorx.Wrap(ErrCaptchaFailed, errorx.Captcha)
+	}
+
+	return nil
+}
+
+// CaptchaTokenSource extracts the captcha token from the request, returning an
+// empty string when the token is not present.
+type CaptchaTokenSource func(c echo.Context) (string, error)
+
+// CaptchaFromHeader reads the token from a request header.
+func CaptchaFromHeader(name string) CaptchaTokenSource {
+	return func(c echo.Context) (string, error) {
+		return strings.TrimSpace(c.Request().Header.Get(name)), nil
+	}
+}
+
+// CaptchaFromBody reads the token from a JSON or form body field. The body is
+// buffered and restored so the handler can still bind it.
+func CaptchaFromBody(field string) CaptchaTokenSource {
+	return func(c echo.Context) (string, error) {
+		req := c.Request()
+		if req.Body == nil || req.Body == http.NoBody {
+			return "", nil
+		}
+
+		// oversized bodies are passed through untouched and not inspected
+		orig := req.Body
+		raw, err := io.ReadAll(io.LimitReader(orig, maxCaptchaBodySize+1))
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(raw), orig), orig}
+		if err != nil {
+			return "", errorx.Wrap(err, errorx.Invalid)
+		}
+		if len(raw) == 0 || len(raw) > maxCaptchaBodySize {
+			return "", nil
+		}
+
+		contentType := req.Header.Get(echo.HeaderContentType)
+		switch {
+		case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+			var payload map[string]any
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return "", errorx.Wrap(err, errorx.Invalid)
+			}
+			token, _ := payload[field].(string)
+			return token, nil
+		case strings.HasPrefix(contentType, echo.MIMEApplicationForm):
+			values, err := url.ParseQuery(string(raw))
+			if err != nil {
+				return "", errorx.Wrap(err, errorx.Invalid)
+			}
+			return values.Get(field), nil
+		}
+
+		return "", nil
+	}
+}
+
+// CaptchaValid rejects requests without a valid captcha token. Sources are
+// tried in order, by default the X-Captcha-Token header then the "captcha"
+// body field.
+func CaptchaValid(verifier CaptchaVerifier, sources ...CaptchaTokenSource) echo.MiddlewareFunc {
+	if len(sources) == 0 {
+		sources = []CaptchaTokenSource{CaptchaFromHeader(CaptchaHeader), CaptchaFromBody("captcha")}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var token string
+			for _, source := range sources {
+				var err error
+				if token, err = source(c); err != nil {
+					return Abort(c, err)
+				}
+				if token != "" {
+					break
+				}
+			}
+
+			if err := verifier.Verify(c.Request().Context(), token, c.RealIP()); err != nil {
+				return Abort(c, err)
+			}
+
+			return next(c)
+		}
+	}
+}
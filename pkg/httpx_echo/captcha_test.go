@@ -0,0 +1,203 @@
+package httpx
+
+import (
+	"api-core/pkg/errorx"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const testCaptchaSecret = "test-secret"
+
+// newStubSiteverify serves the siteverify API, accepting the "good" token only.
+func newStubSiteverify(t *testing.T, resp CaptchaVerifyResponse) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		if r.PostForm.Get("secret") != testCaptchaSecret {
+			t.Errorf("secret = %q, want %q", r.PostForm.Get("secret"), testCaptchaSecret)
+		}
+
+		body := resp
+		if r.PostForm.Get("response") != "good" {
+			body = CaptchaVerifyResponse{Success: false, ErrorCodes: []string{"invalid-input-response"}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func assertCaptchaKind(t *testing.T, err error, kind errorx.Kind) {
+	t.Helper()
+
+	var target *errorx.Error
+	if !errors.As(err, &target) || !target.Of(kind) {
+		t.Fatalf("err = %v, want kind %s", err, kind)
+	}
+}
+
+func TestVerifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		newFunc func(CaptchaConfig) (CaptchaVerifier, error)
+		resp    CaptchaVerifyResponse
+		cfg     CaptchaConfig
+		token   string
+		wantErr bool
+	}{
+		{
+			name:    "recaptcha accepts high score",
+			newFunc: NewRecaptchaVerifier,
+			resp:    CaptchaVerifyResponse{Success: true, Score: 0.9, Action: "signup"},
+			cfg:     CaptchaConfig{MinScore: 0.5, Action: "signup"},
+			token:   "good",
+		},
+		{
+			name:    "recaptcha rejects low score",
+			newFunc: NewRecaptchaVerifier,
+			resp:    CaptchaVerifyResponse{Success: true, Score: 0.1, Action: "signup"},
+			cfg:     CaptchaConfig{MinScore: 0.5, Action: "signup"},
+			token:   "good",
+			wantErr: true,
+		},
+		{
+			name:    "recaptcha rejects other action",
+			newFunc: NewRecaptchaVerifier,
+			resp:    CaptchaVerifyResponse{Success: true, Score: 0.9, Action: "login"},
+			cfg:     CaptchaConfig{Action: "signup"},
+			token:   "good",
+			wantErr: true,
+		},
+		{
+			name:    "hcaptcha ignores score",
+			newFunc: NewHCaptchaVerifier,
+			resp:    CaptchaVerifyResponse{Success: true},
+			cfg:     CaptchaConfig{MinScore: 0.5},
+			token:   "good",
+		},
+		{
+			name:    "turnstile rejects invalid token",
+			newFunc: NewTurnstileVerifier,
+			resp:    CaptchaVerifyResponse{Success: true},
+			token:   "bad",
+			wantErr: true,
+		},
+		{
+			name:    "missing token",
+			newFunc: NewTurnstileVerifier,
+			resp:    CaptchaVerifyResponse{Success: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newStubSiteverify(t, tt.resp)
+			cfg := tt.cfg
+			cfg.Secret = testCaptchaSecret
+			cfg.Endpoint = srv.URL
+
+			verifier, err := tt.newFunc(cfg)
+			if err != nil {
+				t.Fatalf("new verifier: %v", err)
+			}
+
+			err = verifier.Verify(context.Background(), tt.token, "")
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("verify: %v", err)
+				}
+				return
+			}
+			assertCaptchaKind(t, err, errorx.Captcha)
+		})
+	}
+}
+
+func TestVerifierTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	verifier, err := NewHCaptchaVerifier(CaptchaConfig{
+		Secret:   testCaptchaSecret,
+		Endpoint: srv.URL,
+		Timeout:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("new verifier: %v", err)
+	}
+
+	err = verifier.Verify(context.Background(), "good", "")
+	assertCaptchaKind(t, err, errorx.Service)
+}
+
+func TestCaptchaValidKeepsBody(t *testing.T) {
+	srv := newStubSiteverify(t, CaptchaVerifyResponse{Success: true})
+	verifier, err := NewTurnstileVerifier(CaptchaConfig{Secret: testCaptchaSecret, Endpoint: srv.URL})
+	if err != nil {
+		t.Fatalf("new verifier: %v", err)
+	}
+
+	e := echo.New()
+	e.POST("/signup", func(c echo.Context) error {
+		var payload struct {
+			Email   string `json:"email"`
+			Captcha string `json:"captcha"`
+		}
+		if err := c.Bind(&payload); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, payload.Email)
+	}, CaptchaValid(verifier))
+
+	tests := []struct {
+		name       string
+		header     string
+		body       string
+		wantStatus int
+	}{
+		{name: "body token", body: `{"email":"a@example.com","captcha":"good"}`, wantStatus: http.StatusOK},
+		{name: "header token", header: "good", body: `{"email":"a@example.com"}`, wantStatus: http.StatusOK},
+		{name: "invalid token", body: `{"email":"a@example.com","captcha":"bad"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "missing token", body: `{"email":"a@example.com"}`, wantStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.header != "" {
+				req.Header.Set(CaptchaHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				got, _ := io.ReadAll(rec.Body)
+				if string(got) != "a@example.com" {
+					t.Fatalf("handler saw body %q", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package httpx
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"api-core/pkg/requestid"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/net/publicsuffix"
+)
+
+// CORSConfig configures the cross-origin requests. Empty headers and methods
+// fall back to the ones the API uses.
+type CORSConfig struct {
+	// AllowOrigins are origins such as https://app.example.com. A * label
+	// matches any subdomain (https://*.example.com) and a * port any port
+	// (http://localhost:*). A single * allows every origin, without
+	// credentials. No origins disables CORS. Wildcards over a public suffix,
+	// such as https://*.com or https://*.github.io, are ignored.
+	AllowOrigins     []string
+	AllowHeaders     []string
+	AllowMethods     []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+var (
+	defaultCORSHeaders = []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, echo.HeaderXRequestID, requestid.HeaderTraceparent, HeaderIdempotencyKey, HeaderIfNoneMatch}
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions}
+	corsExposeHeaders  = []string{echo.HeaderXRequestID, requestid.HeaderTraceresponse, HeaderIdempotentReplayed, HeaderETag, HeaderCacheStatus}
+)
+
+// CORS answers the preflight requests and sets the CORS headers of the
+// allowed origins. Other origins get no CORS headers, so browsers block them.
+func CORS(cfg CORSConfig) echo.MiddlewareFunc {
+	if len(cfg.AllowHeaders) == 0 {
+		cfg.AllowHeaders = defaultCORSHeaders
+	}
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = defaultCORSMethods
+	}
+
+	config := middleware.CORSConfig{
+		AllowHeaders:  cfg.AllowHeaders,
+		AllowMethods:  cfg.AllowMethods,
+		ExposeHeaders: corsExposeHeaders,
+		MaxAge:        int(cfg.MaxAge.Seconds()),
+	}
+	if len(cfg.AllowOrigins) == 1 && cfg.AllowOrigins[0] == "*" {
+		// browsers reject credentials with a wildcard origin
+		config.AllowOrigins = cfg.AllowOrigins
+	} else {
+		config.AllowOriginFunc = NewOriginMatcher(cfg.AllowOrigins).Match
+		config.AllowCredentials = cfg.AllowCredentials
+	}
+	return middleware.CORSWithConfig(config)
+}
+
+// OriginMatcher matches request origins against exact and wildcard origins.
+type OriginMatcher struct {
+	patterns []originPattern
+}
+
+type originPattern struct {
+	scheme string
+	// labels of the host, a * label matches one or more labels
+	labels []string
+	// port is empty for the default port, * for any port
+	port string
+}
+
+// NewOriginMatcher parses the origins, invalid ones are logged and ignored.
+func NewOriginMatcher(origins []string) *OriginMatcher {
+	m := &OriginMatcher{}
+	for _, origin := range origins {
+		pattern, ok := parseOriginPattern(origin)
+		if !ok {
+			slog.Warn("cors: ignoring invalid origin", "origin", origin)
+			continue
+		}
+		m.patterns = append(m.patterns, pattern)
+	}
+	return m
+}
+
+func parseOriginPattern(origin string) (originPattern, bool) {
+	scheme, rest, ok := strings.Cut(strings.ToLower(strings.TrimSuffix(origin, "/")), "://")
+	if !ok || scheme == "" || rest == "" || strings.ContainsAny(rest, "/?#@") {
+		return originPattern{}, false
+	}
+	host, port, _ := strings.Cut(rest, ":")
+	labels := strings.Split(host, ".")
+	if labels[0] == "*" && len(labels) == 1 {
+		return originPattern{}, false
+	}
+	for i, label := range labels {
+		// only the leftmost label may be a wildcard
+		if label == "" || label == "*" && i > 0 || label != "*" && strings.Contains(label, "*") {
+			return originPattern{}, false
+		}
+	}
+	// anyone can register a subdomain of a public suffix
+	if labels[0] == "*" && isPublicSuffix(strings.Join(labels[1:], ".")) {
+		return originPattern{}, false
+	}
+	return originPattern{scheme: scheme, labels: labels, port: port}, true
+}
+
+// isPublicSuffix reports whether the domain is on the public suffix list, such
+// as com, co.uk or github.io. Unlisted single labels, such as localhost, fall
+// under the default rule of the list and are not.
+func isPublicSuffix(domain string) bool {
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+	return suffix == domain && (icann || strings.Contains(domain, "."))
+}
+
+// Match reports whether the origin is allowed, its signature fits
+// middleware.CORSConfig.AllowOriginFunc.
+func (m *OriginMatcher) Match(origin string) (bool, error) {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return false, nil
+	}
+	labels := strings.Split(u.Hostname(), ".")
+	for _, p := range m.patterns {
+		if p.scheme == u.Scheme && p.matchPort(u.Port()) && p.matchHost(labels) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p originPattern) matchPort(port string) bool {
+	return p.port == "*" || p.port == port
+}
+
+func (p originPattern) matchHost(labels []string) bool {
+	if p.labels[0] != "*" {
+		return slices.Equal(p.labels, labels)
+	}
+	suffix := p.labels[1:]
+	if len(labels) <= len(suffix) {
+		return false
+	}
+	return slices.Equal(suffix, labels[len(labels)-len(suffix):])
+}
@@ -0,0 +1,135 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestParseOriginPattern(t *testing.T) {
+	tests := []struct {
+		origin string
+		valid  bool
+	}{
+		{origin: "https://app.example.com", valid: true},
+		{origin: "https://app.example.com/", valid: true},
+		{origin: "https://*.example.com", valid: true},
+		{origin: "https://*.example.co.uk", valid: true},
+		{origin: "http://localhost:*", valid: true},
+		{origin: "http://*.localhost:3000", valid: true},
+		{origin: "*", valid: false},
+		{origin: "https://*", valid: false},
+		{origin: "app.example.com", valid: false},
+		{origin: "https://app.example.com/path", valid: false},
+		{origin: "https://user@app.example.com", valid: false},
+		{origin: "https://app.*.example.com", valid: false},
+		{origin: "https://a*.example.com", valid: false},
+		{origin: "https://app..example.com", valid: false},
+		// anyone can register a domain under a public suffix
+		{origin: "https://*.com", valid: false},
+		{origin: "https://*.co.uk", valid: false},
+		{origin: "https://*.github.io", valid: false},
+		{origin: "https://*.IO", valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if _, ok := parseOriginPattern(tt.origin); ok != tt.valid {
+				t.Fatalf("valid = %v, want %v", ok, tt.valid)
+			}
+		})
+	}
+}
+
+func TestOriginMatcher(t *testing.T) {
+	m := NewOriginMatcher([]string{"https://app.example.com", "https://*.example.org", "http://localhost:*", "https://*.com", "not an origin"})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://app.example.com", want: true},
+		{origin: "https://APP.example.com", want: true},
+		{origin: "http://app.example.com", want: false},
+		{origin: "https://app.example.com:8443", want: false},
+		{origin: "https://other.example.com", want: false},
+		{origin: "https://a.example.org", want: true},
+		{origin: "https://a.b.example.org", want: true},
+		// the wildcard needs a subdomain
+		{origin: "https://example.org", want: false},
+		{origin: "https://evilexample.org", want: false},
+		{origin: "https://example.org.evil.com", want: false},
+		{origin: "http://localhost", want: true},
+		{origin: "http://localhost:5173", want: true},
+		{origin: "https://localhost:5173", want: false},
+		// the ignored public suffix wildcard matches nothing
+		{origin: "https://evil.com", want: false},
+		{origin: "https://app.example.com/path", want: false},
+		{origin: "https://user@app.example.com", want: false},
+		{origin: "null", want: false},
+		{origin: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			got, err := m.Match(tt.origin)
+			if err != nil || got != tt.want {
+				t.Fatalf("match = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name            string
+		cfg             CORSConfig
+		origin          string
+		wantOrigin      string
+		wantCredentials bool
+	}{
+		{
+			name:            "allowed origin",
+			cfg:             CORSConfig{AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true},
+			origin:          "https://app.example.com",
+			wantOrigin:      "https://app.example.com",
+			wantCredentials: true,
+		},
+		{
+			name:   "other origin",
+			cfg:    CORSConfig{AllowOrigins: []string{"https://*.example.com"}, AllowCredentials: true},
+			origin: "https://evil.com",
+		},
+		{
+			// browsers reject credentials with a wildcard origin
+			name:       "any origin",
+			cfg:        CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true},
+			origin:     "https://evil.com",
+			wantOrigin: "*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(CORS(tt.cfg))
+			e.GET("/items", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+
+			req := httptest.NewRequest(http.MethodOptions, "/items", nil)
+			req.Header.Set(echo.HeaderOrigin, tt.origin)
+			req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodGet)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			header := rec.Header()
+			if got := header.Get(echo.HeaderAccessControlAllowOrigin); got != tt.wantOrigin {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := header.Get(echo.HeaderAccessControlAllowCredentials) == "true"; got != tt.wantCredentials {
+				t.Fatalf("Access-Control-Allow-Credentials = %v, want %v", got, tt.wantCredentials)
+			}
+			if tt.wantOrigin != "" && header.Get(echo.HeaderAccessControlAllowHeaders) == "" {
+				t.Fatalf("headers = %v, want the default allowed headers", header)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package httpx
+
+import (
+	"api-core/pkg/auth"
+	"api-core/pkg/errorx"
+	"api-core/pkg/requestid"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20
+)
+
+// idempotencyVolatileHeaders describe the original request rather than the
+// resource, they are not replayed.
+var idempotencyVolatileHeaders = []string{
+	echo.HeaderXRequestID, requestid.HeaderTraceresponse, echo.HeaderRetryAfter, echo.HeaderSetCookie,
+	HeaderRateLimitLimit, HeaderRateLimitRemaining, HeaderRateLimitReset, HeaderRateLimitPolicy,
+	// CORS depends on the Origin of the request, the retry may come from
+	// another one
+	echo.HeaderAccessControlAllowOrigin, echo.HeaderAccessControlAllowCredentials,
+	echo.HeaderAccessControlAllowHeaders, echo.HeaderAccessControlAllowMethods,
+	echo.HeaderAccessControlExposeHeaders, echo.HeaderVary,
+}
+
+// ErrIdempotencyLockLost is returned by Complete and Release when the pending
+// record is no longer held by the owner, e.g. the lock expired and another
+// request reserved the key.
+var ErrIdempotencyLockLost = errors.New("idempotency lock lost")
+
+// IdempotencyRecord is the state of a key: reserved while the first request
+// runs, then the captured response.
+type IdempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Completed   bool        `json:"completed"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	// Owner is the token of the request holding a pending record.
+	Owner string `json:"owner,omitempty"`
+}
+
+// IdempotencyStore keeps the records of idempotency keys.
+type IdempotencyStore interface {
+	// Reserve stores a pending record under a new owner token unless the key
+	// exists, in which case the existing record is returned.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (owner string, record *IdempotencyRecord, err error)
+	// Complete replaces the pending record of owner with the response.
+	Complete(ctx context.Context, key, owner string, record IdempotencyRecord, ttl time.Duration) error
+	// Release deletes the pending record of owner so the request can be
+	// retried.
+	Release(ctx context.Context, key, owner string) error
+}
+
+// ownedScript runs a command on the key only while it holds the pending
+// record of the owner. KEYS: record, ARGV: owner, then SET and its record and
+// ttl in ms, or DEL. It returns 1 when the command ran.
+var ownedScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return 0
+end
+local ok, record = pcall(cjson.decode, current)
+if not ok or record.completed or record.owner ~= ARGV[1] then
+	return 0
+end
+if ARGV[2] == 'SET' then
+	redis.call('SET', KEYS[1], ARGV[3], 'PX', ARGV[4])
+else
+	redis.call('DEL', KEYS[1])
+end
+return 1
+`)
+
+type redisIdempotencyStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedisIdempotencyStore keeps the records in Redis. A pending record is
+// only completed or released by the request that reserved it.
+func NewRedisIdempotencyStore(client redis.Cmdable) IdempotencyStore {
+	return &redisIdempotencyStore{client: client, prefix: "idempotency:"}
+}
+
+func (s *redisIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (string, *IdempotencyRecord, error) {
+	owner, err := newIdempotencyOwner()
+	if err != nil {
+		return "", nil, err
+	}
+	pending, err := json.Marshal(IdempotencyRecord{Fingerprint: fingerprint, Owner: owner})
+	if err != nil {
+		return "", nil, err
+	}
+
+	reserved, err := s.client.SetNX(ctx, s.prefix+key, pending, ttl).Result()
+	if err != nil {
+		return "", nil, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	if reserved {
+		return owner, nil, nil
+	}
+
+	raw, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// expired in between, the caller may retry
+		return "", nil, errorx.Wrap(errors.New("idempotency key expired, retry the request"), errorx.IdempotencyInProgress)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("read idempotency key: %w", err)
+	}
+
+	var record IdempotencyRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return "", nil, fmt.Errorf("decode idempotency record: %w", err)
+	}
+	return "", &record, nil
+}
+
+func (s *redisIdempotencyStore) Complete(ctx context.Context, key, owner string, record IdempotencyRecord, ttl time.Duration) error {
+	record.Owner = ""
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.runOwned(ctx, key, owner, "SET", raw, ttl.Milliseconds())
+}
+
+func (s *redisIdempotencyStore) Release(ctx context.Context, key, owner string) error {
+	return s.runOwned(ctx, key, owner, "DEL")
+}
+
+func (s *redisIdempotencyStore) runOwned(ctx context.Context, key, owner string, args ...any) error {
+	ran, err := ownedScript.Run(ctx, s.client, []string{s.prefix + key}, append([]any{owner}, args...)...).Int()
+	if err != nil {
+		return fmt.Errorf("idempotency script: %w", err)
+	}
+	if ran == 0 {
+		return ErrIdempotencyLockLost
+	}
+	return nil
+}
+
+func newIdempotencyOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("idempotency owner: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// IdempotencyConfig configures the Idempotency middleware.
+type IdempotencyConfig struct {
+	// TTL is how long responses are replayed, 24h by default.
+	TTL time.Duration
+	// LockTTL bounds how long a key stays reserved by a request that never
+	// completes, 1m by default.
+	LockTTL time.Duration
+	// Methods default to POST and PATCH.
+	Methods []string
+	// Required rejects unsafe requests without a key.
+	Required bool
+}
+
+// Idempotency replays the stored response of requests retried with the same
+// Idempotency-Key. Keys are scoped to the token subject, so it must run after
+// Authn. A key reused with another method, path or body is rejected with a
+// 422, a retry while the first request still runs with a 409. Server errors
+// are not stored, the request can be retried.
+func Idempotency(store IdempotencyStore, cfg IdempotencyConfig) echo.MiddlewareFunc {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = time.Minute
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if !slices.Contains(cfg.Methods, req.Method) {
+				return next(c)
+			}
+
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				if cfg.Required {
+					return Abort(c, errorx.Wrap(errors.New("missing Idempotency-Key header"), errorx.Invalid))
+				}
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return Abort(c, errorx.Wrap(errors.New("invalid Idempotency-Key header"), errorx.Invalid))
+			}
+
+			fingerprint, err := requestFingerprint(req)
+			if err != nil {
+				return Abort(c, err)
+			}
+
+			ctx := req.Context()
+			storeKey := idempotencyScope(ctx) + ":" + key
+			owner, record, err := store.Reserve(ctx, storeKey, fingerprint, cfg.LockTTL)
+			if err != nil {
+				return RestAbort(c, nil, err)
+			}
+			if record != nil {
+				return replayIdempotent(c, record, fingerprint)
+			}
+
+			writer := &capturingWriter{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = writer
+			if err := next(c); err != nil {
+				// render now so the error response is captured
+				c.Error(err)
+			}
+
+			res := c.Response()
+			// the request outlives a client that went away
+			ctx = context.WithoutCancel(ctx)
+			if res.Status >= http.StatusInternalServerError || !res.Committed {
+				if err := store.Release(ctx, storeKey, owner); err != nil {
+					slog.ErrorContext(ctx, "idempotency: release key", "error", err)
+				}
+				return nil
+			}
+
+			header := res.Header().Clone()
+			for _, name := range idempotencyVolatileHeaders {
+				header.Del(name)
+			}
+			err = store.Complete(ctx, storeKey, owner, IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Status:      res.Status,
+				Header:      header,
+				Body:        writer.body.Bytes(),
+			}, cfg.TTL)
+			if err != nil {
+				slog.ErrorContext(ctx, "idempotency: store response", "error", err)
+			}
+			return nil
+		}
+	}
+}
+
+func replayIdempotent(c echo.Context, record *IdempotencyRecord, fingerprint string) error {
+	if record.Fingerprint != fingerprint {
+		return Abort(c, errorx.Wrap(errors.New("idempotency key already used with a different request"), errorx.IdempotencyMismatch))
+	}
+	if !record.Completed {
+		return Abort(c, errorx.Wrap(errors.New("a request with this idempotency key is in progress"), errorx.IdempotencyInProgress))
+	}
+
+	header := c.Response().Header()
+	for name, values := range record.Header {
+		header[name] = values
+	}
+	header.Set(HeaderIdempotentReplayed, "true")
+	c.Response().WriteHeader(record.Status)
+	_, err := c.Response().Write(record.Body)
+	return err
+}
+
+// requestFingerprint hashes the method, path, query and body, the body is
+// restored for the handler.
+func requestFingerprint(req *http.Request) (string, error) {
+	var raw []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		raw, err = io.ReadAll(io.LimitReader(req.Body, maxIdempotentBodySize+1))
+		if err != nil {
+			return "", errorx.Wrap(err, errorx.Invalid)
+		}
+		if len(raw) > maxIdempotentBodySize {
+			return "", errorx.Wrap(errors.New("request body too large for an idempotent request"), errorx.Invalid)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(raw))
+	}
+
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "?" + req.URL.RawQuery + "\n"))
+	h.Write(raw)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// idempotencyScope keeps keys of different users apart.
+func idempotencyScope(ctx context.Context) string {
+	if subject := auth.ResolveSubject(ctx); subject != "" {
+		return "user:" + strings.ReplaceAll(subject, ":", "_")
+	}
+	return "anonymous"
+}
+
+// capturingWriter copies the response body while it is written.
+type capturingWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
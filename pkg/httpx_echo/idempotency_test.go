@@ -0,0 +1,189 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestIdempotencyStore(t *testing.T) (IdempotencyStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	return NewRedisIdempotencyStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})), mr
+}
+
+func TestRedisIdempotencyStoreOwner(t *testing.T) {
+	ctx := context.Background()
+	store, mr := newTestIdempotencyStore(t)
+
+	owner, record, err := store.Reserve(ctx, "k", "fp", time.Minute)
+	if err != nil || owner == "" || record != nil {
+		t.Fatalf("reserve = %q, %v, %v, want a new owner", owner, record, err)
+	}
+
+	other, record, err := store.Reserve(ctx, "k", "fp", time.Minute)
+	if err != nil || other != "" || record == nil || record.Completed {
+		t.Fatalf("reserve = %q, %+v, %v, want the pending record", other, record, err)
+	}
+
+	if err := store.Complete(ctx, "k", "intruder", IdempotencyRecord{Fingerprint: "fp", Completed: true}, time.Hour); !errors.Is(err, ErrIdempotencyLockLost) {
+		t.Fatalf("complete by another owner = %v, want %v", err, ErrIdempotencyLockLost)
+	}
+	if err := store.Release(ctx, "k", "intruder"); !errors.Is(err, ErrIdempotencyLockLost) {
+		t.Fatalf("release by another owner = %v, want %v", err, ErrIdempotencyLockLost)
+	}
+
+	// the lock expires and a retry takes the key over
+	mr.FastForward(2 * time.Minute)
+	retry, _, err := store.Reserve(ctx, "k", "fp", time.Minute)
+	if err != nil || retry == "" || retry == owner {
+		t.Fatalf("reserve after expiry = %q, %v, want a new owner", retry, err)
+	}
+
+	// the slow first request can neither overwrite nor release it
+	if err := store.Complete(ctx, "k", owner, IdempotencyRecord{Fingerprint: "fp", Completed: true, Status: 201}, time.Hour); !errors.Is(err, ErrIdempotencyLockLost) {
+		t.Fatalf("complete by the expired owner = %v, want %v", err, ErrIdempotencyLockLost)
+	}
+	if err := store.Release(ctx, "k", owner); !errors.Is(err, ErrIdempotencyLockLost) {
+		t.Fatalf("release by the expired owner = %v, want %v", err, ErrIdempotencyLockLost)
+	}
+
+	if err := store.Complete(ctx, "k", retry, IdempotencyRecord{Fingerprint: "fp", Completed: true, Status: 201}, time.Hour); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if ttl := mr.TTL("idempotency:k"); ttl != time.Hour {
+		t.Fatalf("ttl = %s, want the response ttl", ttl)
+	}
+	_, record, err = store.Reserve(ctx, "k", "fp", time.Minute)
+	if err != nil || record == nil || !record.Completed || record.Status != 201 || record.Owner != "" {
+		t.Fatalf("reserve = %+v, %v, want the completed record", record, err)
+	}
+
+	// a completed record is never released
+	if err := store.Release(ctx, "k", retry); !errors.Is(err, ErrIdempotencyLockLost) {
+		t.Fatalf("release of a completed record = %v, want %v", err, ErrIdempotencyLockLost)
+	}
+}
+
+// idempotentServer counts the handler calls, the handler answers with the
+// status of the request's X-Status header.
+type idempotentServer struct {
+	e     *echo.Echo
+	calls int
+}
+
+func newIdempotentServer(t *testing.T, store IdempotencyStore, block <-chan struct{}) *idempotentServer {
+	t.Helper()
+
+	s := &idempotentServer{e: echo.New()}
+	s.e.POST("/items", func(c echo.Context) error {
+		s.calls++
+		if block != nil {
+			<-block
+		}
+		status := http.StatusCreated
+		if c.Request().Header.Get("X-Status") == "500" {
+			status = http.StatusInternalServerError
+		}
+		header := c.Response().Header()
+		header.Set(echo.HeaderAccessControlAllowOrigin, c.Request().Header.Get(echo.HeaderOrigin))
+		header.Set(echo.HeaderVary, echo.HeaderOrigin)
+		header.Set(echo.HeaderLocation, "/items/1")
+		return c.JSON(status, map[string]int{"call": s.calls})
+	}, Idempotency(store, IdempotencyConfig{}))
+	return s
+}
+
+func (s *idempotentServer) post(key, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderIdempotencyKey, key)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	store, _ := newTestIdempotencyStore(t)
+	s := newIdempotentServer(t, store, nil)
+
+	first := s.post("k1", `{"name":"a"}`, echo.HeaderOrigin, "https://app.example.com")
+	if first.Code != http.StatusCreated || first.Header().Get(HeaderIdempotentReplayed) != "" {
+		t.Fatalf("status = %d, headers = %v", first.Code, first.Header())
+	}
+
+	replay := s.post("k1", `{"name":"a"}`, echo.HeaderOrigin, "https://other.example.com")
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() || s.calls != 1 {
+		t.Fatalf("replay = %d %s after %d calls, want the first response", replay.Code, replay.Body, s.calls)
+	}
+	if replay.Header().Get(HeaderIdempotentReplayed) != "true" || replay.Header().Get(echo.HeaderLocation) != "/items/1" {
+		t.Fatalf("headers = %v, want the stored headers marked as replayed", replay.Header())
+	}
+	// the CORS headers of the first origin are not replayed to another one
+	if got := replay.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want it not replayed", got)
+	}
+	if got := replay.Header().Get(echo.HeaderVary); got != "" {
+		t.Fatalf("Vary = %q, want it not replayed", got)
+	}
+
+	if rec := s.post("k1", `{"name":"b"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422 for another body", rec.Code)
+	}
+}
+
+func TestIdempotencyServerErrorsAreReleased(t *testing.T) {
+	store, mr := newTestIdempotencyStore(t)
+	s := newIdempotentServer(t, store, nil)
+
+	if rec := s.post("k1", `{}`, "X-Status", "500"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Fatalf("keys = %v, want the reservation released", keys)
+	}
+	if rec := s.post("k1", `{}`); rec.Code != http.StatusCreated || s.calls != 2 {
+		t.Fatalf("status = %d after %d calls, want the retry to run", rec.Code, s.calls)
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store, mr := newTestIdempotencyStore(t)
+	block := make(chan struct{})
+	s := newIdempotentServer(t, store, block)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- s.post("k1", `{}`) }()
+
+	// wait for the first request to reserve the key
+	deadline := time.Now().Add(time.Second)
+	for {
+		if mr.Exists("idempotency:anonymous:k1") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the first request never reserved the key")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if rec := s.post("k1", `{}`); rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409 while the first request runs", rec.Code)
+	}
+	close(block)
+	if rec := <-done; rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want the first request to complete", rec.Code)
+	}
+}
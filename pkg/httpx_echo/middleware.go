@@ -1,28 +1,112 @@
 package httpx
 
 import (
+	"api-core/pkg/audit"
 	"api-core/pkg/auth"
 	"api-core/pkg/errorx"
 	"api-core/pkg/jwtx"
-	"encoding/json"
+	"api-core/pkg/logx"
+	"api-core/pkg/requestid"
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Guard interface {
 	AuthenticateJWT(tokenStr string) (*jwt.Token, error)
 }
 
-func Authn(guard Guard) echo.MiddlewareFunc {
+// Revocations reports whether a token id was revoked, e.g. by a logout.
+type Revocations interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// RequestID accepts or generates the request and trace ids, stores them in the
+// request context and echoes them in the X-Request-ID and traceresponse
+// headers. Behind a tracing middleware the ids follow the server span.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			ids := requestid.FromHeaders(c.Request().Header)
+			if span := trace.SpanContextFromContext(ctx); span.IsValid() && !span.IsRemote() {
+				ids = ids.WithSpan(span.TraceID().String(), span.SpanID().String(), span.IsSampled())
+			}
+			c.SetRequest(c.Request().WithContext(requestid.NewContext(ctx, ids)))
+
+			header := c.Response().Header()
+			header.Set(requestid.HeaderRequestID, ids.RequestID)
+			header.Set(requestid.HeaderTraceresponse, ids.Traceresponse())
+			return next(c)
+		}
+	}
+}
+
+// RequestLogger writes one access log record per request, the request id is
+// added by the logger from the context.
+func RequestLogger(logger *slog.Logger) echo.MiddlewareFunc {
+	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		LogMethod:   true,
+		LogURI:      true,
+		LogStatus:   true,
+		LogLatency:  true,
+		LogRemoteIP: true,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			level := slog.LevelInfo
+			if v.Status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(c.Request().Context(), level, "request",
+				slog.String("method", v.Method),
+				slog.String("uri", v.URI),
+				slog.Int("status", v.Status),
+				slog.Duration("latency", v.Latency),
+				slog.String("remote_ip", v.RemoteIP),
+			)
+			return nil
+		},
+	})
+}
+
+// AuditRequest stores the client address and user agent for audit events.
+func AuditRequest() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := audit.WithRequest(c.Request().Context(), c.RealIP(), c.Request().UserAgent())
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// Authn verifies the bearer token and rejects revoked ones, failures are
+// recorded by the auditor. Nil revocations skips the denylist check.
+func Authn(guard Guard, revocations Revocations, auditor audit.Auditor) echo.MiddlewareFunc {
+	if auditor == nil {
+		auditor = audit.Nop()
+	}
+
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			fail := func(err error) error {
+				auditor.Record(c.Request().Context(), audit.Event{
+					Action:   audit.ActionAuthenticate,
+					Outcome:  audit.OutcomeFailure,
+					Resource: c.Request().Method + " " + c.Path(),
+					Metadata: map[string]any{"reason": err.Error()},
+				})
+				return Abort(c, errorx.Wrap(err, errorx.Authn), -1)
+			}
+
 			header := c.Request().Header.Get("Authorization")
 			if header == "" {
 				return Abort(c, errorx.Wrap(errors.New("missing access token"), errorx.Authn), -1)
@@ -30,81 +114,92 @@ func Authn(guard Guard) echo.MiddlewareFunc {
 
 			parts := strings.Split(header, "Bearer")
 			if len(parts) != 2 {
-				return Abort(c, errorx.Wrap(errors.New("invalid access token"), errorx.Authn), -1)
+				return fail(errors.New("invalid access token"))
 			}
 
 			token := strings.TrimSpace(parts[1])
 			if len(token) == 0 {
-				return Abort(c, errorx.Wrap(errors.New("invalid access token"), errorx.Authn), -1)
+				return fail(errors.New("invalid access token"))
 			}
 
-			claims, err := guard.AuthenticateJWT(token)
+			claims, jwtClaims, err := jwtx.Verify(guard, token)
+			if errors.Is(err, jwtx.ErrInvalidClaims) {
+				return fail(fmt.Errorf("invalid access token: %v", err))
+			}
 			if err != nil {
 				// although it's a client error, we don't want to detailed information
 				//nolint:errcheck
-				return Abort(c, errorx.Wrap(err, errorx.Authn), -1)
+				return fail(err)
 			}
 
 			ctx := c.Request().Context()
-			ctx = auth.WithAuthJWT(ctx, claims.Raw)
-
-			jwtClaims, err := jwtx.NewJWTClaims(claims.Claims)
-			if err != nil {
-				return Abort(c, errorx.Wrap(fmt.Errorf("invalid access token: %v", err), errorx.Authn), -1)
+			if revocations != nil {
+				revoked, err := revocations.IsRevoked(ctx, jwtClaims.ID)
+				if err != nil {
+					return Abort(c, errorx.Wrap(err, errorx.Service), -1)
+				}
+				if revoked {
+					return fail(errors.New("token revoked"))
+				}
 			}
 
+			ctx = auth.WithAuthJWT(ctx, claims.Raw)
 			ctx = auth.WithAuthClaims(ctx, jwtClaims)
+			attrs := []slog.Attr{slog.String("user_id", jwtClaims.Subject)}
+			if jwtClaims.Tenant != "" {
+				attrs = append(attrs, slog.String("tenant", jwtClaims.Tenant))
+			}
+			ctx = logx.WithAttrs(ctx, attrs...)
 			c.SetRequest(c.Request().WithContext(ctx))
 			return next(c)
 		}
 	}
 }
 
-type CaptchaPayload struct {
-	Captcha string `json:"captcha"`
+type Authorizer interface {
+	Allow(ctx context.Context, sub string, resource string, action auth.AuthzAction, ladonCtx map[string]any) error
 }
 
-type CaptchaVerifyResponse struct {
-	Success     bool      `json:"success"`
-	Score       float64   `json:"score"`
-	Action      string    `json:"action"`
-	ChallengeTS time.Time `json:"challenge_ts"`
-	Hostname    string    `json:"hostname"`
-	ErrorCodes  []string  `json:"error-codes"`
-}
-
-func CaptchaValid(secret string, score float64, action string) echo.MiddlewareFunc {
+// Authz checks the authenticated email against the guard policies, it must run after Authn.
+func Authz(guard Authorizer, resource string, action auth.AuthzAction) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			if secret == "" {
-				return Abort(c, errorx.Wrap(errors.New("missing captcha secret"), errorx.Service))
+			email := auth.ResolveEmail(c.Request().Context())
+			if email == "" {
+				return Abort(c, errorx.Wrap(auth.ErrInvalidSession, errorx.Authn), -1)
 			}
 
-			var payload CaptchaPayload
-			if err := c.Bind(&payload); err != nil {
-				return Abort(c, errorx.Wrap(err, errorx.Invalid))
+			authzCtx := map[string]any{
+				auth.RemoteIPContextKey: c.RealIP(),
+				auth.SubjectContextKey:  auth.ResolveSubject(c.Request().Context()),
 			}
-
-			resp, err := http.PostForm("https://www.google.com/recaptcha/api/siteverify", url.Values{
-				"secret":   {secret},
-				"response": {payload.Captcha},
-			})
-			if err != nil {
-				return Abort(c, errorx.Wrap(err, errorx.Service))
+			if err := guard.Allow(c.Request().Context(), email, resource, action, authzCtx); err != nil {
+				return Abort(c, errorx.Wrap(err, errorx.Authz), -1)
 			}
-			defer resp.Body.Close()
 
-			var body CaptchaVerifyResponse
-			if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
-				return Abort(c, errorx.Wrap(err, errorx.Service))
-			}
+			return next(c)
+		}
+	}
+}
 
-			if action != "" && body.Action != action {
-				return Abort(c, errorx.Wrap(errors.New("captcha failed"), errorx.Authz))
+// RequireFreshAuth rejects tokens whose auth_time is older than maxAge, it must
+// run after Authn. Clients get a reauthentication-required error and the
+// RFC 9470 challenge telling them to sign in again and retry.
+func RequireFreshAuth(maxAge time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims := auth.ResolveClaims(c.Request().Context())
+			if claims == nil {
+				return Abort(c, errorx.Wrap(auth.ErrInvalidSession, errorx.Authn), -1)
 			}
 
-			if !body.Success || body.Score < score {
-				return Abort(c, errorx.Wrap(errors.New("captcha failed"), errorx.Authz))
+			// tokens without auth_time (e.g. device grants) never count as fresh
+			if claims.AuthTime == nil || time.Since(claims.AuthTime.Time) > maxAge {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(
+					`Bearer error="insufficient_user_authentication", error_description="a more recent authentication is required", max_age=%d`,
+					int(maxAge.Seconds()),
+				))
+				return Abort(c, errorx.Wrap(errors.New("re-authentication required"), errorx.Reauthentication), -1)
 			}
 
 			return next(c)
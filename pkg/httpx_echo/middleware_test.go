@@ -0,0 +1,175 @@
+package httpx
+
+import (
+	"api-core/pkg/jwtx"
+	"api-core/pkg/requestid"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+// stubRevocations denylists the token ids of the map, a nil map fails.
+type stubRevocations map[string]bool
+
+func (r stubRevocations) IsRevoked(_ context.Context, jti string) (bool, error) {
+	if r == nil {
+		return false, errors.New("redis unavailable")
+	}
+	return r[jti], nil
+}
+
+func newTestIssuer(t *testing.T) *jwtx.HMACIssuer {
+	t.Helper()
+
+	issuer, err := jwtx.NewHMACIssuer("test-secret", "api-core", time.Hour)
+	if err != nil {
+		t.Fatalf("new issuer: %v", err)
+	}
+	return issuer
+}
+
+// serveAuthn runs the request through the middlewares, the handler answers 204.
+func serveAuthn(token string, mw ...echo.MiddlewareFunc) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.GET("/me", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, mw...)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthnRevocation(t *testing.T) {
+	issuer := newTestIssuer(t)
+	token, err := issuer.Issue("42", "alice@example.com")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	_, claims, err := jwtx.Verify(issuer, token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		revocations Revocations
+		token       string
+		want        int
+	}{
+		{name: "valid token", revocations: stubRevocations{}, token: token, want: http.StatusNoContent},
+		{name: "no denylist", revocations: nil, token: token, want: http.StatusNoContent},
+		{name: "revoked token", revocations: stubRevocations{claims.ID: true}, token: token, want: http.StatusUnauthorized},
+		{name: "missing token", revocations: stubRevocations{}, want: http.StatusUnauthorized},
+		{name: "invalid token", revocations: stubRevocations{}, token: token + "x", want: http.StatusUnauthorized},
+		{name: "denylist unavailable", revocations: stubRevocations(nil), token: token, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveAuthn(tt.token, Authn(issuer, tt.revocations, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRequireFreshAuth(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	tests := []struct {
+		name     string
+		authTime *jwt.NumericDate
+		want     int
+	}{
+		{name: "fresh", authTime: jwt.NewNumericDate(time.Now().Add(-time.Minute)), want: http.StatusNoContent},
+		{name: "stale", authTime: jwt.NewNumericDate(time.Now().Add(-time.Hour)), want: http.StatusUnauthorized},
+		{name: "no auth_time", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := issuer.IssueClaims(jwtx.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "42"},
+				AuthTime:         tt.authTime,
+			})
+			if err != nil {
+				t.Fatalf("issue: %v", err)
+			}
+
+			rec := serveAuthn(token, Authn(issuer, nil, nil), RequireFreshAuth(5*time.Minute))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			challenge := rec.Header().Get(echo.HeaderWWWAuthenticate)
+			if tt.want == http.StatusUnauthorized {
+				if !strings.Contains(challenge, `error="insufficient_user_authentication"`) || !strings.Contains(challenge, "max_age=300") {
+					t.Fatalf("challenge = %q, want the RFC 9470 challenge", challenge)
+				}
+				if !strings.Contains(rec.Body.String(), "reauthentication-required") {
+					t.Fatalf("body = %s, want the reauthentication-required code", rec.Body)
+				}
+			} else if challenge != "" {
+				t.Fatalf("challenge = %q, want none", challenge)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	e := echo.New()
+	e.Use(RequestID())
+	var got requestid.IDs
+	e.GET("/", func(c echo.Context) error {
+		got, _ = requestid.FromContext(c.Request().Context())
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		header        string
+		value         string
+		wantRequestID string
+	}{
+		{name: "client id", header: requestid.HeaderRequestID, value: "req-1", wantRequestID: "req-1"},
+		{name: "traceparent", header: requestid.HeaderTraceparent, value: "00-" + traceID + "-00f067aa0ba902b7-01", wantRequestID: traceID},
+		{name: "invalid id replaced", header: requestid.HeaderRequestID, value: "has space"},
+		{name: "generated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			want := tt.wantRequestID
+			if want == "" {
+				want = got.TraceID
+			}
+			if got.RequestID != want || len(got.TraceID) != 32 {
+				t.Fatalf("ids = %+v, want request id %q", got, want)
+			}
+			if id := rec.Header().Get(requestid.HeaderRequestID); id != got.RequestID {
+				t.Fatalf("X-Request-ID = %q, want %q", id, got.RequestID)
+			}
+			traceresponse := rec.Header().Get(requestid.HeaderTraceresponse)
+			if traceID, _, ok := requestid.ParseTraceparent(traceresponse); !ok || traceID != got.TraceID {
+				t.Fatalf("traceresponse = %q, want the trace %s", traceresponse, got.TraceID)
+			}
+		})
+	}
+}
@@ -0,0 +1,489 @@
+package httpx
+
+import (
+	"api-core/pkg/auth"
+	"api-core/pkg/errorx"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// RateLimitAlgorithm selects how requests are counted.
+type RateLimitAlgorithm string
+
+const (
+	// SlidingWindow weights the previous fixed window by the part of it still
+	// covered by the sliding window.
+	SlidingWindow RateLimitAlgorithm = "sliding_window"
+	// TokenBucket refills Requests tokens per Window and allows bursts up to
+	// Requests.
+	TokenBucket RateLimitAlgorithm = "token_bucket"
+)
+
+// Rate allows Requests per Window, a zero rate disables the limit. Windows are
+// counted in milliseconds, shorter ones are rejected.
+type Rate struct {
+	Requests int
+	Window   time.Duration
+}
+
+// MinRateWindow is the shortest window the limiters can count.
+const MinRateWindow = time.Millisecond
+
+func (r Rate) enabled() bool {
+	return r.Requests > 0 && r.Window > 0
+}
+
+func (r Rate) validate() error {
+	if r.Requests < 1 || r.Window < MinRateWindow {
+		return fmt.Errorf("rate limit: invalid rate %d per %s, want at least 1 per %s", r.Requests, r.Window, MinRateWindow)
+	}
+	return nil
+}
+
+// RateLimitResult is the outcome of a single request against a limit.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the quota is fully available again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when
+	// Allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimiter counts a request for key and reports whether it is allowed.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, rate Rate) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc returns the key a request is counted under.
+type RateLimitKeyFunc func(c echo.Context) string
+
+// RateLimitByIP counts requests per client address.
+func RateLimitByIP(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// RateLimitByUser counts requests per token subject, anonymous requests per
+// client address. It must run after Authn.
+func RateLimitByUser(c echo.Context) string {
+	if subject := auth.ResolveSubject(c.Request().Context()); subject != "" {
+		return "user:" + subject
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimitByRoute counts requests per route, combine it with another key to
+// limit a client per endpoint.
+func RateLimitByRoute(c echo.Context) string {
+	return "route:" + c.Request().Method + " " + c.Path()
+}
+
+// RateLimitByAPIKey counts requests per API key read from header, requests
+// without one are counted per client address. Keys are hashed so they never
+// reach Redis in clear.
+func RateLimitByAPIKey(header string) RateLimitKeyFunc {
+	return func(c echo.Context) string {
+		key := c.Request().Header.Get(header)
+		if key == "" {
+			return RateLimitByIP(c)
+		}
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// RateLimitKeys combines several keys, e.g. RateLimitKeys(RateLimitByIP, RateLimitByRoute).
+func RateLimitKeys(funcs ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(c echo.Context) string {
+		parts := make([]string, 0, len(funcs))
+		for _, f := range funcs {
+			parts = append(parts, f(c))
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// RateLimitConfig configures one limit. Name separates the counters of limits
+// sharing a key, e.g. the api and auth limits of the same IP.
+type RateLimitConfig struct {
+	Name string
+	Rate Rate
+	// Key defaults to RateLimitByIP.
+	Key RateLimitKeyFunc
+}
+
+// RateLimit rejects requests over the rate with a 429 rate-limiting error.
+// Responses carry the RateLimit-* headers, rejections a Retry-After header. A
+// failing limiter lets the request through. It panics on a window under
+// MinRateWindow, config.Load rejects them first.
+func RateLimit(limiter RateLimiter, cfg RateLimitConfig) echo.MiddlewareFunc {
+	if cfg.Key == nil {
+		cfg.Key = RateLimitByIP
+	}
+	if cfg.Rate.enabled() {
+		if err := cfg.Rate.validate(); err != nil {
+			panic(err)
+		}
+	}
+	policy := fmt.Sprintf("%d;w=%d", cfg.Rate.Requests, int(cfg.Rate.Window.Seconds()))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !cfg.Rate.enabled() {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			key := cfg.Name + ":" + cfg.Key(c)
+
+			result, err := limiter.Allow(ctx, key, cfg.Rate)
+			if err != nil {
+				slog.ErrorContext(ctx, "rate limit: limiter failed", "limit", cfg.Name, "error", err)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+			header.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.Reset)))
+			header.Set(HeaderRateLimitPolicy, policy)
+
+			if !result.Allowed {
+				header.Set(echo.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				return Abort(c, errorx.Wrap(errors.New("too many requests"), errorx.RateLimiting))
+			}
+			return next(c)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// slidingWindowScript counts the request in the current fixed window and
+// weights the previous one. KEYS: current and previous window, ARGV: limit,
+// window and elapsed time in the current window in ms. It returns allowed,
+// remaining, retry after and reset in ms.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local weighted = math.floor(previous * (window - elapsed) / window)
+local reset = window - elapsed
+if previous > 0 then
+	reset = reset + window
+end
+
+if weighted + current >= limit then
+	local retry = window - elapsed
+	if current < limit and previous > 0 then
+		retry = retry - math.floor((limit - current) * window / previous) + 1
+	end
+	return {0, 0, math.max(retry, 1), reset}
+end
+
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {1, limit - weighted - current - 1, 0, reset}
+`)
+
+// tokenBucketScript takes one token from the bucket. KEYS: bucket, ARGV:
+// capacity, refill rate in tokens per ms and now in ms. It returns allowed,
+// remaining, retry after and reset in ms.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or capacity
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+// redisRateLimitTimeout bounds a script call, so a hanging Redis fails fast
+// and the fallback takes over instead of holding the request.
+const redisRateLimitTimeout = 100 * time.Millisecond
+
+type redisRateLimiter struct {
+	client    redis.Scripter
+	algorithm RateLimitAlgorithm
+	prefix    string
+	timeout   time.Duration
+	now       func() time.Time
+}
+
+// NewRedisRateLimiter shares the counters between instances, each request is
+// counted atomically by a Lua script.
+func NewRedisRateLimiter(client redis.Scripter, algorithm RateLimitAlgorithm) (RateLimiter, error) {
+	if err := validAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	return &redisRateLimiter{
+		client:    client,
+		algorithm: algorithm,
+		prefix:    "ratelimit:",
+		timeout:   redisRateLimitTimeout,
+		now:       time.Now,
+	}, nil
+}
+
+func (l *redisRateLimiter) Allow(ctx context.Context, key string, rate Rate) (RateLimitResult, error) {
+	if err := rate.validate(); err != nil {
+		return RateLimitResult{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	now := l.now().UnixMilli()
+	window := rate.Window.Milliseconds()
+
+	var (
+		values []int64
+		err    error
+	)
+	// the braces keep the keys of a limit in the same cluster slot
+	base := l.prefix + "{" + key + "}"
+	switch l.algorithm {
+	case TokenBucket:
+		refill := float64(rate.Requests) / float64(window)
+		values, err = tokenBucketScript.Run(ctx, l.client, []string{base},
+			rate.Requests, strconv.FormatFloat(refill, 'f', -1, 64), now).Int64Slice()
+	default:
+		index := now / window
+		values, err = slidingWindowScript.Run(ctx, l.client,
+			[]string{base + ":" + strconv.FormatInt(index, 10), base + ":" + strconv.FormatInt(index-1, 10)},
+			rate.Requests, window, now-index*window).Int64Slice()
+	}
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate limit script: %w", err)
+	}
+	if len(values) != 4 {
+		return RateLimitResult{}, errors.New("rate limit script: unexpected reply")
+	}
+
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      rate.Requests,
+		Remaining:  int(max(values[1], 0)),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+// memoryRateLimiter keeps the counters of this instance only, with the same
+// algorithms as the Redis limiter.
+type memoryRateLimiter struct {
+	algorithm RateLimitAlgorithm
+	now       func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryWindow struct {
+	index    int64
+	current  int
+	previous int
+	expires  time.Time
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	expires time.Time
+}
+
+const memorySweepInterval = time.Minute
+
+// NewMemoryRateLimiter counts requests in process, it is the fallback when
+// Redis is unavailable.
+func NewMemoryRateLimiter(algorithm RateLimitAlgorithm) (RateLimiter, error) {
+	if err := validAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	return &memoryRateLimiter{
+		algorithm: algorithm,
+		now:       time.Now,
+		windows:   map[string]*memoryWindow{},
+		buckets:   map[string]*memoryBucket{},
+	}, nil
+}
+
+func (l *memoryRateLimiter) Allow(_ context.Context, key string, rate Rate) (RateLimitResult, error) {
+	if err := rate.validate(); err != nil {
+		return RateLimitResult{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	if l.algorithm == TokenBucket {
+		return l.takeToken(now, key, rate), nil
+	}
+	return l.countInWindow(now, key, rate), nil
+}
+
+func (l *memoryRateLimiter) countInWindow(now time.Time, key string, rate Rate) RateLimitResult {
+	window := rate.Window.Milliseconds()
+	index := now.UnixMilli() / window
+	elapsed := now.UnixMilli() - index*window
+
+	w, ok := l.windows[key]
+	switch {
+	case !ok || w.index < index-1:
+		w = &memoryWindow{index: index}
+		l.windows[key] = w
+	case w.index == index-1:
+		w.index, w.previous, w.current = index, w.current, 0
+	}
+
+	result := RateLimitResult{Limit: rate.Requests, Reset: time.Duration(window-elapsed) * time.Millisecond}
+	if w.previous > 0 {
+		result.Reset += rate.Window
+	}
+
+	weighted := int(int64(w.previous) * (window - elapsed) / window)
+	if weighted+w.current >= rate.Requests {
+		retry := window - elapsed
+		if w.current < rate.Requests && w.previous > 0 {
+			retry -= int64(rate.Requests-w.current)*window/int64(w.previous) - 1
+		}
+		result.RetryAfter = time.Duration(max(retry, 1)) * time.Millisecond
+		return result
+	}
+
+	w.current++
+	w.expires = now.Add(2 * rate.Window)
+	result.Allowed = true
+	result.Remaining = rate.Requests - weighted - w.current
+	return result
+}
+
+func (l *memoryRateLimiter) takeToken(now time.Time, key string, rate Rate) RateLimitResult {
+	capacity := float64(rate.Requests)
+	refill := capacity / float64(rate.Window)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(capacity, b.tokens+float64(now.Sub(b.updated))*refill)
+	b.updated = now
+	b.expires = now.Add(rate.Window)
+
+	result := RateLimitResult{Limit: rate.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) / refill))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration(math.Ceil((capacity - b.tokens) / refill))
+	return result
+}
+
+// sweep drops expired counters, at most once per memorySweepInterval.
+func (l *memoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < memorySweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, w := range l.windows {
+		if now.After(w.expires) {
+			delete(l.windows, key)
+		}
+	}
+	for key, b := range l.buckets {
+		if now.After(b.expires) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type fallbackRateLimiter struct {
+	primary  RateLimiter
+	fallback RateLimiter
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// NewFallbackRateLimiter uses fallback when primary fails, and keeps using it
+// for cooldown before trying primary again.
+func NewFallbackRateLimiter(primary, fallback RateLimiter, cooldown time.Duration) RateLimiter {
+	return &fallbackRateLimiter{primary: primary, fallback: fallback, cooldown: cooldown, now: time.Now}
+}
+
+func (l *fallbackRateLimiter) Allow(ctx context.Context, key string, rate Rate) (RateLimitResult, error) {
+	l.mu.Lock()
+	degraded := l.now().Before(l.until)
+	l.mu.Unlock()
+
+	if !degraded {
+		result, err := l.primary.Allow(ctx, key, rate)
+		if err == nil {
+			return result, nil
+		}
+
+		slog.WarnContext(ctx, "rate limit: falling back to the in-process limiter", "error", err)
+		l.mu.Lock()
+		l.until = l.now().Add(l.cooldown)
+		l.mu.Unlock()
+	}
+	return l.fallback.Allow(ctx, key, rate)
+}
+
+func validAlgorithm(algorithm RateLimitAlgorithm) error {
+	switch algorithm {
+	case SlidingWindow, TokenBucket:
+		return nil
+	}
+	return fmt.Errorf("unknown rate limit algorithm %q", algorithm)
+}
@@ -0,0 +1,238 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// testClock is a manual clock for the now hooks of the limiters.
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time          { return c.t }
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestClock() *testClock {
+	// aligned on a minute, so windows start with the clock
+	return &testClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+// newTestLimiters returns the memory and Redis limiters of the algorithm on
+// the same clock.
+func newTestLimiters(t *testing.T, algorithm RateLimitAlgorithm, clock *testClock) map[string]RateLimiter {
+	t.Helper()
+
+	memory, err := NewMemoryRateLimiter(algorithm)
+	if err != nil {
+		t.Fatalf("new memory limiter: %v", err)
+	}
+	memory.(*memoryRateLimiter).now = clock.now
+
+	mr := miniredis.RunT(t)
+	rl, err := NewRedisRateLimiter(redis.NewClient(&redis.Options{Addr: mr.Addr()}), algorithm)
+	if err != nil {
+		t.Fatalf("new redis limiter: %v", err)
+	}
+	rl.(*redisRateLimiter).now = clock.now
+
+	return map[string]RateLimiter{"memory": memory, "redis": rl}
+}
+
+func allowN(t *testing.T, limiter RateLimiter, rate Rate, n int) (allowed int, last RateLimitResult) {
+	t.Helper()
+
+	for range n {
+		result, err := limiter.Allow(context.Background(), "ip:1.2.3.4", rate)
+		if err != nil {
+			t.Fatalf("allow: %v", err)
+		}
+		if result.Allowed {
+			allowed++
+		}
+		last = result
+	}
+	return allowed, last
+}
+
+func TestSlidingWindow(t *testing.T) {
+	rate := Rate{Requests: 10, Window: time.Minute}
+
+	for _, name := range []string{"memory", "redis"} {
+		t.Run(name, func(t *testing.T) {
+			clock := newTestClock()
+			limiter := newTestLimiters(t, SlidingWindow, clock)[name]
+
+			if allowed, last := allowN(t, limiter, rate, 12); allowed != 10 || last.Allowed || last.RetryAfter <= 0 {
+				t.Fatalf("allowed = %d, last = %+v, want 10 and a rejection with a retry", allowed, last)
+			}
+
+			// half of the previous window still counts: 5 of 10 used
+			clock.advance(90 * time.Second)
+			if allowed, _ := allowN(t, limiter, rate, 10); allowed != 5 {
+				t.Fatalf("allowed = %d after half a window, want 5", allowed)
+			}
+
+			clock.advance(2 * time.Minute)
+			if allowed, last := allowN(t, limiter, rate, 1); allowed != 1 || last.Remaining != 9 {
+				t.Fatalf("allowed = %d, remaining = %d after two windows, want a fresh quota", allowed, last.Remaining)
+			}
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	rate := Rate{Requests: 10, Window: 10 * time.Second}
+
+	for _, name := range []string{"memory", "redis"} {
+		t.Run(name, func(t *testing.T) {
+			clock := newTestClock()
+			limiter := newTestLimiters(t, TokenBucket, clock)[name]
+
+			allowed, last := allowN(t, limiter, rate, 11)
+			if allowed != 10 || last.Allowed {
+				t.Fatalf("allowed = %d, want a burst of 10", allowed)
+			}
+			if last.RetryAfter != time.Second {
+				t.Fatalf("retry after = %s, want a token per second", last.RetryAfter)
+			}
+
+			clock.advance(3 * time.Second)
+			if allowed, _ := allowN(t, limiter, rate, 5); allowed != 3 {
+				t.Fatalf("allowed = %d after 3s, want 3 refilled tokens", allowed)
+			}
+		})
+	}
+}
+
+func TestRateLimitersRejectSubMillisecondWindows(t *testing.T) {
+	clock := newTestClock()
+	for _, algorithm := range []RateLimitAlgorithm{SlidingWindow, TokenBucket} {
+		for name, limiter := range newTestLimiters(t, algorithm, clock) {
+			t.Run(string(algorithm)+"/"+name, func(t *testing.T) {
+				// a divide by zero before the windows were validated
+				_, err := limiter.Allow(context.Background(), "ip:1.2.3.4", Rate{Requests: 10, Window: 500 * time.Microsecond})
+				if err == nil {
+					t.Fatal("expected a sub-millisecond window to be rejected")
+				}
+			})
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected RateLimit to panic on a sub-millisecond window")
+		}
+	}()
+	RateLimit(nil, RateLimitConfig{Rate: Rate{Requests: 10, Window: time.Microsecond}})
+}
+
+// hangingScripter blocks every script until the context is done, like an
+// unresponsive Redis.
+type hangingScripter struct {
+	redis.Scripter
+}
+
+func (hangingScripter) EvalSha(ctx context.Context, _ string, _ []string, _ ...any) *redis.Cmd {
+	<-ctx.Done()
+	cmd := redis.NewCmd(ctx)
+	cmd.SetErr(ctx.Err())
+	return cmd
+}
+
+func TestRedisRateLimiterTimeout(t *testing.T) {
+	primary, err := NewRedisRateLimiter(hangingScripter{}, SlidingWindow)
+	if err != nil {
+		t.Fatalf("new redis limiter: %v", err)
+	}
+	primary.(*redisRateLimiter).timeout = 20 * time.Millisecond
+	fallback, err := NewMemoryRateLimiter(SlidingWindow)
+	if err != nil {
+		t.Fatalf("new memory limiter: %v", err)
+	}
+
+	limiter := NewFallbackRateLimiter(primary, fallback, time.Minute)
+	rate := Rate{Requests: 10, Window: time.Minute}
+
+	start := time.Now()
+	result, err := limiter.Allow(context.Background(), "ip:1.2.3.4", rate)
+	if err != nil || !result.Allowed {
+		t.Fatalf("result = %+v, err = %v, want the fallback to allow", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("allow took %s, want the script call bounded", elapsed)
+	}
+
+	// the cooldown is set, the next call skips Redis
+	start = time.Now()
+	if _, err := limiter.Allow(context.Background(), "ip:1.2.3.4", rate); err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+		t.Fatalf("allow took %s, want the fallback used during the cooldown", elapsed)
+	}
+}
+
+// failingLimiter fails every call and counts them.
+type failingLimiter struct {
+	calls int
+}
+
+func (l *failingLimiter) Allow(context.Context, string, Rate) (RateLimitResult, error) {
+	l.calls++
+	return RateLimitResult{}, errors.New("redis unavailable")
+}
+
+func TestFallbackRateLimiterCooldown(t *testing.T) {
+	clock := newTestClock()
+	primary := &failingLimiter{}
+	fallback := newTestLimiters(t, SlidingWindow, clock)["memory"]
+	limiter := NewFallbackRateLimiter(primary, fallback, 10*time.Second)
+	limiter.(*fallbackRateLimiter).now = clock.now
+
+	rate := Rate{Requests: 10, Window: time.Minute}
+	allowN(t, limiter, rate, 3)
+	if primary.calls != 1 {
+		t.Fatalf("primary calls = %d, want 1 during the cooldown", primary.calls)
+	}
+
+	clock.advance(11 * time.Second)
+	allowN(t, limiter, rate, 1)
+	if primary.calls != 2 {
+		t.Fatalf("primary calls = %d, want a retry after the cooldown", primary.calls)
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	clock := newTestClock()
+	limiter := newTestLimiters(t, SlidingWindow, clock)["memory"]
+
+	e := echo.New()
+	e.GET("/items", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, RateLimit(limiter, RateLimitConfig{Name: "api", Rate: Rate{Requests: 1, Window: time.Minute}}))
+
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+		return rec
+	}
+
+	rec := serve()
+	if rec.Code != http.StatusNoContent || rec.Header().Get(HeaderRateLimitRemaining) != "0" || rec.Header().Get(HeaderRateLimitPolicy) != "1;w=60" {
+		t.Fatalf("status = %d, headers = %v", rec.Code, rec.Header())
+	}
+
+	rec = serve()
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get(echo.HeaderRetryAfter) != "60" {
+		t.Fatalf("status = %d, headers = %v, want a 429 with Retry-After", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,185 @@
+package httpx
+
+import (
+	"api-core/pkg/errorx"
+	"api-core/pkg/requestid"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	errorRendererKey = "httpx.error_renderer"
+
+	MIMEApplicationProblemJSON = "application/problem+json"
+)
+
+// ErrorResponse is an error ready to be rendered, Message is already masked.
+type ErrorResponse struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []errorx.FieldError
+}
+
+// ErrorRenderer writes error responses in a given wire format.
+type ErrorRenderer interface {
+	RenderError(c echo.Context, resp ErrorResponse) error
+}
+
+// EnvelopeRenderer renders the {code, message, details} body, it is the default.
+type EnvelopeRenderer struct{}
+
+func (EnvelopeRenderer) RenderError(c echo.Context, resp ErrorResponse) error {
+	return c.JSON(resp.Status, &body{
+		Code:      resp.Code,
+		Message:   resp.Message,
+		Details:   resp.Fields,
+		RequestID: requestid.RequestID(c.Request().Context()),
+	})
+}
+
+// ProblemRenderer renders RFC 7807 application/problem+json bodies. The type
+// is TypeBaseURI followed by the error code, e.g. urn:api-core:problem:validation.
+type ProblemRenderer struct {
+	TypeBaseURI string
+}
+
+// Problem is an RFC 7807 problem details object, Extensions are serialized
+// as top level members.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+func (r ProblemRenderer) RenderError(c echo.Context, resp ErrorResponse) error {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(resp.Status),
+		Status:   resp.Status,
+		Detail:   resp.Message,
+		Instance: problemInstance(c),
+		Extensions: map[string]any{
+			"code": resp.Code,
+		},
+	}
+	if r.TypeBaseURI != "" {
+		problem.Type = r.TypeBaseURI + resp.Code
+	}
+	if len(resp.Fields) > 0 {
+		problem.Extensions["errors"] = resp.Fields
+	}
+
+	data, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	return c.Blob(resp.Status, MIMEApplicationProblemJSON, data)
+}
+
+// problemInstance identifies the occurrence by request id, or the path when
+// the request has none.
+func problemInstance(c echo.Context) string {
+	if id := requestid.RequestID(c.Request().Context()); id != "" {
+		return id
+	}
+	return c.Request().URL.Path
+}
+
+// NewErrorRenderer returns the renderer for a format name: "problem" or
+// "envelope" (default).
+func NewErrorRenderer(format, problemTypeBaseURI string) (ErrorRenderer, error) {
+	switch strings.ToLower(format) {
+	case "", "envelope":
+		return EnvelopeRenderer{}, nil
+	case "problem":
+		return ProblemRenderer{TypeBaseURI: problemTypeBaseURI}, nil
+	}
+	return nil, fmt.Errorf("unknown error format %q", format)
+}
+
+// RegisterErrorRenderer renders every error of the router with the renderer,
+// both the ones aborted by handlers and echo errors such as unknown routes.
+func RegisterErrorRenderer(e *echo.Echo, renderer ErrorRenderer) {
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(errorRendererKey, renderer)
+			return next(c)
+		}
+	})
+
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+		c.Set(errorRendererKey, renderer)
+
+		var he *echo.HTTPError
+		if !errors.As(err, &he) {
+			//nolint:errcheck
+			Abort(c, err)
+			return
+		}
+
+		if he.Code >= http.StatusInternalServerError {
+			slog.ErrorContext(c.Request().Context(), "request failed", "error", err)
+		}
+		message := http.StatusText(he.Code)
+		if m, ok := he.Message.(string); ok && he.Code < http.StatusInternalServerError {
+			message = m
+		}
+		if err := renderer.RenderError(c, ErrorResponse{Status: he.Code, Code: codeForStatus(he.Code), Message: message}); err != nil {
+			slog.ErrorContext(c.Request().Context(), "render error response", "error", err)
+		}
+	}
+}
+
+func errorRenderer(c echo.Context) ErrorRenderer {
+	if renderer, ok := c.Get(errorRendererKey).(ErrorRenderer); ok {
+		return renderer
+	}
+	return EnvelopeRenderer{}
+}
+
+// codeForStatus maps the status of echo errors to the errorx codes.
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		return errorx.Invalid.String()
+	case http.StatusUnauthorized:
+		return errorx.Authn.String()
+	case http.StatusForbidden:
+		return errorx.Authz.String()
+	case http.StatusNotFound:
+		return errorx.NotExist.String()
+	case http.StatusTooManyRequests:
+		return errorx.RateLimiting.String()
+	}
+	return "error"
+}
@@ -0,0 +1,219 @@
+package httpx
+
+import (
+	"api-core/pkg/errorx"
+	"api-core/pkg/requestid"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// serveError renders err through the renderer, requestID is attached to the
+// request context when set.
+func serveError(renderer ErrorRenderer, requestID string, err error) *httptest.ResponseRecorder {
+	e := echo.New()
+	RegisterErrorRenderer(e, renderer)
+	e.GET("/items", func(c echo.Context) error {
+		return Abort(c, err)
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if requestID != "" {
+				ctx := requestid.NewContext(c.Request().Context(), requestid.IDs{RequestID: requestID})
+				c.SetRequest(c.Request().WithContext(ctx))
+			}
+			return next(c)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+	return rec
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return got
+}
+
+var validationFailure = errorx.NewValidation([]errorx.FieldError{
+	{Field: "items[0].email", Rule: "email", Message: "must be a valid email address"},
+})
+
+func TestEnvelopeRenderer(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		requestID  string
+		wantStatus int
+		wantCode   string
+		wantMsg    string
+	}{
+		{name: "validation", err: validationFailure, requestID: "req-1", wantStatus: http.StatusUnprocessableEntity, wantCode: "validation", wantMsg: "validation failed"},
+		{name: "not found", err: errorx.Wrap(errors.New("no team"), errorx.NotExist), wantStatus: http.StatusNotFound, wantCode: "resource-not-found", wantMsg: "no team"},
+		{name: "service masked", err: errorx.Wrap(errors.New("dial tcp: refused"), errorx.Service), wantStatus: http.StatusInternalServerError, wantCode: "internal-service-failure", wantMsg: "unable to process"},
+		{name: "plain error masked", err: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantCode: "error", wantMsg: "unexpected error occurred"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveError(EnvelopeRenderer{}, tt.requestID, tt.err)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != echo.MIMEApplicationJSON {
+				t.Fatalf("content type = %q, want %q", ct, echo.MIMEApplicationJSON)
+			}
+
+			got := decodeBody(t, rec)
+			if got["code"] != tt.wantCode || got["message"] != tt.wantMsg {
+				t.Fatalf("body = %v, want code %q and message %q", got, tt.wantCode, tt.wantMsg)
+			}
+			if id, _ := got["request_id"].(string); id != tt.requestID {
+				t.Fatalf("request_id = %q, want %q", id, tt.requestID)
+			}
+			if _, ok := got["details"]; ok != (tt.err == validationFailure) {
+				t.Fatalf("details = %v, want them on validation errors only", got["details"])
+			}
+		})
+	}
+}
+
+func TestProblemRenderer(t *testing.T) {
+	tests := []struct {
+		name         string
+		renderer     ProblemRenderer
+		err          error
+		requestID    string
+		wantStatus   int
+		wantType     string
+		wantInstance string
+		wantDetail   string
+	}{
+		{
+			name:         "validation",
+			renderer:     ProblemRenderer{TypeBaseURI: "urn:api-core:problem:"},
+			err:          validationFailure,
+			requestID:    "req-1",
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantType:     "urn:api-core:problem:validation",
+			wantInstance: "req-1",
+			wantDetail:   "validation failed",
+		},
+		{
+			name:         "no base uri",
+			err:          errorx.Wrap(errors.New("no team"), errorx.NotExist),
+			wantStatus:   http.StatusNotFound,
+			wantType:     "about:blank",
+			wantInstance: "/items",
+			wantDetail:   "no team",
+		},
+		{
+			name:         "service masked",
+			renderer:     ProblemRenderer{TypeBaseURI: "urn:api-core:problem:"},
+			err:          errorx.Wrap(errors.New("dial tcp: refused"), errorx.Service),
+			wantStatus:   http.StatusInternalServerError,
+			wantType:     "urn:api-core:problem:internal-service-failure",
+			wantInstance: "/items",
+			wantDetail:   "unable to process",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveError(tt.renderer, tt.requestID, tt.err)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != MIMEApplicationProblemJSON {
+				t.Fatalf("content type = %q, want %q", ct, MIMEApplicationProblemJSON)
+			}
+
+			got := decodeBody(t, rec)
+			if got["type"] != tt.wantType || got["instance"] != tt.wantInstance || got["detail"] != tt.wantDetail {
+				t.Fatalf("problem = %v, want type %q, instance %q and detail %q", got, tt.wantType, tt.wantInstance, tt.wantDetail)
+			}
+			if got["title"] != http.StatusText(tt.wantStatus) || got["status"] != float64(tt.wantStatus) {
+				t.Fatalf("problem = %v, want the title and status of %d", got, tt.wantStatus)
+			}
+
+			errs, _ := got["errors"].([]any)
+			if (tt.err == validationFailure) != (len(errs) == 1) {
+				t.Fatalf("errors = %v, want them on validation errors only", got["errors"])
+			}
+			if len(errs) == 1 {
+				field, _ := errs[0].(map[string]any)
+				if field["field"] != "items[0].email" || field["rule"] != "email" {
+					t.Fatalf("errors[0] = %v, want the rejected field", field)
+				}
+			}
+		})
+	}
+}
+
+func TestProblemExtensionsDoNotOverrideMembers(t *testing.T) {
+	data, err := json.Marshal(Problem{
+		Type:       "about:blank",
+		Title:      "Not Found",
+		Status:     http.StatusNotFound,
+		Extensions: map[string]any{"status": 200, "type": "spoofed", "code": "resource-not-found"},
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got["status"] != float64(http.StatusNotFound) || got["type"] != "about:blank" || got["code"] != "resource-not-found" {
+		t.Fatalf("problem = %v, want the members to win over extensions", got)
+	}
+	if _, ok := got["detail"]; ok {
+		t.Fatalf("problem = %v, want an empty detail omitted", got)
+	}
+}
+
+func TestEchoErrorsUseRenderer(t *testing.T) {
+	e := echo.New()
+	RegisterErrorRenderer(e, ProblemRenderer{TypeBaseURI: "urn:api-core:problem:"})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if got := decodeBody(t, rec); got["type"] != "urn:api-core:problem:resource-not-found" || got["code"] != "resource-not-found" {
+		t.Fatalf("problem = %v, want the not found problem", got)
+	}
+}
+
+func TestNewErrorRenderer(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    ErrorRenderer
+		wantErr bool
+	}{
+		{format: "", want: EnvelopeRenderer{}},
+		{format: "envelope", want: EnvelopeRenderer{}},
+		{format: "Problem", want: ProblemRenderer{TypeBaseURI: "urn:x:"}},
+		{format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := NewErrorRenderer(tt.format, "urn:x:")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("renderer = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
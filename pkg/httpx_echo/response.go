@@ -3,10 +3,10 @@ package httpx
 import (
 	"api-core/pkg/auth"
 	"api-core/pkg/errorx"
+	"api-core/pkg/pagination"
 	"errors"
-	"fmt"
+	"log/slog"
 	"net/http"
-	"strings"
 
 	"github.com/go-playground/validator/v10"
 
@@ -14,9 +14,14 @@ import (
 )
 
 type body struct {
-	Code    string `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
-	Data    any    `json:"data,omitempty"`
+	Code    string              `json:"code,omitempty"`
+	Message string              `json:"message,omitempty"`
+	Data    any                 `json:"data,omitempty"`
+	Details []errorx.FieldError `json:"details,omitempty"`
+	// Pagination is only set on list pages.
+	Pagination *pagination.Pagination `json:"pagination,omitempty"`
+	// RequestID is only set on errors.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func Abort(c echo.Context, v any, codes ...int) error {
@@ -42,11 +47,11 @@ func abortErrorWithStatusJSON(c echo.Context, err error, code int) error {
 	message := errorx.MaskErrorMessage(err)
 
 	if !errors.As(err, &target) {
-		c.Logger().Error(err)
+		slog.ErrorContext(c.Request().Context(), "request failed", "error", err)
 		if code == -1 {
 			code = http.StatusInternalServerError
 		}
-		return c.JSON(code, &body{Code: "error", Message: message})
+		return errorRenderer(c).RenderError(c, ErrorResponse{Status: code, Code: "error", Message: message})
 	}
 
 	if code == -1 {
@@ -54,34 +59,40 @@ func abortErrorWithStatusJSON(c echo.Context, err error, code int) error {
 	}
 
 	if target.Of(errorx.Database) || target.Of(errorx.Service) {
-		c.Logger().Error(err)
-		return c.JSON(code, &body{Code: target.Code(), Message: message})
+		slog.ErrorContext(c.Request().Context(), "request failed", "error", err)
+		return errorRenderer(c).RenderError(c, ErrorResponse{Status: code, Code: target.Code(), Message: message})
 	}
 
-	return c.JSON(code, &body{Code: target.Code(), Message: message})
+	return errorRenderer(c).RenderError(c, ErrorResponse{Status: code, Code: target.Code(), Message: message, Fields: target.Fields()})
 }
 
 type ValidatorStruct interface {
 	Struct(s any) error
 }
 
+// ValidateStruct validates s, go-playground validation failures are returned as
+// an errorx.Validation error with one entry per rejected field.
 func ValidateStruct(c echo.Context, v ValidatorStruct, s any) error {
 	err := v.Struct(s)
 	if err == nil {
 		return nil
 	}
 
-	validationErrors, ok := err.(validator.ValidationErrors)
-	if !ok {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
 		return err
 	}
 
-	fields := []string{}
-	for _, f := range validationErrors {
-		fields = append(fields, f.StructField())
-	}
+	return validationError(validationErrors)
+}
 
-	return fmt.Errorf("invalid %s", strings.Join(fields, ", "))
+// RestPage writes a page of a list with its pagination, or the error as
+// RestAbort does.
+func RestPage(c echo.Context, v any, page pagination.Pagination, err error) error {
+	if err != nil {
+		return RestAbort(c, nil, err)
+	}
+	return c.JSON(http.StatusOK, &body{Data: v, Pagination: &page})
 }
 
 // RestAbort handles HTTP response with proper error handling.
@@ -93,6 +104,11 @@ func RestAbort(c echo.Context, v any, err error) error {
 		return Abort(c, v)
 	}
 
+	// The route deadline wins over the kind of the failed call
+	if timedOut(c, err) {
+		return Abort(c, timeoutError())
+	}
+
 	// Handle specific authentication errors
 	if errors.Is(err, auth.ErrInvalidSession) {
 		return Abort(c, errorx.Wrap(err, errorx.Authn))
@@ -0,0 +1,65 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"api-core/pkg/errorx"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TimeoutConfig bounds the time a handler may run through the request
+// context, so database and outgoing calls are cancelled at the deadline.
+type TimeoutConfig struct {
+	// Default applies to the routes without their own timeout, zero disables
+	// it.
+	Default time.Duration
+	// Routes maps "METHOD /path" or "/path" route templates, e.g.
+	// "GET /api/v1/admin/audit", to their timeout.
+	Routes map[string]time.Duration
+}
+
+func (cfg TimeoutConfig) timeout(c echo.Context) time.Duration {
+	if d, ok := cfg.Routes[c.Request().Method+" "+c.Path()]; ok {
+		return d
+	}
+	if d, ok := cfg.Routes[c.Path()]; ok {
+		return d
+	}
+	return cfg.Default
+}
+
+// Timeout sets the route deadline on the request context. A handler failing
+// with the deadline gets a 503 timeout error.
+func Timeout(cfg TimeoutConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timeout := cfg.timeout(c)
+			if timeout <= 0 {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			err := next(c)
+			if err != nil && !c.Response().Committed && timedOut(c, err) {
+				return Abort(c, timeoutError())
+			}
+			return err
+		}
+	}
+}
+
+// timedOut reports an error caused by the deadline of the request, not by
+// the client going away.
+func timedOut(c echo.Context, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) && errors.Is(c.Request().Context().Err(), context.DeadlineExceeded)
+}
+
+func timeoutError() error {
+	return errorx.Wrap(errors.New("request timed out"), errorx.Timeout)
+}
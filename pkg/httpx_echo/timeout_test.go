@@ -0,0 +1,108 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// waitDeadline blocks until the request context is done, like a slow query,
+// and returns its error wrapped.
+func waitDeadline(c echo.Context) error {
+	ctx := c.Request().Context()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("query: %w", ctx.Err())
+	case <-time.After(time.Second):
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	cfg := TimeoutConfig{
+		Default: 20 * time.Millisecond,
+		Routes: map[string]time.Duration{
+			"GET /slow": time.Second,
+			"/off":      0,
+		},
+	}
+	e := echo.New()
+	RegisterErrorRenderer(e, EnvelopeRenderer{})
+	e.Use(Timeout(cfg))
+	e.GET("/fast", waitDeadline)
+	e.GET("/slow", func(c echo.Context) error {
+		deadline, ok := c.Request().Context().Deadline()
+		if !ok || time.Until(deadline) < 500*time.Millisecond {
+			return errors.New("want the route timeout")
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.GET("/off", func(c echo.Context) error {
+		if _, ok := c.Request().Context().Deadline(); ok {
+			return errors.New("want no deadline")
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.GET("/committed", func(c echo.Context) error {
+		c.Response().WriteHeader(http.StatusAccepted)
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{target: "/fast", want: http.StatusServiceUnavailable},
+		{target: "/slow", want: http.StatusNoContent},
+		{target: "/off", want: http.StatusNoContent},
+		// a response already written is left alone
+		{target: "/committed", want: http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestTimedOut(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	// an outgoing call with its own deadline, shorter than the request's
+	callDeadline := fmt.Errorf("call: %w", context.DeadlineExceeded)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "request deadline", ctx: expired, err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: true},
+		{name: "client went away", ctx: canceled, err: context.Canceled},
+		{name: "client went away during a call", ctx: canceled, err: callDeadline},
+		{name: "call deadline only", ctx: context.Background(), err: callDeadline},
+		{name: "other error", ctx: expired, err: errors.New("boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(tt.ctx)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			if got := timedOut(c, tt.err); got != tt.want {
+				t.Fatalf("timed out = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package httpx
+
+import (
+	"api-core/pkg/errorx"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+var slugRegexp = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Validator adapts go-playground/validator to echo.Validator, failures are
+// returned as errorx.Validation errors listing the rejected JSON fields.
+type Validator struct {
+	validate *validator.Validate
+}
+
+func NewValidator() *Validator {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "query", "param", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	// uuid and e164 are built in, slug is specific to our URLs
+	//nolint:errcheck
+	validate.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugRegexp.MatchString(fl.Field().String())
+	})
+
+	return &Validator{validate: validate}
+}
+
+func (v *Validator) Validate(i any) error {
+	return v.Struct(i)
+}
+
+func (v *Validator) Struct(s any) error {
+	err := v.validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return errorx.Wrap(err, errorx.Invalid)
+	}
+	return validationError(validationErrors)
+}
+
+// BindAndValidate binds the request into a new T and runs the echo Validator.
+func BindAndValidate[T any](c echo.Context) (*T, error) {
+	var v T
+	if err := c.Bind(&v); err != nil {
+		return nil, errorx.Wrap(err, errorx.Invalid)
+	}
+	if err := c.Validate(&v); err != nil {
+		var target *errorx.Error
+		if errors.As(err, &target) {
+			return nil, err
+		}
+		return nil, errorx.Wrap(err, errorx.Service)
+	}
+	return &v, nil
+}
+
+func validationError(validationErrors validator.ValidationErrors) *errorx.Error {
+	fields := make([]errorx.FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fields = append(fields, errorx.FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fieldMessage(fe),
+		})
+	}
+	return errorx.NewValidation(fields)
+}
+
+// fieldPath drops the root struct name from the namespace, which the tag name
+// func turned into JSON names ("Request.items[0].email" -> "items[0].email").
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "e164":
+		return "must be a phone number in E.164 format, e.g. +14155552671"
+	case "slug":
+		return "must contain only lowercase letters, digits and single hyphens"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	case "min", "gte":
+		if unit := lengthUnit(fe.Kind()); unit != "" {
+			return fmt.Sprintf("must contain at least %s %s", fe.Param(), unit)
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max", "lte":
+		if unit := lengthUnit(fe.Kind()); unit != "" {
+			return fmt.Sprintf("must contain at most %s %s", fe.Param(), unit)
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("must have a length of %s", fe.Param())
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
+
+func lengthUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "items"
+	}
+	return ""
+}
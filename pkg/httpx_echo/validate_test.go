@@ -0,0 +1,180 @@
+package httpx
+
+import (
+	"api-core/pkg/errorx"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+type testItem struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type testRequest struct {
+	Slug  string     `json:"slug" validate:"required,slug"`
+	Name  string     `json:"name" validate:"max=5"`
+	Items []testItem `json:"items" validate:"max=2,dive"`
+}
+
+type testQuery struct {
+	Page   int    `query:"page" validate:"min=1"`
+	Status string `query:"status" validate:"omitempty,oneof=pending approved"`
+}
+
+func newTestContext(method, target, body string) echo.Context {
+	e := echo.New()
+	e.Validator = NewValidator()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	return e.NewContext(req, httptest.NewRecorder())
+}
+
+// bindRequest binds the JSON body into testRequest.
+func bindRequest(body string) (*testRequest, error) {
+	return BindAndValidate[testRequest](newTestContext(http.MethodPost, "/items", body))
+}
+
+func assertFields(t *testing.T, err error, kind errorx.Kind, want []string) {
+	t.Helper()
+
+	var target *errorx.Error
+	if !errors.As(err, &target) || !target.Of(kind) {
+		t.Fatalf("err = %v, want kind %s", err, kind)
+	}
+	var fields []string
+	for _, f := range target.Fields() {
+		fields = append(fields, f.Field)
+	}
+	if !slices.Equal(fields, want) {
+		t.Fatalf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestBindAndValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantKind   errorx.Kind
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			body: `{"slug":"my-team","name":"abc","items":[{"email":"a@example.com"}]}`,
+		},
+		{
+			name:       "nested item",
+			body:       `{"slug":"my-team","items":[{"email":"a@example.com"},{"email":"nope"}]}`,
+			wantKind:   errorx.Validation,
+			wantFields: []string{"items[1].email"},
+		},
+		{
+			name:       "every failed field",
+			body:       `{"slug":"My_Team","name":"toolong"}`,
+			wantKind:   errorx.Validation,
+			wantFields: []string{"slug", "name"},
+		},
+		{
+			name:     "malformed body",
+			body:     `{"slug":`,
+			wantKind: errorx.Invalid,
+		},
+		{
+			name:     "mistyped field",
+			body:     `{"slug":1}`,
+			wantKind: errorx.Invalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bindRequest(tt.body)
+			if tt.wantKind == errorx.Other {
+				if err != nil {
+					t.Fatalf("err = %v, want none", err)
+				}
+				if got.Slug != "my-team" || len(got.Items) != 1 {
+					t.Fatalf("bound = %+v", got)
+				}
+				return
+			}
+			assertFields(t, err, tt.wantKind, tt.wantFields)
+		})
+	}
+}
+
+func TestBindAndValidateQuery(t *testing.T) {
+	got, err := BindAndValidate[testQuery](newTestContext(http.MethodGet, "/items?page=2&status=approved", ""))
+	if err != nil {
+		t.Fatalf("err = %v, want none", err)
+	}
+	if got.Page != 2 || got.Status != "approved" {
+		t.Fatalf("bound = %+v", got)
+	}
+
+	// the query names are reported, not the Go ones
+	_, err = BindAndValidate[testQuery](newTestContext(http.MethodGet, "/items?page=0&status=gone", ""))
+	assertFields(t, err, errorx.Validation, []string{"page", "status"})
+
+	_, err = BindAndValidate[testQuery](newTestContext(http.MethodGet, "/items?page=two", ""))
+	assertFields(t, err, errorx.Invalid, nil)
+}
+
+func TestValidationMessages(t *testing.T) {
+	_, err := bindRequest(`{"slug":"a--b","name":"toolong","items":[{},{},{}]}`)
+
+	var target *errorx.Error
+	if !errors.As(err, &target) {
+		t.Fatalf("err = %v, want a validation error", err)
+	}
+	got := map[string]errorx.FieldError{}
+	for _, f := range target.Fields() {
+		got[f.Field] = f
+	}
+
+	want := map[string]errorx.FieldError{
+		"slug":  {Field: "slug", Rule: "slug", Message: "must contain only lowercase letters, digits and single hyphens"},
+		"name":  {Field: "name", Rule: "max", Param: "5", Message: "must contain at most 5 characters"},
+		"items": {Field: "items", Rule: "max", Param: "2", Message: "must contain at most 2 items"},
+	}
+	for field, w := range want {
+		if got[field] != w {
+			t.Fatalf("%s = %+v, want %+v", field, got[field], w)
+		}
+	}
+}
+
+func TestSlugRule(t *testing.T) {
+	tests := []struct {
+		slug string
+		want bool
+	}{
+		{slug: "team", want: true},
+		{slug: "my-team-2", want: true},
+		{slug: "2024", want: true},
+		{slug: "My-Team"},
+		{slug: "my--team"},
+		{slug: "-team"},
+		{slug: "team-"},
+		{slug: "my_team"},
+		{slug: "my team"},
+	}
+	v := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.slug, func(t *testing.T) {
+			err := v.Struct(&struct {
+				Slug string `json:"slug" validate:"slug"`
+			}{Slug: tt.slug})
+			if (err == nil) != tt.want {
+				t.Fatalf("valid = %v, want %v: %v", err == nil, tt.want, err)
+			}
+		})
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"encoding/base64"
 	"errors"
 	"time"
-
-	"github.com/golang-jwt/jwt/v4"
 )
 
 type JWK struct {
@@ -18,31 +16,6 @@ type JWK struct {
 	X     string `json:"x"`
 }
 
-type JWTClaims struct {
-	Email string `json:"email,omitempty"`
-	jwt.RegisteredClaims
-}
-
-func NewJWTClaims(claims jwt.Claims) (*JWTClaims, error) {
-	err := validateMapClaims(claims)
-	if err != nil {
-		return nil, err
-	}
-	claimsMapping := claims.(jwt.MapClaims)
-	return &JWTClaims{
-		Email: claimsMapping["email"].(string),
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    claimsMapping["iss"].(string),
-			Subject:   claimsMapping["sub"].(string),
-			Audience:  jwt.ClaimStrings{claimsMapping["aud"].(string)},
-			ExpiresAt: jwt.NewNumericDate(time.Unix(int64(claimsMapping["exp"].(float64)), 0)),
-			//NotBefore: jwt.NewNumericDate(time.Unix(int64(claimsMapping["nbf"].(float64)), 0)),
-			IssuedAt: jwt.NewNumericDate(time.Unix(int64(claimsMapping["iat"].(float64)), 0)),
-			ID:       claimsMapping["jti"].(string),
-		},
-	}, nil
-}
-
 var (
 	ErrUnableToParse = errors.New("unable to parse")
 	ErrInvalidClaims = errors.New("invalid token claims")
@@ -0,0 +1,198 @@
+package jwtx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claims is the claims model of every token handled by the API, issued or
+// verified. Audience, Roles and AMR accept a single string or an array, Scope
+// is serialized as the space separated "scope" claim (RFC 8693). Unknown
+// claims are kept in Extra.
+type Claims struct {
+	jwt.RegisteredClaims
+
+	Email    string           `json:"email,omitempty"`
+	Scope    Scopes           `json:"scope,omitempty"`
+	Roles    jwt.ClaimStrings `json:"roles,omitempty"`
+	Tenant   string           `json:"tenant,omitempty"`
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	// AMR lists the authentication methods (RFC 8176), e.g. pwd, otp, mfa.
+	AMR jwt.ClaimStrings `json:"amr,omitempty"`
+
+	// Extra holds extension claims, registered names are never overridden.
+	Extra map[string]any `json:"-"`
+}
+
+// knownClaims are decoded into typed fields and never land in Extra.
+var knownClaims = []string{
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti",
+	"email", "scope", "roles", "tenant", "auth_time", "amr",
+}
+
+type claimsAlias Claims
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	var alias claimsAlias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, name := range knownClaims {
+		delete(raw, name)
+	}
+	alias.Extra = nil
+	if len(raw) > 0 {
+		alias.Extra = raw
+	}
+
+	*c = Claims(alias)
+	return nil
+}
+
+func (c Claims) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(claimsAlias(c))
+	if err != nil || len(c.Extra) == 0 {
+		return data, err
+	}
+
+	merged := make(map[string]any, len(c.Extra)+len(knownClaims))
+	for name, v := range c.Extra {
+		merged[name] = v
+	}
+	var typed map[string]any
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return nil, err
+	}
+	for name, v := range typed {
+		merged[name] = v
+	}
+	return json.Marshal(merged)
+}
+
+// Valid checks the time based claims and that the claims required by the API
+// are present.
+func (c *Claims) Valid() error {
+	if err := c.RegisteredClaims.Valid(); err != nil {
+		return err
+	}
+	switch {
+	case c.Issuer == "":
+		return errors.New("missing required claim: iss")
+	case c.Subject == "":
+		return errors.New("missing required claim: sub")
+	case c.ExpiresAt == nil:
+		return errors.New("missing required claim: exp")
+	case c.IssuedAt == nil:
+		return errors.New("missing required claim: iat")
+	case c.ID == "":
+		return errors.New("missing required claim: jti")
+	}
+	return nil
+}
+
+// HasScope reports whether every given scope was granted.
+func (c *Claims) HasScope(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scope, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasRole reports whether the token carries the role.
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// AuthenticatedAt returns auth_time, falling back to iat when the issuer did
+// not set it.
+func (c *Claims) AuthenticatedAt() time.Time {
+	if c.AuthTime != nil {
+		return c.AuthTime.Time
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// Scopes is a space separated list of scopes, an array is accepted on input.
+type Scopes []string
+
+// ParseScopes splits a space separated scope string.
+func ParseScopes(scope string) Scopes {
+	fields := strings.Fields(scope)
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+func (s Scopes) String() string {
+	return strings.Join(s, " ")
+}
+
+func (s Scopes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Scopes) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		*s = nil
+	case string:
+		*s = ParseScopes(v)
+	case []any:
+		scopes := make(Scopes, 0, len(v))
+		for _, item := range v {
+			scope, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("invalid type for claim: scope")
+			}
+			scopes = append(scopes, scope)
+		}
+		*s = scopes
+	default:
+		return fmt.Errorf("invalid type for claim: scope")
+	}
+	return nil
+}
+
+// ParseClaims maps the claims of a verified token, whatever the type the
+// parser decoded them into.
+func ParseClaims(claims jwt.Claims) (*Claims, error) {
+	if typed, ok := claims.(*Claims); ok {
+		return typed, typed.Valid()
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed Claims
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+	if err := parsed.Valid(); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,131 @@
+package jwtx
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mapClaims returns the claims a third-party verifier decodes, every required
+// claim set.
+func mapClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": "https://issuer.example.com",
+		"sub": "42",
+		"exp": float64(now.Add(time.Hour).Unix()),
+		"iat": float64(now.Unix()),
+		"jti": "token-1",
+	}
+}
+
+// mapAuthenticator returns the claims as is, like a verifier decoding into
+// jwt.MapClaims.
+type mapAuthenticator jwt.MapClaims
+
+func (a mapAuthenticator) AuthenticateJWT(string) (*jwt.Token, error) {
+	return &jwt.Token{Claims: jwt.MapClaims(a), Valid: true}, nil
+}
+
+func TestParseClaimsAudience(t *testing.T) {
+	tests := []struct {
+		name string
+		aud  any
+		want []string
+	}{
+		{name: "array", aud: []any{"api-core", "billing"}, want: []string{"api-core", "billing"}},
+		{name: "single string", aud: "api-core", want: []string{"api-core"}},
+		{name: "absent", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := mapClaims()
+			if tt.aud != nil {
+				raw["aud"] = tt.aud
+			}
+
+			claims, err := ParseClaims(raw)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if !slices.Equal(claims.Audience, tt.want) {
+				t.Fatalf("aud = %v, want %v", claims.Audience, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClaimsRequired(t *testing.T) {
+	for _, name := range []string{"iss", "sub", "exp", "iat", "jti"} {
+		t.Run(name, func(t *testing.T) {
+			raw := mapClaims()
+			delete(raw, name)
+
+			_, err := ParseClaims(raw)
+			if err == nil || !strings.Contains(err.Error(), "missing required claim: "+name) {
+				t.Fatalf("err = %v, want missing %s", err, name)
+			}
+		})
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		raw := mapClaims()
+		raw["exp"] = float64(time.Now().Add(-time.Minute).Unix())
+		if _, err := ParseClaims(raw); err == nil {
+			t.Fatal("expected an expired token to fail")
+		}
+	})
+}
+
+func TestVerifyMapClaims(t *testing.T) {
+	raw := mapClaims()
+	raw["aud"] = []any{"api-core", "billing"}
+	raw["scope"] = "read write"
+	raw["roles"] = "admin"
+	raw["org"] = "acme"
+
+	_, claims, err := Verify(mapAuthenticator(raw), "token")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !slices.Equal(claims.Audience, []string{"api-core", "billing"}) || !claims.HasScope("read", "write") || !claims.HasRole("admin") {
+		t.Fatalf("claims = %+v", claims)
+	}
+	if claims.Extra["org"] != "acme" {
+		t.Fatalf("extra = %v, want org", claims.Extra)
+	}
+
+	delete(raw, "jti")
+	if _, _, err := Verify(mapAuthenticator(raw), "token"); !errors.Is(err, ErrInvalidClaims) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidClaims)
+	}
+}
+
+func TestHMACIssuerAudienceRoundTrip(t *testing.T) {
+	issuer, err := NewHMACIssuer("test-secret", "api-core", time.Hour)
+	if err != nil {
+		t.Fatalf("new issuer: %v", err)
+	}
+
+	token, err := issuer.IssueClaims(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "42", Audience: jwt.ClaimStrings{"api-core", "billing"}},
+	})
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	_, claims, err := Verify(issuer, token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !slices.Equal(claims.Audience, []string{"api-core", "billing"}) {
+		t.Fatalf("aud = %v, want both audiences", claims.Audience)
+	}
+	if claims.Issuer != "api-core" || claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		t.Fatalf("claims = %+v, want the issuer set claims", claims)
+	}
+}
@@ -2,6 +2,7 @@ package jwtx
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -31,23 +32,55 @@ func NewHMACIssuer(secret string, issuer string, expiration time.Duration) (*HMA
 	}, nil
 }
 
-type Claims struct {
-	Email string `json:"email,omitempty"`
-	jwt.RegisteredClaims
+// Expiration returns the lifetime of issued tokens.
+func (i *HMACIssuer) Expiration() time.Duration {
+	return i.expiration
 }
 
 func (i *HMACIssuer) Issue(subject string, email string) (string, error) {
-	now := time.Now()
-	claims := Claims{
-		Email: email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    i.issuer,
-			Subject:   subject,
-			ExpiresAt: jwt.NewNumericDate(now.Add(i.expiration)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			ID:        uuid.NewString(),
-		},
+	return i.IssueClaims(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+		Email:            email,
+	})
+}
+
+// IssueClaims signs the claims, iss, iat, exp and jti are always set by the
+// issuer.
+func (i *HMACIssuer) IssueClaims(claims Claims) (string, error) {
+	if claims.Subject == "" {
+		return "", errors.New("jwt issuer: empty subject")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	now := time.Now()
+	claims.Issuer = i.issuer
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(i.expiration))
+	claims.ID = uuid.NewString()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims)
 	return token.SignedString(i.secret)
 }
+
+// AuthenticateJWT verifies a token previously signed by Issue, so the issuer can
+// back httpx.Authn and auth.Guard.
+func (i *HMACIssuer) AuthenticateJWT(tokenStr string) (*jwt.Token, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return i.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidClaims
+	}
+	if claims.Issuer != i.issuer {
+		return nil, errors.New("invalid issuer")
+	}
+
+	return token, nil
+}
@@ -2,47 +2,27 @@ package jwtx
 
 import (
 	"fmt"
+
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func validateMapClaims(claims jwt.Claims) error {
-	claimsMapping, ok := claims.(jwt.MapClaims)
-	if !ok {
-		return fmt.Errorf("invalid type for claims mapping")
-	}
+// Authenticator verifies the signature and registered claims of a raw token.
+type Authenticator interface {
+	AuthenticateJWT(tokenStr string) (*jwt.Token, error)
+}
 
-	requiredClaims := []string{"iss", "sub", "aud", "exp", "iat", "jti"}
-	for _, claim := range requiredClaims {
-		if _, ok := claimsMapping[claim]; !ok {
-			return fmt.Errorf("missing required claim: %s", claim)
-		}
+// Verify authenticates a raw token and maps its claims, this is the single
+// verification path shared by the HTTP middleware and token introspection.
+func Verify(authn Authenticator, tokenStr string) (*jwt.Token, *Claims, error) {
+	token, err := authn.AuthenticateJWT(tokenStr)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Convert and validate claim types
-	_, ok = claimsMapping["iss"].(string)
-	if !ok {
-		return fmt.Errorf("invalid type for claim: iss")
-	}
-	_, ok = claimsMapping["sub"].(string)
-	if !ok {
-		return fmt.Errorf("invalid type for claim: sub")
-	}
-	_, ok = claimsMapping["aud"].(string)
-	if !ok {
-		return fmt.Errorf("invalid type for claim: aud")
-	}
-	_, ok = claimsMapping["exp"].(float64)
-	if !ok {
-		return fmt.Errorf("invalid type for claim: exp")
-	}
-	_, ok = claimsMapping["iat"].(float64)
-	if !ok {
-		return fmt.Errorf("invalid type for claim: iat")
-	}
-	_, ok = claimsMapping["jti"].(string)
-	if !ok {
-		return fmt.Errorf("invalid type for claim: jti")
+	claims, err := ParseClaims(token.Claims)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidClaims, err)
 	}
 
-	return nil
+	return token, claims, nil
 }
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTP records the request durations labelled by method, route template and
+// status, so /users/:id is one series whatever the id.
+func HTTP(reg prometheus.Registerer) echo.MiddlewareFunc {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of the HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	inFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Requests being served.",
+	})
+	reg.MustRegister(duration, inFlight)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				// render now so the status of the error response is recorded
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" || route == "/*" {
+				// unmatched paths would create a series per URL
+				route = "unmatched"
+			}
+			status := strconv.Itoa(c.Response().Status)
+			duration.WithLabelValues(c.Request().Method, route, status).Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
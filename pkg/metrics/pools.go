@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// pgxPoolCollector reads the pool statistics on every scrape.
+type pgxPoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired     *prometheus.Desc
+	idle         *prometheus.Desc
+	total        *prometheus.Desc
+	max          *prometheus.Desc
+	acquires     *prometheus.Desc
+	emptyAcquire *prometheus.Desc
+	canceled     *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+// NewPGXPoolCollector exports the connection pool statistics of pool.
+func NewPGXPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	return &pgxPoolCollector{
+		pool:         pool,
+		acquired:     prometheus.NewDesc("pgxpool_acquired_connections", "Connections currently acquired.", nil, nil),
+		idle:         prometheus.NewDesc("pgxpool_idle_connections", "Idle connections in the pool.", nil, nil),
+		total:        prometheus.NewDesc("pgxpool_total_connections", "Connections in the pool.", nil, nil),
+		max:          prometheus.NewDesc("pgxpool_max_connections", "Maximum size of the pool.", nil, nil),
+		acquires:     prometheus.NewDesc("pgxpool_acquires_total", "Successful connection acquires.", nil, nil),
+		emptyAcquire: prometheus.NewDesc("pgxpool_empty_acquires_total", "Acquires that waited for a connection.", nil, nil),
+		canceled:     prometheus.NewDesc("pgxpool_canceled_acquires_total", "Acquires canceled by their context.", nil, nil),
+		waitDuration: prometheus.NewDesc("pgxpool_acquire_wait_seconds_total", "Time spent waiting for a connection.", nil, nil),
+	}
+}
+
+func (c *pgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *pgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds())
+}
+
+// redisPoolCollector reads the go-redis pool statistics on every scrape.
+type redisPoolCollector struct {
+	client *redis.Client
+
+	hits     *prometheus.Desc
+	misses   *prometheus.Desc
+	timeouts *prometheus.Desc
+	total    *prometheus.Desc
+	idle     *prometheus.Desc
+	stale    *prometheus.Desc
+}
+
+// NewRedisPoolCollector exports the connection pool statistics of client.
+func NewRedisPoolCollector(client *redis.Client) prometheus.Collector {
+	return &redisPoolCollector{
+		client:   client,
+		hits:     prometheus.NewDesc("redis_pool_hits_total", "Times a free connection was found in the pool.", nil, nil),
+		misses:   prometheus.NewDesc("redis_pool_misses_total", "Times a free connection was not found in the pool.", nil, nil),
+		timeouts: prometheus.NewDesc("redis_pool_timeouts_total", "Times a wait for a connection timed out.", nil, nil),
+		total:    prometheus.NewDesc("redis_pool_total_connections", "Connections in the pool.", nil, nil),
+		idle:     prometheus.NewDesc("redis_pool_idle_connections", "Idle connections in the pool.", nil, nil),
+		stale:    prometheus.NewDesc("redis_pool_stale_connections_total", "Stale connections removed from the pool.", nil, nil),
+	}
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(stats.StaleConns))
+}
+
+// CacheStats are the cumulative lookups of a cache.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type cacheCollector struct {
+	name  string
+	stats func() CacheStats
+
+	hits   *prometheus.Desc
+	misses *prometheus.Desc
+}
+
+// NewCacheCollector exports the hits and misses of a cache, labelled by name.
+func NewCacheCollector(name string, stats func() CacheStats) prometheus.Collector {
+	labels := prometheus.Labels{"cache": name}
+	return &cacheCollector{
+		name:   name,
+		stats:  stats,
+		hits:   prometheus.NewDesc("cache_hits_total", "Cache lookups served from the cache.", nil, labels),
+		misses: prometheus.NewDesc("cache_misses_total", "Cache lookups missing the cache.", nil, labels),
+	}
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+}
@@ -0,0 +1,5 @@
+package openapi
+
+// The docs UI script is pinned to an exact version and embedded, so the page
+// never loads code that changed behind the same URL.
+//go:generate curl -fsSL -o assets/scalar-api-reference.js https://cdn.jsdelivr.net/npm/@scalar/api-reference@1.25.0/dist/browser/standalone.js
@@ -0,0 +1,94 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed docs.html
+var docsHTML string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// assets holds the docs UI script fetched by go generate, see generate.go.
+//
+//go:embed all:assets
+var assets embed.FS
+
+// DocsScriptName is the name of the embedded docs UI script.
+const DocsScriptName = "scalar-api-reference.js"
+
+// DocsContentSecurityPolicy allows the embedded docs UI script, the styles and
+// fonts it injects, and the try it out requests to this origin.
+const DocsContentSecurityPolicy = "default-src 'none'; script-src 'self'; " +
+	"style-src 'unsafe-inline' https://fonts.scalar.com; font-src data: https://fonts.scalar.com; " +
+	"img-src 'self' data: https:; connect-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+
+// docsScript returns the embedded docs UI script, nil when the build has none.
+func docsScript() []byte {
+	script, err := fs.ReadFile(assets, "assets/"+DocsScriptName)
+	if err != nil {
+		return nil
+	}
+	return script
+}
+
+// SpecHandler serves the document as JSON. It is built on the first request,
+// once every route is registered.
+func SpecHandler(registry *Registry) echo.HandlerFunc {
+	var (
+		once sync.Once
+		spec []byte
+		err  error
+	)
+	return func(c echo.Context) error {
+		once.Do(func() {
+			spec, err = json.Marshal(registry.Document())
+		})
+		if err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, spec)
+	}
+}
+
+// DocsHandler serves an HTML page rendering the document found at specURL
+// with the script served by DocsScriptHandler at scriptURL. Without an
+// embedded script the page explains how to add one. A Content-Security-Policy
+// set by a security middleware, enforced or report only, is replaced with
+// DocsContentSecurityPolicy.
+func DocsHandler(title, specURL, scriptURL string) echo.HandlerFunc {
+	if docsScript() == nil {
+		scriptURL = ""
+	}
+	return func(c echo.Context) error {
+		header := c.Response().Header()
+		for _, name := range []string{echo.HeaderContentSecurityPolicy, echo.HeaderContentSecurityPolicyReportOnly} {
+			if header.Get(name) != "" {
+				header.Set(name, DocsContentSecurityPolicy)
+			}
+		}
+		header.Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+		c.Response().WriteHeader(http.StatusOK)
+		return docsTemplate.Execute(c.Response(), map[string]string{"Title": title, "SpecURL": specURL, "ScriptURL": scriptURL})
+	}
+}
+
+// DocsScriptHandler serves the embedded docs UI script, 404 when the build has
+// none.
+func DocsScriptHandler() echo.HandlerFunc {
+	script := docsScript()
+	return func(c echo.Context) error {
+		if script == nil {
+			return echo.ErrNotFound
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=86400")
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJavaScriptCharsetUTF8, script)
+	}
+}
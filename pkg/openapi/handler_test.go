@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// cspDirectives splits a policy into its directives and their sources.
+func cspDirectives(policy string) map[string][]string {
+	directives := map[string][]string{}
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) > 0 {
+			directives[fields[0]] = fields[1:]
+		}
+	}
+	return directives
+}
+
+func TestDocsContentSecurityPolicy(t *testing.T) {
+	directives := cspDirectives(DocsContentSecurityPolicy)
+
+	// only the script served from this origin runs, no inline or CDN script
+	if got := directives["script-src"]; !slices.Equal(got, []string{"'self'"}) {
+		t.Fatalf("script-src = %v, want only 'self'", got)
+	}
+	for name, want := range map[string][]string{
+		"default-src":     {"'none'"},
+		"connect-src":     {"'self'"},
+		"frame-ancestors": {"'none'"},
+		"base-uri":        {"'none'"},
+		"form-action":     {"'none'"},
+	} {
+		if got := directives[name]; !slices.Equal(got, want) {
+			t.Fatalf("%s = %v, want %v", name, got, want)
+		}
+	}
+	for name, sources := range directives {
+		for _, source := range sources {
+			if source == "*" || source == "'unsafe-eval'" || name != "style-src" && source == "'unsafe-inline'" {
+				t.Fatalf("%s allows %s", name, source)
+			}
+		}
+	}
+}
+
+func TestDocsHandler(t *testing.T) {
+	e := echo.New()
+	secure := func(name string) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.Response().Header().Set(name, "default-src 'self'")
+				return next(c)
+			}
+		}
+	}
+	docs := DocsHandler("API", "/docs/openapi.json", "/docs/"+DocsScriptName)
+	e.GET("/docs", docs, secure(echo.HeaderContentSecurityPolicy))
+	e.GET("/docs/report-only", docs, secure(echo.HeaderContentSecurityPolicyReportOnly))
+	e.GET("/docs/plain", docs)
+	e.GET("/docs/"+DocsScriptName, DocsScriptHandler())
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := serve("/docs")
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderContentSecurityPolicy) != DocsContentSecurityPolicy {
+		t.Fatalf("status = %d, headers = %v, want the docs policy", rec.Code, rec.Header())
+	}
+	if got := serve("/docs/report-only").Header().Get(echo.HeaderContentSecurityPolicyReportOnly); got != DocsContentSecurityPolicy {
+		t.Fatalf("report only policy = %q, want the docs policy", got)
+	}
+	// no policy is added where the security middleware set none
+	if got := serve("/docs/plain").Header().Get(echo.HeaderContentSecurityPolicy); got != "" {
+		t.Fatalf("policy = %q, want none", got)
+	}
+
+	body := rec.Body.String()
+	script := serve("/docs/" + DocsScriptName)
+	if docsScript() == nil {
+		if strings.Contains(body, "<script") || !strings.Contains(body, "go generate ./pkg/openapi") {
+			t.Fatalf("body = %s, want the note instead of the script", body)
+		}
+		if script.Code != http.StatusNotFound {
+			t.Fatalf("script status = %d, want 404 without an embedded script", script.Code)
+		}
+		return
+	}
+	if !strings.Contains(body, `<script src="/docs/`+DocsScriptName+`">`) || !strings.Contains(body, `data-url="/docs/openapi.json"`) {
+		t.Fatalf("body = %s, want the embedded script", body)
+	}
+	if script.Code != http.StatusOK || script.Header().Get(echo.HeaderCacheControl) == "" {
+		t.Fatalf("script status = %d, headers = %v", script.Code, script.Header())
+	}
+}
@@ -0,0 +1,317 @@
+package openapi
+
+import (
+	"api-core/pkg/auth"
+	"api-core/pkg/errorx"
+	httpx "api-core/pkg/httpx_echo"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// validatedKey marks a request already checked by a Validate middleware.
+const validatedKey = "openapi.validated"
+
+// defaultMaxBodyBytes bounds the JSON bodies read for validation.
+const defaultMaxBodyBytes = 1 << 20
+
+// ValidationConfig configures the Validate middleware.
+type ValidationConfig struct {
+	// Responses also validates the responses and replaces the ones drifting
+	// from the document with a 500, meant for the dev and test profiles.
+	Responses bool
+	// MaxBodyBytes rejects larger JSON bodies with a 413, 1 MiB by default.
+	MaxBodyBytes int64
+}
+
+// operations indexes the document by method and echo route path.
+type operations struct {
+	validator    *schemaValidator
+	byRoute      map[string]*Operation
+	maxBodyBytes int64
+}
+
+func newOperations(doc *Document, maxBodyBytes int64) *operations {
+	ops := &operations{
+		validator:    newSchemaValidator(doc.Components.Schemas),
+		byRoute:      map[string]*Operation{},
+		maxBodyBytes: maxBodyBytes,
+	}
+	for path, item := range doc.Paths {
+		for method, op := range *item {
+			ops.byRoute[strings.ToUpper(method)+" "+path] = op
+		}
+	}
+	return ops
+}
+
+func (o *operations) lookup(c echo.Context) *Operation {
+	return o.byRoute[c.Request().Method+" "+pathParam.ReplaceAllString(c.Path(), "{$1}")]
+}
+
+// Validate checks the parameters and the body of documented routes against the
+// document before the handler runs, failures are errorx.Validation errors.
+// Routes missing from the document are not checked. The document is built on
+// the first request, once every route is registered.
+//
+// Secured routes are only checked once the request is authenticated, so an
+// unauthenticated caller gets the 401 rather than the 422. Use the same
+// middleware on the group and after the route authentication: each request is
+// checked once, by the first one that can.
+func Validate(registry *Registry, cfg ValidationConfig) echo.MiddlewareFunc {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	var (
+		once sync.Once
+		ops  *operations
+	)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			once.Do(func() {
+				ops = newOperations(registry.Document(), cfg.MaxBodyBytes)
+			})
+
+			op := ops.lookup(c)
+			if op == nil || c.Get(validatedKey) != nil {
+				return next(c)
+			}
+			if len(op.Security) > 0 && auth.ResolveSubject(c.Request().Context()) == "" {
+				return next(c)
+			}
+			c.Set(validatedKey, true)
+
+			if err := ops.request(c, op); err != nil {
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					return err
+				}
+				return httpx.Abort(c, err)
+			}
+			if !cfg.Responses {
+				return next(c)
+			}
+			return ops.validateResponse(c, op, next)
+		}
+	}
+}
+
+func (o *operations) request(c echo.Context, op *Operation) error {
+	req := c.Request()
+
+	var fields []errorx.FieldError
+	for _, param := range op.Parameters {
+		var values []string
+		switch param.In {
+		case "path":
+			if value := c.Param(param.Name); value != "" {
+				values = []string{value}
+			}
+		case "query":
+			values = c.QueryParams()[param.Name]
+		case "header":
+			values = req.Header.Values(param.Name)
+		}
+		// handlers read an empty value as a missing one
+		if len(values) == 0 || len(values) == 1 && values[0] == "" {
+			if param.Required {
+				fields = append(fields, fieldError(param.Name, "required", "", "is required"))
+			}
+			continue
+		}
+		fields = append(fields, o.validator.validate(param.Name, param.Schema, coerce(param.Schema, values))...)
+	}
+
+	if op.RequestBody != nil {
+		bodyFields, err := o.body(c, op.RequestBody)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, bodyFields...)
+	}
+
+	if len(fields) > 0 {
+		return errorx.NewValidation(fields)
+	}
+	return nil
+}
+
+// body validates JSON and form bodies. Other content types are left to the
+// handler binding.
+func (o *operations) body(c echo.Context, body *RequestBody) ([]errorx.FieldError, error) {
+	req := c.Request()
+	contentType := mediaType(req.Header.Get(echo.HeaderContentType))
+	media, ok := findMedia(body.Content, contentType)
+	if !ok {
+		return nil, nil
+	}
+
+	switch {
+	case isJSON(contentType):
+		raw, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, o.maxBodyBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, echo.ErrStatusRequestEntityTooLarge
+		}
+		if err != nil {
+			return nil, errorx.Wrap(err, errorx.Invalid)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(raw))
+		if len(bytes.TrimSpace(raw)) == 0 {
+			if body.Required {
+				return nil, errorx.Wrap(errors.New("request body is required"), errorx.Invalid)
+			}
+			return nil, nil
+		}
+
+		value, err := decodeJSON(raw)
+		if err != nil {
+			return nil, errorx.Wrap(fmt.Errorf("invalid JSON body: %w", err), errorx.Invalid)
+		}
+		return o.validator.validate("", media.Schema, value), nil
+
+	case contentType == echo.MIMEApplicationForm:
+		form, err := c.FormParams()
+		if err != nil {
+			return nil, errorx.Wrap(err, errorx.Invalid)
+		}
+		schema := o.validator.resolve(media.Schema)
+		value := map[string]any{}
+		for name, values := range form {
+			if len(values) == 0 || len(values) == 1 && values[0] == "" {
+				continue
+			}
+			if property, ok := schema.Properties[name]; ok {
+				value[name] = coerce(property, values)
+			}
+		}
+		return o.validator.validate("", schema, value), nil
+	}
+	return nil, nil
+}
+
+// validateResponse buffers the response and checks it before writing it.
+func (o *operations) validateResponse(c echo.Context, op *Operation, next echo.HandlerFunc) error {
+	res := c.Response()
+	original := res.Writer
+	buffer := &bufferingWriter{ResponseWriter: original}
+	res.Writer = buffer
+	if err := next(c); err != nil {
+		// render now so the error response is checked too
+		c.Error(err)
+	}
+	res.Writer = original
+
+	status := buffer.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	drift := o.response(c, op, status, buffer.body.Bytes())
+	if drift == "" {
+		if buffer.status != 0 {
+			original.WriteHeader(buffer.status)
+		}
+		_, err := original.Write(buffer.body.Bytes())
+		return err
+	}
+
+	ctx := c.Request().Context()
+	slog.ErrorContext(ctx, "openapi: response does not match the document",
+		"method", c.Request().Method, "route", c.Path(), "status", status, "drift", drift)
+
+	// discard the drifting response and render the error instead
+	res.Committed = false
+	res.Size = 0
+	res.Header().Del(echo.HeaderContentLength)
+	return httpx.Abort(c, errorx.Wrap(fmt.Errorf("response does not match the OpenAPI document: %s", drift), errorx.Other))
+}
+
+// response describes how the response drifts from the document, empty when
+// it matches. Undocumented server errors are accepted.
+func (o *operations) response(c echo.Context, op *Operation, status int, body []byte) string {
+	documented, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if status >= http.StatusInternalServerError {
+			return ""
+		}
+		return fmt.Sprintf("status %d is not documented", status)
+	}
+
+	if len(documented.Content) == 0 || len(body) == 0 || c.Request().Method == http.MethodHead {
+		return ""
+	}
+
+	contentType := mediaType(c.Response().Header().Get(echo.HeaderContentType))
+	media, ok := findMedia(documented.Content, contentType)
+	if !ok {
+		return fmt.Sprintf("content type %q is not documented for status %d", contentType, status)
+	}
+	if !isJSON(contentType) {
+		return ""
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return "invalid JSON body: " + err.Error()
+	}
+	fields := o.validator.validate("", media.Schema, value)
+	descriptions := make([]string, len(fields))
+	for i, field := range fields {
+		path := field.Field
+		if path == "" {
+			path = "body"
+		}
+		descriptions[i] = path + " " + field.Message
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+func findMedia(content map[string]*MediaType, contentType string) (*MediaType, bool) {
+	for documented, media := range content {
+		if mediaType(documented) == contentType {
+			return media, true
+		}
+	}
+	return nil, false
+}
+
+// mediaType drops the parameters, e.g. the charset.
+func mediaType(contentType string) string {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return media
+}
+
+func isJSON(media string) bool {
+	return media == echo.MIMEApplicationJSON || strings.HasSuffix(media, "+json")
+}
+
+// bufferingWriter holds the response until it is validated.
+type bufferingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferingWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
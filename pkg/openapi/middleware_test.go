@@ -0,0 +1,115 @@
+package openapi
+
+import (
+	httpx "api-core/pkg/httpx_echo"
+	"api-core/pkg/jwtx"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type createItemRequest struct {
+	Name string `json:"name" validate:"required,max=5"`
+}
+
+type itemResponse struct {
+	Name string `json:"name"`
+}
+
+type validationServer struct {
+	e     *echo.Echo
+	token string
+	calls int
+}
+
+// newValidationServer mounts a public and a secured route the way the router
+// does: the validator on the group and after the authentication.
+func newValidationServer(t *testing.T, cfg ValidationConfig) *validationServer {
+	t.Helper()
+
+	issuer, err := jwtx.NewHMACIssuer("test-secret", "api-core", time.Hour)
+	if err != nil {
+		t.Fatalf("new issuer: %v", err)
+	}
+	token, err := issuer.Issue("42", "alice@example.com")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	s := &validationServer{e: echo.New(), token: token}
+	docs := NewRegistry(Config{Validation: true})
+	validate := Validate(docs, cfg)
+	authn := httpx.Authn(issuer, nil, nil)
+	authorized := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return authn(validate(next))
+	}
+
+	handler := func(c echo.Context) error {
+		s.calls++
+		return c.JSON(http.StatusCreated, map[string]any{"data": itemResponse{Name: "a"}})
+	}
+	group := s.e.Group("/api/v1", validate)
+	spec := Spec{Request: createItemRequest{}, Response: itemResponse{}, Status: http.StatusCreated}
+	docs.Route(group.POST("/public", handler), spec)
+	spec.Security = []string{SecurityBearer}
+	docs.Route(group.POST("/secured", handler, authorized), spec)
+	return s
+}
+
+func (s *validationServer) post(path, body string, authenticated bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if authenticated {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+s.token)
+	}
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestValidateRequests(t *testing.T) {
+	s := newValidationServer(t, ValidationConfig{})
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		authenticated bool
+		want          int
+	}{
+		{name: "public valid", path: "/api/v1/public", body: `{"name":"a"}`, want: http.StatusCreated},
+		{name: "public invalid", path: "/api/v1/public", body: `{"name":"toolong"}`, want: http.StatusUnprocessableEntity},
+		{name: "public malformed", path: "/api/v1/public", body: `{"name":`, want: http.StatusBadRequest},
+		{name: "secured invalid unauthenticated", path: "/api/v1/secured", body: `{"name":"toolong"}`, want: http.StatusUnauthorized},
+		{name: "secured invalid authenticated", path: "/api/v1/secured", body: `{"name":"toolong"}`, authenticated: true, want: http.StatusUnprocessableEntity},
+		{name: "secured valid", path: "/api/v1/secured", body: `{"name":"a"}`, authenticated: true, want: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.post(tt.path, tt.body, tt.authenticated)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestValidateBodyLimit(t *testing.T) {
+	s := newValidationServer(t, ValidationConfig{MaxBodyBytes: 16})
+
+	rec := s.post("/api/v1/public", `{"name":"`+strings.Repeat("a", 64)+`"}`, false)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413: %s", rec.Code, rec.Body)
+	}
+	if s.calls != 0 {
+		t.Fatalf("calls = %d, want the handler skipped", s.calls)
+	}
+
+	if rec := s.post("/api/v1/public", `{"name":"a"}`, false); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 under the limit: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,397 @@
+package openapi
+
+import (
+	"api-core/pkg/errorx"
+	httpx "api-core/pkg/httpx_echo"
+	"api-core/pkg/pagination"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Security scheme names usable in Spec.Security.
+const (
+	SecurityBearer = "bearerAuth"
+	SecurityBasic  = "basicAuth"
+)
+
+const (
+	mimeJSON    = echo.MIMEApplicationJSON
+	mimeForm    = echo.MIMEApplicationForm
+	mimeProblem = "application/problem+json"
+)
+
+var pathParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// Spec documents one route. Types are given as zero values, e.g.
+// Response: []waitliststore.Entry{}.
+type Spec struct {
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Path, Query and Header are structs whose param, query and header tagged
+	// fields are the parameters. Undocumented path parameters are strings.
+	Path   any
+	Query  any
+	Header any
+	// Request is the JSON body, Form an application/x-www-form-urlencoded one
+	// described by form tags.
+	Request any
+	Form    any
+
+	// Response is the data member of the success envelope. With Raw it is the
+	// whole body, as for the OAuth endpoints.
+	Response any
+	Raw      bool
+	// Paginated adds the pagination member of httpx.RestPage to the envelope.
+	Paginated bool
+	// ContentType of a raw response, application/json by default.
+	ContentType string
+	// Status of the success response, 200 by default.
+	Status int
+	// Cached documents the response cache of the route: the If-None-Match
+	// header, the ETag of the success response and the 304.
+	Cached bool
+
+	// Errors lists the error kinds the route returns, on top of the ones
+	// every route can return.
+	Errors []errorx.Kind
+	// ErrorBody replaces the error envelope, e.g. with the RFC 6749 error.
+	ErrorBody any
+
+	Security []string
+}
+
+// Config describes the API. ProblemDetails documents RFC 7807 error
+// responses instead of the envelope.
+type Config struct {
+	Info           Info
+	Servers        []Server
+	ProblemDetails bool
+	// Errors are returned by every route, e.g. rate limiting.
+	Errors []errorx.Kind
+	// Validation documents the errorx.Validation errors of the Validate
+	// middleware on routes with parameters or a body.
+	Validation bool
+}
+
+type route struct {
+	method string
+	path   string
+	name   string
+	spec   Spec
+}
+
+// Registry collects the annotated routes.
+type Registry struct {
+	cfg Config
+
+	mu     sync.Mutex
+	routes []route
+}
+
+func NewRegistry(cfg Config) *Registry {
+	return &Registry{cfg: cfg}
+}
+
+// Route documents an echo route and returns it, so it wraps the registration:
+//
+//	docs.Route(group.GET("/users", h.List), openapi.Spec{...})
+func (r *Registry) Route(rt *echo.Route, spec Spec) *echo.Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route{method: rt.Method, path: rt.Path, name: rt.Name, spec: spec})
+	return rt
+}
+
+// Document builds the OpenAPI document of the registered routes.
+func (r *Registry) Document() *Document {
+	r.mu.Lock()
+	routes := slices.Clone(r.routes)
+	r.mu.Unlock()
+
+	b := &builder{cfg: r.cfg, schemas: newSchemas()}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    r.cfg.Info,
+		Servers: r.cfg.Servers,
+		Paths:   map[string]*PathItem{},
+	}
+
+	for _, rt := range routes {
+		path := pathParam.ReplaceAllString(rt.path, "{$1}")
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(rt.method)] = b.operation(rt)
+	}
+
+	doc.Components = Components{
+		Schemas: b.schemas.components,
+		SecuritySchemes: map[string]*SecurityScheme{
+			SecurityBearer: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			SecurityBasic:  {Type: "http", Scheme: "basic", Description: "OAuth client credentials"},
+		},
+	}
+	return doc
+}
+
+type builder struct {
+	cfg     Config
+	schemas *schemas
+}
+
+func (b *builder) operation(rt route) *Operation {
+	spec := rt.spec
+	op := &Operation{
+		OperationID: operationID(rt),
+		Summary:     spec.Summary,
+		Description: spec.Description,
+		Tags:        spec.Tags,
+		Responses:   map[string]*Response{},
+	}
+
+	op.Parameters = append(op.Parameters, b.pathParameters(rt.path, spec.Path)...)
+	op.Parameters = append(op.Parameters, b.parameters(spec.Query, "query")...)
+	op.Parameters = append(op.Parameters, b.parameters(spec.Header, "header")...)
+	if spec.Cached {
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        httpx.HeaderIfNoneMatch,
+			In:          "header",
+			Description: "ETag of a previous response, answered with a 304 while it is current.",
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+
+	switch {
+	case spec.Request != nil:
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{
+			mimeJSON: {Schema: b.schemas.of(spec.Request)},
+		}}
+	case spec.Form != nil:
+		form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		b.schemas.fields(reflect.TypeOf(spec.Form), "form", form)
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{
+			mimeForm: {Schema: form},
+		}}
+	}
+
+	for _, name := range spec.Security {
+		op.Security = append(op.Security, map[string][]string{name: {}})
+	}
+
+	status := spec.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = b.success(spec)
+	if spec.Cached {
+		etag := map[string]*Header{httpx.HeaderETag: {Description: "Strong ETag of the body.", Schema: &Schema{Type: "string"}}}
+		op.Responses[strconv.Itoa(status)].Headers = etag
+		op.Responses[strconv.Itoa(http.StatusNotModified)] = &Response{Description: http.StatusText(http.StatusNotModified), Headers: etag}
+	}
+
+	// errors of the middlewares every route runs use the envelope, even when
+	// the route renders its own errors
+	global := slices.Clone(b.cfg.Errors)
+	if b.cfg.Validation && (len(op.Parameters) > 0 || op.RequestBody != nil) {
+		global = append(global, errorx.Validation)
+	}
+	for code, response := range b.errors(global, nil) {
+		op.Responses[code] = response
+	}
+
+	kinds := slices.Clone(spec.Errors)
+	if len(spec.Security) > 0 {
+		kinds = append(kinds, errorx.Authn)
+	}
+	if spec.Request != nil || spec.Query != nil {
+		// JSON bodies and query parameters go through httpx.BindAndValidate
+		kinds = append(kinds, errorx.Invalid, errorx.Validation)
+	}
+	for code, response := range b.errors(kinds, spec.ErrorBody) {
+		op.Responses[code] = response
+	}
+	return op
+}
+
+func operationID(rt route) string {
+	// echo names routes after the handler, e.g. api-core/internal/handler/auth.(*Handler).GoogleLogin-fm
+	name := strings.TrimSuffix(rt.name, "-fm")
+	name = name[strings.LastIndex(name, ".")+1:]
+	if name == "" || strings.ContainsAny(name, "/(") || strings.HasPrefix(name, "func") {
+		return ""
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func (b *builder) pathParameters(path string, params any) []*Parameter {
+	documented := map[string]*Parameter{}
+	for _, p := range b.parameters(params, "path") {
+		documented[p.Name] = p
+	}
+
+	var result []*Parameter
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		p, ok := documented[match[1]]
+		if !ok {
+			p = &Parameter{Name: match[1], In: "path", Schema: &Schema{Type: "string"}}
+		}
+		p.Required = true
+		result = append(result, p)
+	}
+	return result
+}
+
+// parameters reads the param (path), query or header tagged fields of v.
+func (b *builder) parameters(v any, in string) []*Parameter {
+	if v == nil {
+		return nil
+	}
+	tag := in
+	if in == "path" {
+		tag = "param"
+	}
+
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.schemas.fields(reflect.TypeOf(v), tag, object)
+
+	var params []*Parameter
+	for name, schema := range object.Properties {
+		params = append(params, &Parameter{
+			Name:        name,
+			In:          in,
+			Description: schema.Description,
+			Required:    isRequiredParam(v, name, tag),
+			Schema:      schema,
+		})
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	return params
+}
+
+// isRequiredParam only trusts the validate tag, parameters are optional by
+// default unlike JSON fields.
+func isRequiredParam(v any, name, tag string) bool {
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if fieldTagName(field, tag) == name {
+			return slices.Contains(strings.Split(field.Tag.Get("validate"), ","), "required")
+		}
+	}
+	return false
+}
+
+func fieldTagName(field reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func (b *builder) success(spec Spec) *Response {
+	response := &Response{Description: http.StatusText(max(spec.Status, http.StatusOK))}
+	if spec.Response == nil {
+		return response
+	}
+
+	schema := b.schemas.of(spec.Response)
+	contentType := spec.ContentType
+	if contentType == "" {
+		contentType = mimeJSON
+	}
+	if !spec.Raw {
+		schema = &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"data": schema},
+			Required:   []string{"data"},
+		}
+		if spec.Paginated {
+			schema.Properties["pagination"] = b.schemas.of(pagination.Pagination{})
+			schema.Required = append(schema.Required, "pagination")
+		}
+	}
+	response.Content = map[string]*MediaType{contentType: {Schema: schema}}
+	return response
+}
+
+// errors groups the kinds by status, each response lists its error codes. A
+// non nil body replaces the error envelope.
+func (b *builder) errors(kinds []errorx.Kind, body any) map[string]*Response {
+	codes := map[int][]any{}
+	for _, kind := range kinds {
+		status := kind.HTTPStatus()
+		if !slices.Contains(codes[status], any(kind.String())) {
+			codes[status] = append(codes[status], kind.String())
+		}
+	}
+
+	responses := map[string]*Response{}
+	for status, enum := range codes {
+		response := &Response{Description: http.StatusText(status)}
+		switch {
+		case body != nil:
+			response.Content = map[string]*MediaType{mimeJSON: {Schema: b.schemas.of(body)}}
+		case b.cfg.ProblemDetails:
+			response.Content = map[string]*MediaType{mimeProblem: {Schema: b.errorSchema("Problem", b.problemSchema, enum)}}
+		default:
+			response.Content = map[string]*MediaType{mimeJSON: {Schema: b.errorSchema("Error", b.envelopeSchema, enum)}}
+		}
+		responses[strconv.Itoa(status)] = response
+	}
+	return responses
+}
+
+// errorSchema references the named error component and narrows its code.
+func (b *builder) errorSchema(name string, build func() *Schema, codes []any) *Schema {
+	if _, ok := b.schemas.components[name]; !ok {
+		b.schemas.components[name] = build()
+	}
+	return &Schema{AllOf: []*Schema{
+		{Ref: "#/components/schemas/" + name},
+		{Type: "object", Properties: map[string]*Schema{"code": {Type: "string", Enum: codes}}},
+	}}
+}
+
+// envelopeSchema mirrors the httpx error envelope.
+func (b *builder) envelopeSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":       {Type: "string"},
+			"message":    {Type: "string"},
+			"details":    {Type: "array", Items: b.schemas.of(errorx.FieldError{})},
+			"request_id": {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
+}
+
+// problemSchema mirrors httpx.Problem with its extension members.
+func (b *builder) problemSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type":     {Type: "string", Format: "uri-reference"},
+			"title":    {Type: "string"},
+			"status":   {Type: "integer", Format: "int32"},
+			"detail":   {Type: "string"},
+			"instance": {Type: "string"},
+			"code":     {Type: "string"},
+			"errors":   {Type: "array", Items: b.schemas.of(errorx.FieldError{})},
+		},
+		Required: []string{"type", "title", "status", "code"},
+	}
+}
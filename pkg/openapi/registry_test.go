@@ -0,0 +1,232 @@
+package openapi
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"testing"
+
+	"api-core/pkg/errorx"
+	httpx "api-core/pkg/httpx_echo"
+
+	"github.com/labstack/echo/v4"
+)
+
+type registryHandler struct{}
+
+func (registryHandler) GetUser(echo.Context) error      { return nil }
+func (registryHandler) ListUsers(echo.Context) error    { return nil }
+func (registryHandler) CreateUser(echo.Context) error   { return nil }
+func (registryHandler) IssueToken(echo.Context) error   { return nil }
+func (registryHandler) DeleteMember(echo.Context) error { return nil }
+
+type registryUserPath struct {
+	ID int64 `param:"id" validate:"required"`
+}
+
+type registryUserQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,max=100"`
+	Cursor string `query:"cursor"`
+	Email  string `query:"email" validate:"required,email"`
+}
+
+type registryUser struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+type registryCreateUser struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type registryTokenForm struct {
+	GrantType string `form:"grant_type" validate:"required,oneof=client_credentials"`
+	Scope     string `form:"scope,omitempty"`
+}
+
+type registryTokenError struct {
+	Error string `json:"error"`
+}
+
+func responseCodes(op *Operation) []string {
+	var codes []string
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func TestRegistryRoute(t *testing.T) {
+	e := echo.New()
+	h := registryHandler{}
+	r := NewRegistry(Config{
+		Info:       Info{Title: "API", Version: "1"},
+		Errors:     []errorx.Kind{errorx.RateLimiting},
+		Validation: true,
+	})
+
+	g := e.Group("/v1")
+	route := g.GET("/users/:id", h.GetUser)
+	if got := r.Route(route, Spec{Path: registryUserPath{}, Response: registryUser{}, Security: []string{SecurityBearer}, Errors: []errorx.Kind{errorx.NotExist}, Cached: true}); got != route {
+		t.Fatalf("route = %v, want the registered route", got)
+	}
+	r.Route(g.DELETE("/users/:id/members/:member", h.DeleteMember), Spec{Status: http.StatusNoContent})
+	r.Route(g.GET("/users", h.ListUsers), Spec{Query: registryUserQuery{}, Response: []registryUser{}, Paginated: true})
+	r.Route(g.POST("/users", h.CreateUser), Spec{Request: registryCreateUser{}, Response: registryUser{}, Status: http.StatusCreated})
+	r.Route(e.POST("/oauth/token", h.IssueToken), Spec{Form: registryTokenForm{}, Response: map[string]any{}, Raw: true, ErrorBody: registryTokenError{}, Security: []string{SecurityBasic}})
+	r.Route(e.GET("/health", func(echo.Context) error { return nil }), Spec{})
+
+	doc := r.Document()
+	if doc.OpenAPI != Version || doc.Info.Title != "API" {
+		t.Fatalf("document = %s %+v", doc.OpenAPI, doc.Info)
+	}
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if want := []string{"/health", "/oauth/token", "/v1/users", "/v1/users/{id}", "/v1/users/{id}/members/{member}"}; !slices.Equal(paths, want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	t.Run("path parameters", func(t *testing.T) {
+		op := (*doc.Paths["/v1/users/{id}"])["get"]
+		if op.OperationID != "getUser" || len(op.Parameters) != 2 {
+			t.Fatalf("operation = %s", marshal(t, op))
+		}
+		if got := marshal(t, op.Parameters[0]); got != `{"name":"id","in":"path","required":true,"schema":{"type":"integer","format":"int64"}}` {
+			t.Fatalf("id = %s", got)
+		}
+		if op.Parameters[1].Name != httpx.HeaderIfNoneMatch || op.Parameters[1].In != "header" {
+			t.Fatalf("parameter = %s, want If-None-Match", marshal(t, op.Parameters[1]))
+		}
+		// undocumented path parameters are required strings
+		members := (*doc.Paths["/v1/users/{id}/members/{member}"])["delete"]
+		if got := marshal(t, members.Parameters); got != `[{"name":"id","in":"path","required":true,"schema":{"type":"string"}},{"name":"member","in":"path","required":true,"schema":{"type":"string"}}]` {
+			t.Fatalf("parameters = %s", got)
+		}
+		if codes := responseCodes(members); !slices.Equal(codes, []string{"204", "422", "429"}) {
+			t.Fatalf("responses = %v", codes)
+		}
+	})
+
+	t.Run("success and errors", func(t *testing.T) {
+		op := (*doc.Paths["/v1/users/{id}"])["get"]
+		if codes := responseCodes(op); !slices.Equal(codes, []string{"200", "304", "401", "404", "422", "429"}) {
+			t.Fatalf("responses = %v", codes)
+		}
+		if got := marshal(t, op.Responses["200"].Content[mimeJSON].Schema); got != `{"type":"object","properties":{"data":{"$ref":"#/components/schemas/RegistryUser"}},"required":["data"]}` {
+			t.Fatalf("success = %s", got)
+		}
+		if op.Responses["200"].Headers[httpx.HeaderETag] == nil {
+			t.Fatal("success without the ETag header")
+		}
+		if got := marshal(t, op.Responses["404"].Content[mimeJSON].Schema); got != `{"allOf":[{"$ref":"#/components/schemas/Error"},{"type":"object","properties":{"code":{"type":"string","enum":["resource-not-found"]}}}]}` {
+			t.Fatalf("not found = %s", got)
+		}
+		if got := marshal(t, op.Security); got != `[{"bearerAuth":[]}]` {
+			t.Fatalf("security = %s", got)
+		}
+	})
+
+	t.Run("query and pagination", func(t *testing.T) {
+		op := (*doc.Paths["/v1/users"])["get"]
+		if op.OperationID != "listUsers" {
+			t.Fatalf("operation id = %s", op.OperationID)
+		}
+		want := `[{"name":"cursor","in":"query","schema":{"type":"string"}},{"name":"email","in":"query","required":true,"schema":{"type":"string","format":"email"}},{"name":"limit","in":"query","schema":{"type":"integer","format":"int32","maximum":100}}]`
+		if got := marshal(t, op.Parameters); got != want {
+			t.Fatalf("parameters = %s, want %s", got, want)
+		}
+		schema := op.Responses["200"].Content[mimeJSON].Schema
+		if got := marshal(t, schema.Required); got != `["data","pagination"]` || schema.Properties["pagination"].Ref != "#/components/schemas/Pagination" {
+			t.Fatalf("envelope = %s", marshal(t, schema))
+		}
+		// query parameters are bound and validated by the handler
+		if codes := responseCodes(op); !slices.Equal(codes, []string{"200", "400", "422", "429"}) {
+			t.Fatalf("responses = %v", codes)
+		}
+	})
+
+	t.Run("json body", func(t *testing.T) {
+		op := (*doc.Paths["/v1/users"])["post"]
+		if got := marshal(t, op.RequestBody); got != `{"required":true,"content":{"application/json":{"schema":{"$ref":"#/components/schemas/RegistryCreateUser"}}}}` {
+			t.Fatalf("request body = %s", got)
+		}
+		if op.Responses["201"] == nil || op.Responses["200"] != nil {
+			t.Fatalf("responses = %v, want 201", responseCodes(op))
+		}
+	})
+
+	t.Run("form body and raw response", func(t *testing.T) {
+		op := (*doc.Paths["/oauth/token"])["post"]
+		if got := marshal(t, op.RequestBody); got != `{"required":true,"content":{"application/x-www-form-urlencoded":{"schema":{"type":"object","properties":{"grant_type":{"type":"string","enum":["client_credentials"]},"scope":{"type":"string"}},"required":["grant_type"]}}}}` {
+			t.Fatalf("request body = %s", got)
+		}
+		if got := marshal(t, op.Responses["200"].Content[mimeJSON].Schema); got != `{"type":"object","additionalProperties":{}}` {
+			t.Fatalf("raw success = %s", got)
+		}
+		// the route renders its errors, the rate limiter keeps the envelope
+		if got := op.Responses["401"].Content[mimeJSON].Schema.Ref; got != "#/components/schemas/RegistryTokenError" {
+			t.Fatalf("authentication error = %s", marshal(t, op.Responses["401"]))
+		}
+		if got := op.Responses["429"].Content[mimeJSON].Schema.AllOf; len(got) == 0 || got[0].Ref != "#/components/schemas/Error" {
+			t.Fatalf("rate limiting error = %s", marshal(t, op.Responses["429"]))
+		}
+	})
+
+	t.Run("anonymous handler", func(t *testing.T) {
+		op := (*doc.Paths["/health"])["get"]
+		if op.OperationID != "" || len(op.Parameters) != 0 {
+			t.Fatalf("operation = %s", marshal(t, op))
+		}
+		// no parameters or body, nothing to validate
+		if codes := responseCodes(op); !slices.Equal(codes, []string{"200", "429"}) {
+			t.Fatalf("responses = %v", codes)
+		}
+	})
+
+	for _, name := range []string{"Error", "RegistryUser", "RegistryCreateUser", "RegistryTokenError", "Pagination", "FieldError"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Fatalf("components = %s, want %s", marshal(t, doc.Components.Schemas), name)
+		}
+	}
+	if doc.Components.SecuritySchemes[SecurityBearer] == nil || doc.Components.SecuritySchemes[SecurityBasic] == nil {
+		t.Fatalf("security schemes = %s", marshal(t, doc.Components.SecuritySchemes))
+	}
+}
+
+func TestRegistryProblemDetails(t *testing.T) {
+	e := echo.New()
+	r := NewRegistry(Config{ProblemDetails: true})
+	r.Route(e.GET("/users", registryHandler{}.ListUsers), Spec{Errors: []errorx.Kind{errorx.Authz, errorx.Service}})
+
+	op := (*r.Document().Paths["/users"])["get"]
+	forbidden := op.Responses["403"].Content[mimeProblem]
+	if forbidden == nil || forbidden.Schema.AllOf[0].Ref != "#/components/schemas/Problem" {
+		t.Fatalf("forbidden = %s, want a problem", marshal(t, op.Responses["403"]))
+	}
+	if codes := responseCodes(op); !slices.Equal(codes, []string{"200", "403", "500"}) {
+		t.Fatalf("responses = %v", codes)
+	}
+}
+
+func TestOperationID(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "api-core/internal/handler/auth.(*Handler).GoogleLogin-fm", want: "googleLogin"},
+		{name: "api-core/internal/handler/health.Live", want: "live"},
+		{name: "api-core/internal/handler/health.New.func1", want: ""},
+		{name: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := operationID(route{name: tt.name}); got != tt.want {
+				t.Fatalf("operationID(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	nameSanitizer  = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// validatePatterns document the custom validator tags registered by httpx.
+var validatePatterns = map[string]string{
+	"e164": `^\+[1-9]\d{1,14}$`,
+	"slug": `^[a-z0-9]+(?:-[a-z0-9]+)*$`,
+}
+
+// schemas reflects Go types, named structs are stored as components and
+// referenced.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// of returns the schema of v's type, nil for a nil v.
+func (s *schemas) of(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem := s.schema(t.Elem())
+		if typ, ok := elem.Type.(string); ok && elem.Ref == "" {
+			elem.Type = []string{typ, "null"}
+		}
+		return elem
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		if t == reflect.TypeOf(time.Duration(0)) {
+			return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+		}
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.ref(t)
+	}
+	// interfaces accept any value
+	return &Schema{}
+}
+
+func (s *schemas) ref(t reflect.Type) *Schema {
+	name, ok := s.names[t]
+	if !ok {
+		name = s.componentName(t)
+		s.names[t] = name
+		// registered before reflecting the fields so recursive types terminate
+		s.components[name] = &Schema{}
+		*s.components[name] = *s.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName is the exported type name, prefixed by the package when two
+// packages declare the same name.
+func (s *schemas) componentName(t reflect.Type) string {
+	name := exportedName(t.Name())
+	if _, taken := s.components[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	return exportedName(pkg) + name
+}
+
+func exportedName(name string) string {
+	name = strings.Trim(nameSanitizer.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.fields(t, "json", object)
+	return object
+}
+
+// fields adds the properties named by tag, embedded structs are flattened.
+func (s *schemas) fields(t reflect.Type, tag string, object *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, ok := fieldName(field, tag)
+		if !ok {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.fields(embedded, tag, object)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := s.schema(field.Type)
+		required := applyValidate(schema, field)
+		if required || (!omitempty && field.Type.Kind() != reflect.Pointer) {
+			object.Required = append(object.Required, name)
+		}
+		object.Properties[name] = schema
+	}
+}
+
+// fieldName reads the tag name, ok is false for skipped fields.
+func fieldName(field reflect.StructField, tag string) (name string, omitempty bool, ok bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", false, false
+	}
+	value, found := field.Tag.Lookup(tag)
+	if !found {
+		return "", false, field.IsExported() || field.Anonymous
+	}
+	name, opts, _ := strings.Cut(value, ",")
+	if name == "-" {
+		return "", false, false
+	}
+	return name, strings.Contains(opts, "omitempty"), true
+}
+
+// applyValidate maps the validate tag rules to schema constraints and reports
+// whether the field is required.
+func applyValidate(schema *Schema, field reflect.StructField) bool {
+	required := false
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "url", "http_url":
+			schema.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, enumValue(schema, v))
+			}
+			// JSON Schema checks the enum of nullable values against null too
+			if _, nullable := schema.Type.([]string); nullable {
+				schema.Enum = append(schema.Enum, nil)
+			}
+		case "min", "gte":
+			setBound(schema, param, true)
+		case "max", "lte":
+			setBound(schema, param, false)
+		default:
+			if pattern, ok := validatePatterns[name]; ok {
+				schema.Pattern = pattern
+			}
+		}
+	}
+	return required
+}
+
+// baseType is the type of the schema, without the null of pointers.
+func baseType(schema *Schema) string {
+	switch typ := schema.Type.(type) {
+	case string:
+		return typ
+	case []string:
+		return typ[0]
+	}
+	return ""
+}
+
+// enumValue keeps the oneof values of numbers numeric.
+func enumValue(schema *Schema, v string) any {
+	switch baseType(schema) {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+func setBound(schema *Schema, param string, lower bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	length := int(n)
+	switch baseType(schema) {
+	case "string":
+		if lower {
+			schema.MinLength = &length
+		} else {
+			schema.MaxLength = &length
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &length
+		} else {
+			schema.MaxItems = &length
+		}
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"api-core/pkg/errorx"
+)
+
+type schemaAddress struct {
+	City string `json:"city"`
+}
+
+type schemaBase struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type schemaNode struct {
+	Name     string        `json:"name"`
+	Children []*schemaNode `json:"children,omitempty"`
+}
+
+type schemaUser struct {
+	schemaBase
+	Email    string          `json:"email" validate:"required,email"`
+	Name     *string         `json:"name,omitempty" validate:"omitempty,min=1,max=64"`
+	Role     string          `json:"role" validate:"oneof=admin member"`
+	Level    int             `json:"level,omitempty" validate:"oneof=1 2 3"`
+	Tags     []string        `json:"tags,omitempty" validate:"max=10"`
+	Address  *schemaAddress  `json:"address,omitempty"`
+	Deleted  *time.Time      `json:"deleted_at"`
+	Labels   map[string]int  `json:"labels,omitempty"`
+	Avatar   []byte          `json:"avatar,omitempty"`
+	Extra    json.RawMessage `json:"extra,omitempty"`
+	Timeout  time.Duration   `json:"timeout,omitempty"`
+	Phone    string          `json:"phone,omitempty" validate:"omitempty,e164"`
+	Password string          `json:"-"`
+	internal string
+}
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(data)
+}
+
+func TestSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{name: "nil", v: nil, want: `null`},
+		{name: "bool", v: true, want: `{"type":"boolean"}`},
+		{name: "int", v: 0, want: `{"type":"integer","format":"int32"}`},
+		{name: "int64", v: int64(0), want: `{"type":"integer","format":"int64"}`},
+		{name: "float", v: 0.5, want: `{"type":"number"}`},
+		{name: "duration", v: time.Duration(0), want: `{"type":"integer","format":"int64","description":"nanoseconds"}`},
+		{name: "time", v: time.Time{}, want: `{"type":"string","format":"date-time"}`},
+		{name: "bytes", v: []byte{}, want: `{"type":"string","format":"byte"}`},
+		{name: "raw message", v: json.RawMessage{}, want: `{}`},
+		{name: "slice", v: []string{}, want: `{"type":"array","items":{"type":"string"}}`},
+		{name: "array", v: [2]int{}, want: `{"type":"array","items":{"type":"integer","format":"int32"}}`},
+		{name: "map", v: map[string]bool{}, want: `{"type":"object","additionalProperties":{"type":"boolean"}}`},
+		{name: "pointer", v: new(string), want: `{"type":["string","null"]}`},
+		{name: "pointer to time", v: new(time.Time), want: `{"type":["string","null"],"format":"date-time"}`},
+		// a reference stays a reference
+		{name: "pointer to struct", v: &schemaAddress{}, want: `{"$ref":"#/components/schemas/SchemaAddress"}`},
+		{name: "slice of structs", v: []schemaAddress{}, want: `{"type":"array","items":{"$ref":"#/components/schemas/SchemaAddress"}}`},
+		{name: "anonymous struct", v: struct {
+			Count int `json:"count"`
+		}{}, want: `{"type":"object","properties":{"count":{"type":"integer","format":"int32"}},"required":["count"]}`},
+		{name: "interface", v: []any{}, want: `{"type":"array","items":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := marshal(t, newSchemas().of(tt.v)); got != tt.want {
+				t.Fatalf("schema = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaStructTags(t *testing.T) {
+	s := newSchemas()
+	if got := marshal(t, s.of(schemaUser{})); got != `{"$ref":"#/components/schemas/SchemaUser"}` {
+		t.Fatalf("schema = %s, want a reference", got)
+	}
+
+	user := s.components["SchemaUser"]
+	// embedded fields are flattened, unexported and json:"-" ones skipped
+	if len(user.Properties) != 14 {
+		t.Fatalf("properties = %s", marshal(t, user.Properties))
+	}
+	for _, name := range []string{"Password", "internal", "-", "schemaBase"} {
+		if _, ok := user.Properties[name]; ok {
+			t.Fatalf("property %s documented", name)
+		}
+	}
+	// required are validate required and non pointer fields without omitempty
+	if got := marshal(t, user.Required); got != `["id","created_at","email","role"]` {
+		t.Fatalf("required = %s", got)
+	}
+
+	for name, want := range map[string]string{
+		"id":         `{"type":"integer","format":"int64"}`,
+		"email":      `{"type":"string","format":"email"}`,
+		"name":       `{"type":["string","null"],"minLength":1,"maxLength":64}`,
+		"role":       `{"type":"string","enum":["admin","member"]}`,
+		"level":      `{"type":"integer","format":"int32","enum":[1,2,3]}`,
+		"tags":       `{"type":"array","items":{"type":"string"},"maxItems":10}`,
+		"address":    `{"$ref":"#/components/schemas/SchemaAddress"}`,
+		"deleted_at": `{"type":["string","null"],"format":"date-time"}`,
+		"labels":     `{"type":"object","additionalProperties":{"type":"integer","format":"int32"}}`,
+		"phone":      `{"type":"string","pattern":"^\\+[1-9]\\d{1,14}$"}`,
+	} {
+		if got := marshal(t, user.Properties[name]); got != want {
+			t.Fatalf("%s = %s, want %s", name, got, want)
+		}
+	}
+	if got := marshal(t, s.components["SchemaAddress"]); got != `{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}` {
+		t.Fatalf("address = %s", got)
+	}
+}
+
+func TestSchemaRecursive(t *testing.T) {
+	s := newSchemas()
+	s.of(schemaNode{})
+
+	want := `{"type":"object","properties":{"children":{"type":"array","items":{"$ref":"#/components/schemas/SchemaNode"}},"name":{"type":"string"}},"required":["name"]}`
+	if got := marshal(t, s.components["SchemaNode"]); got != want {
+		t.Fatalf("node = %s, want %s", got, want)
+	}
+}
+
+func TestValidateBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{name: "string length", v: struct {
+			V string `json:"v" validate:"min=2,max=8"`
+		}{}, want: `{"type":"string","minLength":2,"maxLength":8}`},
+		{name: "number range", v: struct {
+			V float64 `json:"v" validate:"gte=0.5,lte=1"`
+		}{}, want: `{"type":"number","minimum":0.5,"maximum":1}`},
+		{name: "nullable integer", v: struct {
+			V *int `json:"v" validate:"omitempty,min=1"`
+		}{}, want: `{"type":["integer","null"],"format":"int32","minimum":1}`},
+		{name: "nullable enum", v: struct {
+			V *string `json:"v" validate:"omitempty,oneof=asc desc"`
+		}{}, want: `{"type":["string","null"],"enum":["asc","desc",null]}`},
+		{name: "uuid", v: struct {
+			V string `json:"v" validate:"uuid4"`
+		}{}, want: `{"type":"string","format":"uuid"}`},
+		{name: "url", v: struct {
+			V string `json:"v" validate:"http_url"`
+		}{}, want: `{"type":"string","format":"uri"}`},
+		{name: "unparsable bound", v: struct {
+			V string `json:"v" validate:"min=abc"`
+		}{}, want: `{"type":"string"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := marshal(t, newSchemas().of(tt.v).Properties["v"]); got != tt.want {
+				t.Fatalf("schema = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNullableEnumValidation(t *testing.T) {
+	s := newSchemas()
+	schema := s.of(struct {
+		V *string `json:"v" validate:"omitempty,oneof=asc desc"`
+	}{})
+	v := newSchemaValidator(s.components)
+
+	for _, body := range []string{`{"v":null}`, `{"v":"asc"}`} {
+		value, _ := decodeJSON([]byte(body))
+		if fields := v.validate("", schema, value); len(fields) != 0 {
+			t.Fatalf("%s: fields = %v, want none", body, fields)
+		}
+	}
+	value, _ := decodeJSON([]byte(`{"v":"up"}`))
+	fields := v.validate("", schema, value)
+	if len(fields) != 1 || fields[0].Param != "asc desc" {
+		t.Fatalf("fields = %v, want a oneof failure without null", fields)
+	}
+}
+
+// FieldError collides with errorx.FieldError.
+type FieldError struct{}
+
+func TestComponentName(t *testing.T) {
+	s := newSchemas()
+	s.of(FieldError{})
+	s.of(errorx.FieldError{})
+
+	for _, name := range []string{"FieldError", "ErrorxFieldError"} {
+		if _, ok := s.components[name]; !ok {
+			t.Fatalf("components = %s, want %s", marshal(t, s.components), name)
+		}
+	}
+	if got := exportedName("api-core.user_v2"); got != "Api_core_user_v2" {
+		t.Fatalf("exportedName = %s", got)
+	}
+}
@@ -0,0 +1,288 @@
+package openapi
+
+import (
+	"api-core/pkg/errorx"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// schemaValidator checks decoded JSON values against the schemas of a
+// document. Failures use the rule names of the validate tags, so they read
+// like the handler validation errors.
+type schemaValidator struct {
+	components map[string]*Schema
+	// patterns caches the compiled schema patterns, requests are validated
+	// concurrently.
+	patterns sync.Map
+}
+
+func newSchemaValidator(components map[string]*Schema) *schemaValidator {
+	return &schemaValidator{components: components}
+}
+
+// decodeJSON decodes numbers as json.Number so integers keep their precision.
+func decodeJSON(raw []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+func (v *schemaValidator) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = v.components[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// validate returns the failures of value, path is the JSON path of value.
+func (v *schemaValidator) validate(path string, schema *Schema, value any) []errorx.FieldError {
+	schema = v.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	var fields []errorx.FieldError
+	for _, sub := range schema.AllOf {
+		fields = append(fields, v.validate(path, sub, value)...)
+	}
+
+	types := schemaTypes(schema)
+	if len(types) > 0 && !matchesType(types, value) {
+		return append(fields, fieldError(path, "type", "", "must be "+typeDescription(types)))
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		var enum []string
+		for _, e := range schema.Enum {
+			if e != nil {
+				enum = append(enum, fmt.Sprint(e))
+			}
+		}
+		return append(fields, fieldError(path, "oneof", strings.Join(enum, " "), "must be one of: "+strings.Join(enum, ", ")))
+	}
+
+	switch value := value.(type) {
+	case string:
+		fields = append(fields, v.validateString(path, schema, value)...)
+	case json.Number:
+		fields = append(fields, validateNumber(path, schema, value)...)
+	case []any:
+		if schema.MinItems != nil && len(value) < *schema.MinItems {
+			fields = append(fields, fieldError(path, "min", strconv.Itoa(*schema.MinItems), fmt.Sprintf("must contain at least %d items", *schema.MinItems)))
+		}
+		if schema.MaxItems != nil && len(value) > *schema.MaxItems {
+			fields = append(fields, fieldError(path, "max", strconv.Itoa(*schema.MaxItems), fmt.Sprintf("must contain at most %d items", *schema.MaxItems)))
+		}
+		for i, item := range value {
+			fields = append(fields, v.validate(fmt.Sprintf("%s[%d]", path, i), schema.Items, item)...)
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				fields = append(fields, fieldError(joinPath(path, name), "required", "", "is required"))
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			fields = append(fields, v.validate(joinPath(path, name), property, value[name])...)
+		}
+	}
+	return fields
+}
+
+func (v *schemaValidator) validateString(path string, schema *Schema, value string) []errorx.FieldError {
+	var fields []errorx.FieldError
+	length := utf8.RuneCountInString(value)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		fields = append(fields, fieldError(path, "min", strconv.Itoa(*schema.MinLength), fmt.Sprintf("must contain at least %d characters", *schema.MinLength)))
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		fields = append(fields, fieldError(path, "max", strconv.Itoa(*schema.MaxLength), fmt.Sprintf("must contain at most %d characters", *schema.MaxLength)))
+	}
+	if schema.Pattern != "" {
+		pattern, ok := v.patterns.Load(schema.Pattern)
+		if !ok {
+			pattern, _ = v.patterns.LoadOrStore(schema.Pattern, regexp.MustCompile(schema.Pattern))
+		}
+		if !pattern.(*regexp.Regexp).MatchString(value) {
+			fields = append(fields, fieldError(path, "pattern", schema.Pattern, "must match "+schema.Pattern))
+		}
+	}
+	if rule, message, ok := validateFormat(schema.Format, value); !ok {
+		fields = append(fields, fieldError(path, rule, "", message))
+	}
+	return fields
+}
+
+// validateFormat checks the string formats emitted by the generator, unknown
+// formats are annotations only.
+func validateFormat(format, value string) (rule, message string, ok bool) {
+	switch format {
+	case "email":
+		addr, err := mail.ParseAddress(value)
+		return "email", "must be a valid email address", err == nil && addr.Address == value
+	case "uuid":
+		return "uuid", "must be a valid UUID", uuidPattern.MatchString(value)
+	case "uri":
+		u, err := url.Parse(value)
+		return "url", "must be a valid URL", err == nil && u.Scheme != "" && u.Host != ""
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return "datetime", "must be an RFC 3339 date-time", err == nil
+	case "byte":
+		_, err := base64.StdEncoding.DecodeString(value)
+		return "base64", "must be base64 encoded", err == nil
+	}
+	return "", "", true
+}
+
+func validateNumber(path string, schema *Schema, value json.Number) []errorx.FieldError {
+	n, err := value.Float64()
+	if err != nil {
+		return []errorx.FieldError{fieldError(path, "type", "", "must be a number")}
+	}
+
+	var fields []errorx.FieldError
+	if schema.Minimum != nil && n < *schema.Minimum {
+		param := strconv.FormatFloat(*schema.Minimum, 'f', -1, 64)
+		fields = append(fields, fieldError(path, "min", param, "must be at least "+param))
+	}
+	if schema.Maximum != nil && n > *schema.Maximum {
+		param := strconv.FormatFloat(*schema.Maximum, 'f', -1, 64)
+		fields = append(fields, fieldError(path, "max", param, "must be at most "+param))
+	}
+	if schema.Format == "int32" && (n < math.MinInt32 || n > math.MaxInt32) {
+		fields = append(fields, fieldError(path, "type", "", "must be a 32-bit integer"))
+	}
+	return fields
+}
+
+func schemaTypes(schema *Schema) []string {
+	switch typ := schema.Type.(type) {
+	case string:
+		return []string{typ}
+	case []string:
+		return typ
+	}
+	return nil
+}
+
+func matchesType(types []string, value any) bool {
+	for _, typ := range types {
+		switch value := value.(type) {
+		case nil:
+			if typ == "null" {
+				return true
+			}
+		case bool:
+			if typ == "boolean" {
+				return true
+			}
+		case string:
+			if typ == "string" {
+				return true
+			}
+		case json.Number:
+			if typ == "number" {
+				return true
+			}
+			if typ == "integer" {
+				if _, err := value.Int64(); err == nil {
+					return true
+				}
+			}
+		case []any:
+			if typ == "array" {
+				return true
+			}
+		case map[string]any:
+			if typ == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeDescription(types []string) string {
+	descriptions := make([]string, len(types))
+	for i, typ := range types {
+		switch typ {
+		case "integer", "array", "object":
+			descriptions[i] = "an " + typ
+		default:
+			descriptions[i] = "a " + typ
+		}
+	}
+	return strings.Join(descriptions, " or ")
+}
+
+// coerce converts parameter values to the schema type, values that do not
+// parse are kept as strings and fail the type check.
+func coerce(schema *Schema, values []string) any {
+	types := schemaTypes(schema)
+	if slices.Contains(types, "array") {
+		items := make([]any, len(values))
+		for i, value := range values {
+			items[i] = coerceValue(schemaTypes(schema.Items), value)
+		}
+		return items
+	}
+	return coerceValue(types, values[0])
+}
+
+func coerceValue(types []string, value string) any {
+	for _, typ := range types {
+		switch typ {
+		case "integer", "number":
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				return json.Number(value)
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func fieldError(path, rule, param, message string) errorx.FieldError {
+	return errorx.FieldError{Field: path, Rule: rule, Param: param, Message: message}
+}
@@ -0,0 +1,128 @@
+package pagination
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the position of a page boundary, encoded as base64url JSON. It is
+// opaque to clients but not secret, the values are the sort fields of a row
+// the client already received.
+type cursor struct {
+	Signature string   `json:"q"`
+	Values    []string `json:"v"`
+	// Backward cursors select the rows before the boundary.
+	Backward bool `json:"b,omitempty"`
+	// Inclusive cursors select the boundary row too.
+	Inclusive bool `json:"i,omitempty"`
+
+	// values are parsed to the types of the sort fields.
+	values []any
+}
+
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor[T any](v string, orders []order[T]) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || len(c.Values) != len(orders) {
+		return nil, errInvalidCursor
+	}
+
+	c.values = make([]any, len(orders))
+	for i, o := range orders {
+		value, err := parseValue(o.field.Type, c.Values[i])
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		c.values[i] = value
+	}
+	return &c, nil
+}
+
+// signature hashes the sort and the filter parameters.
+func signature[T any](orders []order[T], params url.Values) string {
+	var b strings.Builder
+	for _, o := range orders {
+		if o.desc {
+			b.WriteByte('-')
+		}
+		b.WriteString(o.field.Name + ",")
+	}
+
+	var names []string
+	for name := range params {
+		if filterParam.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range params[name] {
+			b.WriteString("\n" + name + "=" + value)
+		}
+	}
+
+	h := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(h[:8])
+}
+
+func parseValue(typ Type, raw string) (any, error) {
+	switch typ {
+	case Int:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return v, nil
+	case Time:
+		v, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, errors.New("must be an RFC 3339 date-time")
+		}
+		return v, nil
+	case Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("must be a boolean")
+		}
+		return v, nil
+	}
+	return raw, nil
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		// full precision, the cursor must compare equal to the stored value
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,465 @@
+// Package pagination parses the list query parameters into bob query mods:
+//
+//	?limit=50&sort=-created_at,email&filter[email][contains]=example&cursor=...
+//
+// Fields are whitelisted per listing, unknown fields and operators are
+// validation errors, and every value is bound as an argument. Pages are
+// keyset based: the opaque cursors hold the sort values of the first or last
+// row, so pages stay stable while rows are inserted.
+package pagination
+
+import (
+	"api-core/pkg/errorx"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stephenafamo/bob"
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/dialect"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+)
+
+const (
+	ParamLimit  = "limit"
+	ParamCursor = "cursor"
+	ParamSort   = "sort"
+
+	defaultLimit = 50
+	maxLimit     = 200
+	// maxInValues bounds the values of an in filter.
+	maxInValues = 100
+)
+
+// filterParam matches filter[field] and filter[field][op].
+var filterParam = regexp.MustCompile(`^filter\[([A-Za-z0-9_]+)\](?:\[([a-z]+)\])?$`)
+
+// Type is the type of a field, query values are parsed to it.
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Time
+	Bool
+)
+
+// Op is a filter operator.
+type Op string
+
+const (
+	OpEQ  Op = "eq"
+	OpNE  Op = "ne"
+	OpLT  Op = "lt"
+	OpLTE Op = "lte"
+	OpGT  Op = "gt"
+	OpGTE Op = "gte"
+	// OpIn takes comma separated values.
+	OpIn Op = "in"
+	// OpContains is a case insensitive substring match, for strings.
+	OpContains Op = "contains"
+)
+
+// Field whitelists a column of a listing.
+type Field[T any] struct {
+	// Name is the field in the sort and filter parameters.
+	Name   string
+	Column psql.Expression
+	Type   Type
+	// Sortable fields must be NOT NULL columns, the keyset conditions do not
+	// order NULLs.
+	Sortable bool
+	Filters  []Op
+	// Value reads the field of a row for the cursors, required when Sortable.
+	Value func(row T) any
+}
+
+// Config describes a listing of rows of type T, declared with MustConfig.
+type Config[T any] struct {
+	Fields []Field[T]
+	// Key names the unique sortable field ending every order, so the order
+	// is total, e.g. id.
+	Key string
+	// Sort is the default sort parameter, e.g. "-created_at".
+	Sort string
+	// DefaultLimit and MaxLimit default to 50 and 200, larger limits are
+	// reduced to MaxLimit.
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// MustConfig returns the config, it panics when the config is invalid. Listings
+// declare their config with it, so a mistake fails at package init rather than
+// on the first request.
+func MustConfig[T any](cfg Config[T]) Config[T] {
+	if err := cfg.validate(); err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// validate checks the fields and the key the queries rely on.
+func (cfg *Config[T]) validate() error {
+	var errs []error
+	for i, field := range cfg.Fields {
+		if field.Name == "" {
+			errs = append(errs, fmt.Errorf("pagination: field %d has no name", i))
+		} else if cfg.field(field.Name) != &cfg.Fields[i] {
+			errs = append(errs, fmt.Errorf("pagination: field %s is repeated", field.Name))
+		}
+		if field.Sortable && field.Value == nil {
+			errs = append(errs, fmt.Errorf("pagination: sortable field %s has no Value", field.Name))
+		}
+		if slices.Contains(field.Filters, OpContains) && field.Type != String {
+			errs = append(errs, fmt.Errorf("pagination: contains filter on %s, which is not a string", field.Name))
+		}
+	}
+	if key := cfg.field(cfg.Key); key == nil || !key.Sortable {
+		errs = append(errs, fmt.Errorf("pagination: key %q is not a sortable field", cfg.Key))
+	} else if _, err := parseSort(cfg, cfg.Sort); err != nil {
+		errs = append(errs, fmt.Errorf("pagination: default sort %q: %s", cfg.Sort, err.Message))
+	}
+	return errors.Join(errs...)
+}
+
+func (cfg *Config[T]) field(name string) *Field[T] {
+	for i := range cfg.Fields {
+		if cfg.Fields[i].Name == name {
+			return &cfg.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Pagination is the pagination member of the list responses. The cursors are
+// opaque, a client sends one back with the same sort and filters.
+type Pagination struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	// HasMore reports rows after the page, NextCursor is set then.
+	HasMore bool `json:"has_more"`
+}
+
+type order[T any] struct {
+	field *Field[T]
+	desc  bool
+}
+
+type filter[T any] struct {
+	field  *Field[T]
+	op     Op
+	values []any
+}
+
+// Query is a parsed list request.
+type Query[T any] struct {
+	limit   int
+	orders  []order[T]
+	filters []filter[T]
+	cursor  *cursor
+	// signature identifies the sort and filters, cursors only apply to the
+	// query they were built for.
+	signature string
+}
+
+// Parse reads the limit, cursor, sort and filter parameters. Invalid
+// parameters are an errorx.Validation error, a malformed cursor or one built
+// for another sort or filters an errorx.Invalid error. The config is trusted,
+// declare it with MustConfig.
+func Parse[T any](params url.Values, cfg Config[T]) (*Query[T], error) {
+	if cfg.DefaultLimit <= 0 {
+		cfg.DefaultLimit = defaultLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = maxLimit
+	}
+
+	q := &Query[T]{limit: cfg.DefaultLimit}
+	var fields []errorx.FieldError
+
+	if v := params.Get(ParamLimit); v != "" {
+		limit, err := strconv.Atoi(v)
+		switch {
+		case err != nil:
+			fields = append(fields, errorx.FieldError{Field: ParamLimit, Rule: "number", Message: "must be a number"})
+		case limit < 1:
+			fields = append(fields, errorx.FieldError{Field: ParamLimit, Rule: "min", Param: "1", Message: "must be at least 1"})
+		default:
+			q.limit = min(limit, cfg.MaxLimit)
+		}
+	}
+
+	sortParam := params.Get(ParamSort)
+	if sortParam == "" {
+		sortParam = cfg.Sort
+	}
+	orders, fieldErr := parseSort(&cfg, sortParam)
+	if fieldErr != nil {
+		fields = append(fields, *fieldErr)
+	}
+	q.orders = orders
+
+	filters, filterErrs := parseFilters(&cfg, params)
+	fields = append(fields, filterErrs...)
+	q.filters = filters
+
+	if len(fields) > 0 {
+		return nil, errorx.NewValidation(fields)
+	}
+
+	q.signature = signature(q.orders, params)
+	if v := params.Get(ParamCursor); v != "" {
+		c, err := decodeCursor(v, q.orders)
+		if err != nil {
+			return nil, errorx.Wrap(err, errorx.Invalid)
+		}
+		if c.Signature != q.signature {
+			return nil, errorx.Wrap(errors.New("cursor does not match the sort and filters"), errorx.Invalid)
+		}
+		q.cursor = c
+	}
+	return q, nil
+}
+
+// parseSort reads the comma separated fields, descending when prefixed with
+// a minus. The key is appended when missing.
+func parseSort[T any](cfg *Config[T], param string) ([]order[T], *errorx.FieldError) {
+	var orders []order[T]
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+
+		field := cfg.field(name)
+		if field == nil || !field.Sortable {
+			return nil, &errorx.FieldError{Field: ParamSort, Rule: "oneof", Param: strings.Join(sortable(cfg), " "), Message: "must be one of: " + strings.Join(sortable(cfg), ", ")}
+		}
+		if slices.ContainsFunc(orders, func(o order[T]) bool { return o.field == field }) {
+			return nil, &errorx.FieldError{Field: ParamSort, Rule: "unique", Message: name + " is repeated"}
+		}
+		orders = append(orders, order[T]{field: field, desc: desc})
+	}
+
+	key := cfg.field(cfg.Key)
+	if !slices.ContainsFunc(orders, func(o order[T]) bool { return o.field == key }) {
+		desc := len(orders) > 0 && orders[len(orders)-1].desc
+		orders = append(orders, order[T]{field: key, desc: desc})
+	}
+	return orders, nil
+}
+
+func sortable[T any](cfg *Config[T]) []string {
+	var names []string
+	for _, field := range cfg.Fields {
+		if field.Sortable {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+// parseFilters reads filter[field][op] parameters, filter[field] is an eq
+// filter. Repeated filters are all applied.
+func parseFilters[T any](cfg *Config[T], params url.Values) ([]filter[T], []errorx.FieldError) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		filters []filter[T]
+		errs    []errorx.FieldError
+	)
+	for _, name := range names {
+		match := filterParam.FindStringSubmatch(name)
+		if match == nil {
+			if strings.HasPrefix(name, "filter[") {
+				errs = append(errs, errorx.FieldError{Field: name, Rule: "filter", Message: "must be filter[field] or filter[field][op]"})
+			}
+			continue
+		}
+
+		field := cfg.field(match[1])
+		op := Op(match[2])
+		if op == "" {
+			op = OpEQ
+		}
+		if field == nil || !slices.Contains(field.Filters, op) {
+			errs = append(errs, errorx.FieldError{Field: name, Rule: "filter", Message: "is not a supported filter"})
+			continue
+		}
+
+		for _, raw := range params[name] {
+			values, err := parseFilterValues(field.Type, op, raw)
+			if err != nil {
+				errs = append(errs, errorx.FieldError{Field: name, Rule: "type", Message: err.Error()})
+				continue
+			}
+			filters = append(filters, filter[T]{field: field, op: op, values: values})
+		}
+	}
+	return filters, errs
+}
+
+func parseFilterValues(typ Type, op Op, raw string) ([]any, error) {
+	if op == OpContains {
+		if typ != String {
+			return nil, errors.New("contains only applies to strings")
+		}
+		return []any{"%" + escapeLike(raw) + "%"}, nil
+	}
+
+	raws := []string{raw}
+	if op == OpIn {
+		raws = strings.Split(raw, ",")
+		if len(raws) > maxInValues {
+			return nil, fmt.Errorf("must have at most %d values", maxInValues)
+		}
+	}
+
+	values := make([]any, len(raws))
+	for i, raw := range raws {
+		value, err := parseValue(typ, strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// escapeLike makes the wildcards of a contains value literal.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Mods returns the filters, the keyset condition of the cursor, the order and
+// the limit. One row more than the limit is selected, for Page to tell
+// whether more rows follow.
+func (q *Query[T]) Mods() []bob.Mod[*dialect.SelectQuery] {
+	var mods []bob.Mod[*dialect.SelectQuery]
+	for _, f := range q.filters {
+		mods = append(mods, sm.Where(f.expression()))
+	}
+
+	backward := q.cursor != nil && q.cursor.Backward
+	if q.cursor != nil {
+		mods = append(mods, sm.Where(q.keyset()))
+	}
+	for _, o := range q.orders {
+		// a backward page is read in reverse order then flipped by Page
+		if o.desc != backward {
+			mods = append(mods, sm.OrderBy(o.field.Column).Desc())
+		} else {
+			mods = append(mods, sm.OrderBy(o.field.Column).Asc())
+		}
+	}
+	return append(mods, sm.Limit(q.limit+1))
+}
+
+func (f filter[T]) expression() psql.Expression {
+	column := f.field.Column
+	args := make([]bob.Expression, len(f.values))
+	for i, value := range f.values {
+		args[i] = psql.Arg(value)
+	}
+
+	switch f.op {
+	case OpNE:
+		return column.NE(args[0])
+	case OpLT:
+		return column.LT(args[0])
+	case OpLTE:
+		return column.LTE(args[0])
+	case OpGT:
+		return column.GT(args[0])
+	case OpGTE:
+		return column.GTE(args[0])
+	case OpIn:
+		return column.In(args...)
+	case OpContains:
+		return column.ILike(args[0])
+	}
+	return column.EQ(args[0])
+}
+
+// keyset selects the rows after the cursor in the sort order, or before it
+// for a backward cursor: (a > va) OR (a = va AND b > vb) OR ... An inclusive
+// cursor compares the last field with >= instead.
+func (q *Query[T]) keyset() psql.Expression {
+	var alternatives []bob.Expression
+	for i, o := range q.orders {
+		var terms []bob.Expression
+		for j := range i {
+			terms = append(terms, q.orders[j].field.Column.EQ(psql.Arg(q.cursor.values[j])))
+		}
+		value := psql.Arg(q.cursor.values[i])
+		// the key ends the order, including it includes the boundary row
+		inclusive := q.cursor.Inclusive && i == len(q.orders)-1
+		switch {
+		case o.desc != q.cursor.Backward && inclusive:
+			terms = append(terms, o.field.Column.LTE(value))
+		case o.desc != q.cursor.Backward:
+			terms = append(terms, o.field.Column.LT(value))
+		case inclusive:
+			terms = append(terms, o.field.Column.GTE(value))
+		default:
+			terms = append(terms, o.field.Column.GT(value))
+		}
+		alternatives = append(alternatives, psql.And(terms...))
+	}
+	return psql.Or(alternatives...)
+}
+
+// Page drops the extra row selected by Mods and returns the rows in the sort
+// order with the cursors of the neighbouring pages.
+func (q *Query[T]) Page(rows []T) ([]T, Pagination) {
+	more := len(rows) > q.limit
+	if more {
+		rows = rows[:q.limit]
+	}
+
+	var page Pagination
+	if q.cursor != nil && q.cursor.Backward {
+		slices.Reverse(rows)
+		if len(rows) > 0 {
+			// the cursor came from the following page
+			page.NextCursor = q.encode(rows[len(rows)-1], false)
+			if more {
+				page.PrevCursor = q.encode(rows[0], true)
+			}
+		} else {
+			// the rows before the cursor are gone, the following page starts
+			// at the boundary row of the cursor
+			next := *q.cursor
+			next.Backward, next.Inclusive = false, true
+			page.NextCursor = encodeCursor(next)
+		}
+	} else if len(rows) > 0 {
+		if more {
+			page.NextCursor = q.encode(rows[len(rows)-1], false)
+		}
+		if q.cursor != nil {
+			page.PrevCursor = q.encode(rows[0], true)
+		}
+	}
+	page.HasMore = page.NextCursor != ""
+	return rows, page
+}
+
+func (q *Query[T]) encode(row T, backward bool) string {
+	values := make([]string, len(q.orders))
+	for i, o := range q.orders {
+		values[i] = formatValue(o.field.Value(row))
+	}
+	return encodeCursor(cursor{Signature: q.signature, Values: values, Backward: backward})
+}
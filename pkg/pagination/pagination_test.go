@@ -0,0 +1,461 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"api-core/pkg/errorx"
+
+	"github.com/stephenafamo/bob"
+	"github.com/stephenafamo/bob/dialect/psql"
+	"github.com/stephenafamo/bob/dialect/psql/dialect"
+	"github.com/stephenafamo/bob/dialect/psql/sm"
+)
+
+type testRow struct {
+	ID    int64
+	Email string
+	Score int64
+}
+
+func testFields() []Field[testRow] {
+	return []Field[testRow]{
+		{Name: "id", Column: psql.Quote("id"), Type: Int, Sortable: true, Filters: []Op{OpEQ, OpIn}, Value: func(r testRow) any { return r.ID }},
+		{Name: "email", Column: psql.Quote("email"), Type: String, Sortable: true, Filters: []Op{OpEQ, OpContains}, Value: func(r testRow) any { return r.Email }},
+		{Name: "score", Column: psql.Quote("score"), Type: Int, Sortable: true, Value: func(r testRow) any { return r.Score }},
+		{Name: "name", Column: psql.Quote("name"), Type: String, Filters: []Op{OpContains}},
+	}
+}
+
+var testConfig = MustConfig(Config[testRow]{Fields: testFields(), Key: "id", Sort: "-score", MaxLimit: 10})
+
+// buildSQL returns the WHERE and ORDER BY clauses and the arguments of the
+// query.
+func buildSQL(t *testing.T, q *Query[testRow]) (where, orderBy string, args []any) {
+	t.Helper()
+
+	mods := append([]bob.Mod[*dialect.SelectQuery]{sm.From("t")}, q.Mods()...)
+	query, args, err := bob.Build(context.Background(), psql.Select(mods...))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	for _, line := range strings.Split(query, "\n") {
+		if v, ok := strings.CutPrefix(line, "WHERE "); ok {
+			where = v
+		}
+		if v, ok := strings.CutPrefix(line, "ORDER BY "); ok {
+			orderBy = v
+		}
+	}
+	return where, orderBy, args
+}
+
+// parseWithCursor parses the parameters with a cursor at the values, signed
+// for them.
+func parseWithCursor(t *testing.T, params url.Values, c cursor) (*Query[testRow], error) {
+	t.Helper()
+
+	q, err := Parse(params, testConfig)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	c.Signature = q.signature
+	params = cloneValues(params)
+	params.Set(ParamCursor, encodeCursor(c))
+	return Parse(params, testConfig)
+}
+
+func cloneValues(params url.Values) url.Values {
+	clone := url.Values{}
+	for k, v := range params {
+		clone[k] = slices.Clone(v)
+	}
+	return clone
+}
+
+func assertKind(t *testing.T, err error, kind errorx.Kind) {
+	t.Helper()
+
+	var target *errorx.Error
+	if !errors.As(err, &target) || !target.Of(kind) {
+		t.Fatalf("err = %v, want kind %s", err, kind.String())
+	}
+}
+
+func TestMustConfig(t *testing.T) {
+	withoutValue := testFields()
+	withoutValue[2].Value = nil
+	containsInt := testFields()
+	containsInt[2].Filters = []Op{OpContains}
+	repeated := append(testFields(), testFields()[1])
+
+	tests := []struct {
+		name    string
+		cfg     Config[testRow]
+		wantErr string
+	}{
+		{name: "valid", cfg: Config[testRow]{Fields: testFields(), Key: "id", Sort: "-score,email"}},
+		{name: "missing key", cfg: Config[testRow]{Fields: testFields()}, wantErr: `key "" is not a sortable field`},
+		{name: "unsortable key", cfg: Config[testRow]{Fields: testFields(), Key: "name"}, wantErr: `key "name" is not a sortable field`},
+		{name: "sortable without value", cfg: Config[testRow]{Fields: withoutValue, Key: "id"}, wantErr: "sortable field score has no Value"},
+		{name: "contains on an int", cfg: Config[testRow]{Fields: containsInt, Key: "id"}, wantErr: "contains filter on score"},
+		{name: "repeated field", cfg: Config[testRow]{Fields: repeated, Key: "id"}, wantErr: "field email is repeated"},
+		{name: "invalid default sort", cfg: Config[testRow]{Fields: testFields(), Key: "id", Sort: "name"}, wantErr: `default sort "name"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if tt.wantErr == "" && r != nil {
+					t.Fatalf("panic = %v, want none", r)
+				}
+				if tt.wantErr != "" && (r == nil || !strings.Contains(fmt.Sprint(r), tt.wantErr)) {
+					t.Fatalf("panic = %v, want %q", r, tt.wantErr)
+				}
+			}()
+			MustConfig(tt.cfg)
+		})
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	tests := []struct {
+		name      string
+		sort      string
+		cursor    cursor
+		wantWhere string
+		wantOrder string
+		wantArgs  []any
+	}{
+		{
+			name:      "default sort",
+			cursor:    cursor{Values: []string{"5", "7"}},
+			wantWhere: `((("score" < $1)) OR (("score" = $2) AND ("id" < $3)))`,
+			wantOrder: `"score" DESC, "id" DESC`,
+			wantArgs:  []any{int64(5), int64(5), int64(7)},
+		},
+		{
+			name:      "mixed directions",
+			sort:      "-score,email",
+			cursor:    cursor{Values: []string{"5", "a@example.com", "7"}},
+			wantWhere: `((("score" < $1)) OR (("score" = $2) AND ("email" > $3)) OR (("score" = $4) AND ("email" = $5) AND ("id" > $6)))`,
+			wantOrder: `"score" DESC, "email" ASC, "id" ASC`,
+			wantArgs:  []any{int64(5), int64(5), "a@example.com", int64(5), "a@example.com", int64(7)},
+		},
+		{
+			name:      "mixed directions backward",
+			sort:      "-score,email",
+			cursor:    cursor{Values: []string{"5", "a@example.com", "7"}, Backward: true},
+			wantWhere: `((("score" > $1)) OR (("score" = $2) AND ("email" < $3)) OR (("score" = $4) AND ("email" = $5) AND ("id" < $6)))`,
+			wantOrder: `"score" ASC, "email" DESC, "id" DESC`,
+			wantArgs:  []any{int64(5), int64(5), "a@example.com", int64(5), "a@example.com", int64(7)},
+		},
+		{
+			name:      "explicit key direction",
+			sort:      "email,-id",
+			cursor:    cursor{Values: []string{"a@example.com", "7"}},
+			wantWhere: `((("email" > $1)) OR (("email" = $2) AND ("id" < $3)))`,
+			wantOrder: `"email" ASC, "id" DESC`,
+			wantArgs:  []any{"a@example.com", "a@example.com", int64(7)},
+		},
+		{
+			name:      "inclusive",
+			sort:      "email",
+			cursor:    cursor{Values: []string{"a@example.com", "7"}, Inclusive: true},
+			wantWhere: `((("email" > $1)) OR (("email" = $2) AND ("id" >= $3)))`,
+			wantOrder: `"email" ASC, "id" ASC`,
+			wantArgs:  []any{"a@example.com", "a@example.com", int64(7)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := url.Values{}
+			if tt.sort != "" {
+				params.Set(ParamSort, tt.sort)
+			}
+			q, err := parseWithCursor(t, params, tt.cursor)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			where, orderBy, args := buildSQL(t, q)
+			if where != tt.wantWhere {
+				t.Fatalf("where = %s, want %s", where, tt.wantWhere)
+			}
+			if orderBy != tt.wantOrder {
+				t.Fatalf("order = %s, want %s", orderBy, tt.wantOrder)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Fatalf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	rows := []testRow{{ID: 1, Score: 9}, {ID: 2, Score: 8}, {ID: 3, Score: 7}}
+	// rows read backward come in reverse order
+	reversed := slices.Clone(rows)
+	slices.Reverse(reversed)
+
+	tests := []struct {
+		name     string
+		cursor   *cursor
+		rows     []testRow
+		wantIDs  []int64
+		wantNext *cursor
+		wantPrev *cursor
+	}{
+		{
+			name:     "first page",
+			rows:     rows,
+			wantIDs:  []int64{1, 2},
+			wantNext: &cursor{Values: []string{"8", "2"}},
+		},
+		{
+			name:    "single page",
+			rows:    rows[:2],
+			wantIDs: []int64{1, 2},
+		},
+		{
+			name:     "last page",
+			cursor:   &cursor{Values: []string{"10", "0"}},
+			rows:     rows[1:],
+			wantIDs:  []int64{2, 3},
+			wantPrev: &cursor{Values: []string{"8", "2"}, Backward: true},
+		},
+		{
+			name:     "backward page",
+			cursor:   &cursor{Values: []string{"6", "4"}, Backward: true},
+			rows:     reversed,
+			wantIDs:  []int64{2, 3},
+			wantNext: &cursor{Values: []string{"7", "3"}},
+			wantPrev: &cursor{Values: []string{"8", "2"}, Backward: true},
+		},
+		{
+			name:     "first backward page",
+			cursor:   &cursor{Values: []string{"6", "4"}, Backward: true},
+			rows:     reversed[:2],
+			wantIDs:  []int64{2, 3},
+			wantNext: &cursor{Values: []string{"7", "3"}},
+		},
+		{
+			// the rows before the cursor were deleted, the client goes back
+			// to the page the cursor came from
+			name:     "empty backward page",
+			cursor:   &cursor{Values: []string{"7", "3"}, Backward: true},
+			wantNext: &cursor{Values: []string{"7", "3"}, Inclusive: true},
+		},
+		{
+			name:   "empty page",
+			cursor: &cursor{Values: []string{"1", "9"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := url.Values{ParamLimit: {"2"}}
+			q, err := Parse(params, testConfig)
+			if tt.cursor != nil {
+				q, err = parseWithCursor(t, params, *tt.cursor)
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			got, page := q.Page(slices.Clone(tt.rows))
+			var ids []int64
+			for _, row := range got {
+				ids = append(ids, row.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Fatalf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			assertCursor(t, q, "next", page.NextCursor, tt.wantNext)
+			assertCursor(t, q, "prev", page.PrevCursor, tt.wantPrev)
+			if page.HasMore != (tt.wantNext != nil) {
+				t.Fatalf("has more = %v, want %v", page.HasMore, tt.wantNext != nil)
+			}
+		})
+	}
+}
+
+func assertCursor(t *testing.T, q *Query[testRow], name, got string, want *cursor) {
+	t.Helper()
+
+	if want == nil {
+		if got != "" {
+			t.Fatalf("%s cursor = %q, want none", name, got)
+		}
+		return
+	}
+	c, err := decodeCursor(got, q.orders)
+	if err != nil {
+		t.Fatalf("%s cursor %q: %v", name, got, err)
+	}
+	if c.Signature != q.signature || !slices.Equal(c.Values, want.Values) || c.Backward != want.Backward || c.Inclusive != want.Inclusive {
+		t.Fatalf("%s cursor = %+v, want %+v", name, c, want)
+	}
+}
+
+func TestParseCursorErrors(t *testing.T) {
+	q, err := Parse(url.Values{ParamSort: {"email"}, "filter[email][contains]": {"a"}, ParamLimit: {"2"}}, testConfig)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	_, page := q.Page([]testRow{{ID: 1, Email: "a"}, {ID: 2, Email: "b"}, {ID: 3, Email: "c"}})
+	valid := page.NextCursor
+
+	tests := []struct {
+		name    string
+		params  url.Values
+		wantErr bool
+	}{
+		{name: "same query", params: url.Values{ParamSort: {"email"}, "filter[email][contains]": {"a"}, ParamCursor: {valid}}},
+		{name: "limit changed", params: url.Values{ParamSort: {"email"}, "filter[email][contains]": {"a"}, ParamLimit: {"5"}, ParamCursor: {valid}}},
+		{name: "other direction", params: url.Values{ParamSort: {"-email"}, "filter[email][contains]": {"a"}, ParamCursor: {valid}}, wantErr: true},
+		{name: "other sort", params: url.Values{ParamSort: {"score"}, "filter[email][contains]": {"a"}, ParamCursor: {valid}}, wantErr: true},
+		{name: "other filter", params: url.Values{ParamSort: {"email"}, "filter[email][contains]": {"b"}, ParamCursor: {valid}}, wantErr: true},
+		{name: "filter removed", params: url.Values{ParamSort: {"email"}, ParamCursor: {valid}}, wantErr: true},
+		{name: "not base64", params: url.Values{ParamSort: {"email"}, "filter[email][contains]": {"a"}, ParamCursor: {"%%%"}}, wantErr: true},
+		{name: "not json", params: url.Values{ParamSort: {"email"}, "filter[email][contains]": {"a"}, ParamCursor: {"bm90IGpzb24"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.params, testConfig)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("parse: %v", err)
+				}
+				return
+			}
+			assertKind(t, err, errorx.Invalid)
+		})
+	}
+
+	// a cursor value that does not parse to the field type
+	if _, err := parseWithCursor(t, url.Values{}, cursor{Values: []string{"high", "1"}}); err == nil {
+		t.Fatal("expected a cursor with a malformed value to fail")
+	}
+	if _, err := parseWithCursor(t, url.Values{}, cursor{Values: []string{"1"}}); err == nil {
+		t.Fatal("expected a cursor with missing values to fail")
+	}
+}
+
+func TestFilters(t *testing.T) {
+	values := func(n int) string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = fmt.Sprint(i + 1)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	tests := []struct {
+		name       string
+		params     url.Values
+		wantWhere  string
+		wantArgs   []any
+		wantFields []string
+	}{
+		{
+			name:      "contains escapes wildcards",
+			params:    url.Values{"filter[email][contains]": {`50%_off\`}},
+			wantWhere: `("email" ILIKE $1)`,
+			wantArgs:  []any{`%50\%\_off\\%`},
+		},
+		{
+			name:      "eq by default",
+			params:    url.Values{"filter[email]": {"a@example.com"}},
+			wantWhere: `("email" = $1)`,
+			wantArgs:  []any{"a@example.com"},
+		},
+		{
+			name:      "in",
+			params:    url.Values{"filter[id][in]": {"1, 2,3"}},
+			wantWhere: `("id" IN ($1, $2, $3))`,
+			wantArgs:  []any{int64(1), int64(2), int64(3)},
+		},
+		{
+			name:      "in at the limit",
+			params:    url.Values{"filter[id][in]": {values(maxInValues)}},
+			wantWhere: `("id" IN (` + placeholders(maxInValues) + `))`,
+		},
+		{name: "in over the limit", params: url.Values{"filter[id][in]": {values(maxInValues + 1)}}, wantFields: []string{"filter[id][in]"}},
+		{name: "in with a malformed value", params: url.Values{"filter[id][in]": {"1,x"}}, wantFields: []string{"filter[id][in]"}},
+		{name: "unsupported operator", params: url.Values{"filter[score][gt]": {"1"}}, wantFields: []string{"filter[score][gt]"}},
+		{name: "unknown field", params: url.Values{"filter[password]": {"x"}}, wantFields: []string{"filter[password]"}},
+		{name: "malformed name", params: url.Values{"filter[email": {"x"}}, wantFields: []string{"filter[email"}},
+		{name: "unknown sort", params: url.Values{ParamSort: {"name"}}, wantFields: []string{ParamSort}},
+		{name: "repeated sort", params: url.Values{ParamSort: {"email,-email"}}, wantFields: []string{ParamSort}},
+		{name: "invalid limit", params: url.Values{ParamLimit: {"0"}}, wantFields: []string{ParamLimit}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.params, testConfig)
+			if tt.wantFields != nil {
+				assertKind(t, err, errorx.Validation)
+				var target *errorx.Error
+				errors.As(err, &target)
+				var fields []string
+				for _, f := range target.Fields() {
+					fields = append(fields, f.Field)
+				}
+				if !slices.Equal(fields, tt.wantFields) {
+					t.Fatalf("fields = %v, want %v", fields, tt.wantFields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			where, _, args := buildSQL(t, q)
+			if where != tt.wantWhere {
+				t.Fatalf("where = %s, want %s", where, tt.wantWhere)
+			}
+			if tt.wantArgs != nil && !slices.Equal(args, tt.wantArgs) {
+				t.Fatalf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func placeholders(n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(ph, ", ")
+}
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		limit string
+		want  int
+	}{
+		{limit: "", want: defaultLimit},
+		{limit: "3", want: 3},
+		// reduced to the max limit of the config
+		{limit: "500", want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.limit, func(t *testing.T) {
+			params := url.Values{}
+			if tt.limit != "" {
+				params.Set(ParamLimit, tt.limit)
+			}
+			q, err := Parse(params, testConfig)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if q.limit != tt.want {
+				t.Fatalf("limit = %d, want %d", q.limit, tt.want)
+			}
+		})
+	}
+}
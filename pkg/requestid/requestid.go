@@ -0,0 +1,210 @@
+// Package requestid carries the request and trace ids of an incoming request
+// through the context, so they can be attached to logs, outgoing HTTP calls
+// and SQL statements.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	HeaderRequestID     = "X-Request-ID"
+	HeaderTraceparent   = "traceparent"
+	HeaderTraceresponse = "traceresponse"
+)
+
+// maxLength bounds accepted X-Request-ID values, longer ones are replaced.
+const maxLength = 128
+
+type contextKey struct{}
+
+// IDs identifies a request. TraceID is a W3C trace-id (32 lowercase hex
+// digits), RequestID is the client supplied X-Request-ID or the trace id.
+// SpanID and Sampled are set when the request is traced, see WithSpan.
+type IDs struct {
+	RequestID string
+	TraceID   string
+	SpanID    string
+	Sampled   bool
+}
+
+// NewContext attaches the ids to the context.
+func NewContext(ctx context.Context, ids IDs) context.Context {
+	return context.WithValue(ctx, contextKey{}, ids)
+}
+
+// FromContext returns the ids of the request, ok is false outside a request.
+func FromContext(ctx context.Context) (IDs, bool) {
+	ids, ok := ctx.Value(contextKey{}).(IDs)
+	return ids, ok
+}
+
+// RequestID returns the request id of the context, empty outside a request.
+func RequestID(ctx context.Context) string {
+	ids, _ := FromContext(ctx)
+	return ids.RequestID
+}
+
+// FromHeaders reuses the X-Request-ID and traceparent headers when they are
+// valid and generates the missing ids.
+func FromHeaders(h http.Header) IDs {
+	var ids IDs
+	if traceID, _, ok := ParseTraceparent(h.Get(HeaderTraceparent)); ok {
+		ids.TraceID = traceID
+	}
+	if id := h.Get(HeaderRequestID); validRequestID(id) {
+		ids.RequestID = id
+	}
+
+	if ids.TraceID == "" {
+		if isTraceID(ids.RequestID) {
+			ids.TraceID = ids.RequestID
+		} else {
+			ids.TraceID = newHex(16)
+		}
+	}
+	if ids.RequestID == "" {
+		ids.RequestID = ids.TraceID
+	}
+	return ids
+}
+
+// WithSpan makes the ids follow the server span of a traced request, so logs
+// and the traceresponse header name the exported trace. A request id
+// defaulted to the previous trace id follows too.
+func (ids IDs) WithSpan(traceID, spanID string, sampled bool) IDs {
+	if ids.RequestID == ids.TraceID {
+		ids.RequestID = traceID
+	}
+	ids.TraceID = traceID
+	ids.SpanID = spanID
+	ids.Sampled = sampled
+	return ids
+}
+
+// Traceparent returns a traceparent value for a new span of the trace.
+func (ids IDs) Traceparent() string {
+	return "00-" + ids.TraceID + "-" + newHex(8) + "-01"
+}
+
+// Traceresponse returns the traceresponse value of the request, naming the
+// server span when the request is traced.
+func (ids IDs) Traceresponse() string {
+	if ids.SpanID == "" {
+		return ids.Traceparent()
+	}
+	flags := "00"
+	if ids.Sampled {
+		flags = "01"
+	}
+	return "00-" + ids.TraceID + "-" + ids.SpanID + "-" + flags
+}
+
+// ParseTraceparent extracts the trace and parent ids of a version 00
+// traceparent header.
+func ParseTraceparent(v string) (traceID, parentID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) != 4 || parts[0] != "00" || !isHex(parts[3], 2) {
+		return "", "", false
+	}
+	if !isTraceID(parts[1]) || !isHex(parts[2], 16) || parts[2] == strings.Repeat("0", 16) {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// Transport adds the X-Request-ID and traceparent headers of the request
+// context to outgoing requests. A nil base uses http.DefaultTransport.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ids, ok := FromContext(req.Context())
+	if !ok {
+		return base.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	if req.Header.Get(HeaderRequestID) == "" {
+		req.Header.Set(HeaderRequestID, ids.RequestID)
+	}
+	if req.Header.Get(HeaderTraceparent) == "" {
+		req.Header.Set(HeaderTraceparent, ids.Traceparent())
+	}
+	return base.RoundTrip(req)
+}
+
+// NewHTTPClient returns a copy of client propagating the ids, a nil client
+// copies http.DefaultClient.
+func NewHTTPClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if _, ok := client.Transport.(*Transport); ok {
+		return client
+	}
+
+	propagating := *client
+	propagating.Transport = &Transport{Base: client.Transport}
+	return &propagating
+}
+
+// SQLComment appends the request id to the query in the sqlcommenter format,
+// the query is returned unchanged outside a request.
+func SQLComment(ctx context.Context, query string) string {
+	id := RequestID(ctx)
+	if id == "" {
+		return query
+	}
+	return query + " /*request_id='" + url.QueryEscape(id) + "'*/"
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isTraceID(v string) bool {
+	return isHex(v, 32) && v != strings.Repeat("0", 32)
+}
+
+func isHex(v string, length int) bool {
+	if len(v) != length {
+		return false
+	}
+	for _, r := range v {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newHex(n int) string {
+	b := make([]byte, n)
+	//nolint:errcheck
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
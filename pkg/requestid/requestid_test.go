@@ -0,0 +1,183 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentID = "00f067aa0ba902b7"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		wantOK bool
+	}{
+		{name: "sampled", value: "00-" + traceID + "-" + parentID + "-01", wantOK: true},
+		{name: "not sampled", value: "00-" + traceID + "-" + parentID + "-00", wantOK: true},
+		{name: "surrounding spaces", value: " 00-" + traceID + "-" + parentID + "-01 ", wantOK: true},
+		{name: "empty", value: ""},
+		{name: "unknown version", value: "01-" + traceID + "-" + parentID + "-01"},
+		{name: "upper case", value: "00-" + strings.ToUpper(traceID) + "-" + parentID + "-01"},
+		{name: "short trace id", value: "00-" + traceID[1:] + "-" + parentID + "-01"},
+		{name: "zero trace id", value: "00-" + strings.Repeat("0", 32) + "-" + parentID + "-01"},
+		{name: "zero parent id", value: "00-" + traceID + "-" + strings.Repeat("0", 16) + "-01"},
+		{name: "bad flags", value: "00-" + traceID + "-" + parentID + "-1"},
+		{name: "extra field", value: "00-" + traceID + "-" + parentID + "-01-00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTrace, gotParent, ok := ParseTraceparent(tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (gotTrace != traceID || gotParent != parentID) {
+				t.Fatalf("ids = %s, %s, want %s, %s", gotTrace, gotParent, traceID, parentID)
+			}
+		})
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "req-1", want: true},
+		{id: "A.b_c:d-9", want: true},
+		{id: strings.Repeat("a", maxLength), want: true},
+		{id: strings.Repeat("a", maxLength+1)},
+		{id: ""},
+		{id: "has space"},
+		// a comment terminator would end the SQL comment
+		{id: "a*/b"},
+		{id: "quote'"},
+		{id: "é"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := validRequestID(tt.id); got != tt.want {
+				t.Fatalf("valid = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromHeaders(t *testing.T) {
+	traceparent := "00-" + traceID + "-" + parentID + "-01"
+	otherTrace := "0af7651916cd43dd8448eb211c80319c"
+
+	tests := []struct {
+		name          string
+		requestID     string
+		traceparent   string
+		wantRequestID string
+		wantTraceID   string
+	}{
+		{name: "both headers", requestID: "req-1", traceparent: traceparent, wantRequestID: "req-1", wantTraceID: traceID},
+		{name: "traceparent only", traceparent: traceparent, wantRequestID: traceID, wantTraceID: traceID},
+		{name: "request id only", requestID: "req-1", wantRequestID: "req-1"},
+		// a request id shaped like a trace id continues that trace
+		{name: "trace shaped request id", requestID: otherTrace, wantRequestID: otherTrace, wantTraceID: otherTrace},
+		{name: "invalid request id", requestID: "has space", traceparent: traceparent, wantRequestID: traceID, wantTraceID: traceID},
+		{name: "invalid traceparent", requestID: "req-1", traceparent: "garbage", wantRequestID: "req-1"},
+		{name: "no headers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.requestID != "" {
+				h.Set(HeaderRequestID, tt.requestID)
+			}
+			if tt.traceparent != "" {
+				h.Set(HeaderTraceparent, tt.traceparent)
+			}
+
+			ids := FromHeaders(h)
+			if !isTraceID(ids.TraceID) {
+				t.Fatalf("trace id = %q, want 32 hex digits", ids.TraceID)
+			}
+			if tt.wantTraceID != "" && ids.TraceID != tt.wantTraceID {
+				t.Fatalf("trace id = %s, want %s", ids.TraceID, tt.wantTraceID)
+			}
+			wantRequestID := tt.wantRequestID
+			if wantRequestID == "" {
+				// a generated trace id doubles as request id
+				wantRequestID = ids.TraceID
+			}
+			if ids.RequestID != wantRequestID {
+				t.Fatalf("request id = %s, want %s", ids.RequestID, wantRequestID)
+			}
+		})
+	}
+}
+
+func TestSQLComment(t *testing.T) {
+	ctx := NewContext(context.Background(), IDs{RequestID: "req:1"})
+	if got, want := SQLComment(ctx, "SELECT 1"), "SELECT 1 /*request_id='req%3A1'*/"; got != want {
+		t.Fatalf("query = %s, want %s", got, want)
+	}
+	if got := SQLComment(context.Background(), "SELECT 1"); got != "SELECT 1" {
+		t.Fatalf("query = %s, want it unchanged outside a request", got)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(nil)
+	if NewHTTPClient(client) != client {
+		t.Fatal("client wrapped twice")
+	}
+	ctx := NewContext(context.Background(), IDs{RequestID: "req-1", TraceID: traceID})
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		header          http.Header
+		wantRequestID   string
+		wantTraceparent bool
+	}{
+		{name: "request context", ctx: ctx, wantRequestID: "req-1", wantTraceparent: true},
+		{name: "caller headers kept", ctx: ctx, header: http.Header{"X-Request-Id": {"caller"}}, wantRequestID: "caller", wantTraceparent: true},
+		{name: "outside a request", ctx: context.Background()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(tt.ctx, http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("do: %v", err)
+			}
+			resp.Body.Close()
+
+			if id := got.Get(HeaderRequestID); id != tt.wantRequestID {
+				t.Fatalf("X-Request-ID = %q, want %q", id, tt.wantRequestID)
+			}
+			traceparent := got.Get(HeaderTraceparent)
+			if gotTrace, _, ok := ParseTraceparent(traceparent); ok != tt.wantTraceparent || ok && gotTrace != traceID {
+				t.Fatalf("traceparent = %q, want one in trace %s: %v", traceparent, traceID, tt.wantTraceparent)
+			}
+			// the caller's request is not modified
+			if len(req.Header) != len(tt.header) {
+				t.Fatalf("request headers = %v, want %v", req.Header, tt.header)
+			}
+		})
+	}
+}
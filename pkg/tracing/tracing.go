@@ -0,0 +1,151 @@
+// Package tracing sets up OpenTelemetry tracing. The instrumentations read the
+// global tracer provider and propagator when they are created, so Setup must
+// run before the router, the database pool and the redis client are built.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"api-core/pkg/requestid"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// shutdownTimeout bounds the flush of the pending spans on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+type Config struct {
+	// Exporter is none, otlp or stdout. The OTLP exporter sends over HTTP and
+	// reads the standard OTEL_EXPORTER_OTLP_* variables, such as the endpoint.
+	Exporter string
+	// File is where the stdout exporter writes, empty writes to stdout.
+	File string
+	// SampleRatio is the share of new traces recorded, between 0 and 1.
+	// Requests continuing a trace follow the sampling decision of the caller.
+	SampleRatio float64
+
+	ServiceName    string
+	ServiceVersion string
+}
+
+// Provider owns the tracer provider installed by Setup. It implements
+// do.Shutdownable, so the container flushes the pending spans.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	closer   io.Closer
+	once     sync.Once
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. With the none exporter nothing is installed, the global no-op
+// provider records nothing and the request ids keep their own trace ids.
+func Setup(cfg Config) (*Provider, error) {
+	if cfg.Exporter == "" || cfg.Exporter == ExporterNone {
+		return &Provider{}, nil
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("tracing: sample ratio %v is not between 0 and 1", cfg.SampleRatio)
+	}
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+		err      error
+	)
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(context.Background())
+	case ExporterStdout:
+		out := io.Writer(os.Stdout)
+		if cfg.File != "" {
+			file, ferr := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if ferr != nil {
+				return nil, fmt.Errorf("tracing: open %s: %w", cfg.File, ferr)
+			}
+			out, closer = file, file
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q, expected none, otlp or stdout", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.Merge(
+		resource.NewSchemaless(
+			semconv.ServiceName(cfg.ServiceName),
+			semconv.ServiceVersion(cfg.ServiceVersion),
+		),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return &Provider{provider: provider, closer: closer}, nil
+}
+
+// Shutdown flushes the pending spans and stops the exporter. do keeps a
+// service whose shutdown failed, so only the first call reports the error.
+func (p *Provider) Shutdown() error {
+	if p.provider == nil {
+		return nil
+	}
+
+	var err error
+	p.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err = p.provider.Shutdown(ctx)
+		if p.closer != nil {
+			err = errors.Join(err, p.closer.Close())
+		}
+	})
+	return err
+}
+
+// NewHTTPClient returns a copy of client creating a span per outgoing request
+// and propagating the request ids, a nil client copies http.DefaultClient.
+func NewHTTPClient(client *http.Client) *http.Client {
+	if client != nil {
+		if _, ok := client.Transport.(*otelhttp.Transport); ok {
+			return client
+		}
+	}
+
+	traced := *requestid.NewHTTPClient(client)
+	// outermost, so the traceparent of the client span is sent rather than the
+	// one of the request ids
+	traced.Transport = otelhttp.NewTransport(traced.Transport)
+	return &traced
+}
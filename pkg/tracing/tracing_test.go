@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"api-core/pkg/requestid"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "empty", cfg: Config{}},
+		{name: "none", cfg: Config{Exporter: ExporterNone, SampleRatio: 2}},
+		{name: "unknown exporter", cfg: Config{Exporter: "jaeger", SampleRatio: 1}, wantErr: true},
+		{name: "negative ratio", cfg: Config{Exporter: ExporterStdout, SampleRatio: -0.1}, wantErr: true},
+		{name: "ratio above one", cfg: Config{Exporter: ExporterStdout, SampleRatio: 1.5}, wantErr: true},
+		{name: "unwritable file", cfg: Config{Exporter: ExporterStdout, File: filepath.Join(t.TempDir(), "missing", "spans.json"), SampleRatio: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Setup(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// the none exporter installs nothing and has nothing to flush
+			if p.provider != nil {
+				t.Fatalf("provider = %v, want none", p.provider)
+			}
+			if err := p.Shutdown(); err != nil {
+				t.Fatalf("shutdown: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetupStdoutFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "spans.json")
+	p, err := Setup(Config{Exporter: ExporterStdout, File: file, SampleRatio: 1, ServiceName: "api-core-test", ServiceVersion: "1.2.3"})
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+
+	// the batched span is written on shutdown, which also closes the file
+	if err := p.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	// the container calls it again after a failed shutdown
+	if err := p.Shutdown(); err != nil {
+		t.Fatalf("second shutdown: %v, want the closed file left alone", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	for _, want := range []string{`"Name":"work"`, "api-core-test", "1.2.3"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("spans = %s, want %s", data, want)
+		}
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(nil)
+	if http.DefaultClient.Transport != nil {
+		t.Fatal("NewHTTPClient modified http.DefaultClient")
+	}
+	if again := NewHTTPClient(client); again != client {
+		t.Fatal("NewHTTPClient wrapped a traced client twice")
+	}
+
+	ids := requestid.IDs{RequestID: "req-1", TraceID: "0af7651916cd43dd8448eb211c80319c"}
+	ctx := requestid.NewContext(context.Background(), ids)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want a client span", len(spans))
+	}
+	sc := spans[0].SpanContext()
+	if got := header.Get(requestid.HeaderRequestID); got != "req-1" {
+		t.Fatalf("X-Request-ID = %q, want req-1", got)
+	}
+	// the traceparent names the client span rather than the request ids
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+	if got := header.Get(requestid.HeaderTraceparent); got != want {
+		t.Fatalf("traceparent = %q, want %q", got, want)
+	}
+}